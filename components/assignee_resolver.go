@@ -0,0 +1,278 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Assignment 负责人解析的结果 Assignee 不为空时直接分配，否则由候选人或者候选组认领
+type Assignment struct {
+	Assignee        string
+	CandidateUsers  []string
+	CandidateGroups []string
+}
+
+func (assignment Assignment) empty() bool {
+	return assignment.Assignee == "" && len(assignment.CandidateUsers) == 0 && len(assignment.CandidateGroups) == 0
+}
+
+// AssigneeResolver 按审批节点的 assigneeKey 解析负责人 按 assigneeType 注册，组织架构服务实现这个接口接入自己的规则
+type AssigneeResolver interface {
+	ResolveAssignee(ctx *WorkflowContext, assigneeKey string) (Assignment, error)
+}
+
+// AssigneeKeyValidator 解析器可以选择实现 部署时检查 assigneeKey 的配置，例如引用的节点是否存在
+type AssigneeKeyValidator interface {
+	ValidateAssigneeKey(model *Model, assigneeKey string) error
+}
+
+// AssigneeResolverFunc 让普通函数实现 AssigneeResolver
+type AssigneeResolverFunc func(ctx *WorkflowContext, assigneeKey string) (Assignment, error)
+
+func (f AssigneeResolverFunc) ResolveAssignee(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	return f(ctx, assigneeKey)
+}
+
+var (
+	assigneeResolverRegistry = map[string]AssigneeResolver{
+		ASSIGNEETYPE_NAME:              AssigneeResolverFunc(resolveByName),
+		ASSIGNEETYPE_COMPANY:           AssigneeResolverFunc(resolveByParentCompany),
+		ASSIGNEETYPE_INITIATOR:         AssigneeResolverFunc(resolveByInitiator),
+		ASSIGNEETYPE_INITIATOR_MANAGER: AssigneeResolverFunc(resolveByInitiatorManager),
+		ASSIGNEETYPE_ROLE:              AssigneeResolverFunc(resolveByRole),
+		ASSIGNEETYPE_EXPRESSION:        expressionAssigneeResolver{},
+		ASSIGNEETYPE_SAME_AS_NODE:      sameAsNodeAssigneeResolver{},
+	}
+	assigneeResolverMutex sync.RWMutex
+)
+
+// RegisterAssigneeResolver 注册一种负责人指定方式 业务服务在启动时调用 名称重复或者解析器为空直接panic
+func RegisterAssigneeResolver(assigneeType string, resolver AssigneeResolver) {
+	assigneeType = strings.TrimSpace(assigneeType)
+	if assigneeType == "" {
+		panic("assignee type must not be empty")
+	}
+	if resolver == nil {
+		panic(fmt.Sprintf("assignee resolver %s must not be nil", assigneeType))
+	}
+
+	assigneeResolverMutex.Lock()
+	defer assigneeResolverMutex.Unlock()
+	if _, exists := assigneeResolverRegistry[assigneeType]; exists {
+		panic(fmt.Sprintf("assignee resolver %s is already registered", assigneeType))
+	}
+	assigneeResolverRegistry[assigneeType] = resolver
+}
+
+func getAssigneeResolver(assigneeType string) (AssigneeResolver, bool) {
+	assigneeResolverMutex.RLock()
+	defer assigneeResolverMutex.RUnlock()
+	resolver, ok := assigneeResolverRegistry[assigneeType]
+	return resolver, ok
+}
+
+// 按负责人指定方式解析负责人 解析不出任何人时报错，不再退回到默认用户
+func resolveAssignment(ctx *WorkflowContext, assigneeType string, assigneeKey string) (Assignment, error) {
+	resolver, ok := getAssigneeResolver(assigneeType)
+	if !ok {
+		return Assignment{}, fmt.Errorf("assignee resolver %s is not registered", assigneeType)
+	}
+	assignment, err := resolver.ResolveAssignee(ctx, assigneeKey)
+	if err != nil {
+		return Assignment{}, fmt.Errorf("failed to resolve assignee %s %s: %v", assigneeType, assigneeKey, err)
+	}
+	if assignment.empty() {
+		return Assignment{}, fmt.Errorf("assignee %s %s resolved to nobody", assigneeType, assigneeKey)
+	}
+	return assignment, nil
+}
+
+// ManagerProvider 查询用户的上级 升级定时器和按发起人上级分配时使用
+type ManagerProvider interface {
+	GetManager(userId string) (string, error)
+}
+
+// ManagerFunc 让普通函数实现 ManagerProvider
+type ManagerFunc func(userId string) (string, error)
+
+func (f ManagerFunc) GetManager(userId string) (string, error) {
+	return f(userId)
+}
+
+var (
+	currentManagerProvider ManagerProvider
+	managerMutex           sync.RWMutex
+)
+
+// SetManagerProvider 设置查询上级的实现 业务服务在启动时调用，传 nil 表示没有组织架构
+func SetManagerProvider(provider ManagerProvider) {
+	managerMutex.Lock()
+	defer managerMutex.Unlock()
+	currentManagerProvider = provider
+}
+
+func getManager(userId string) (string, error) {
+	managerMutex.RLock()
+	provider := currentManagerProvider
+	managerMutex.RUnlock()
+	if provider == nil {
+		return "", fmt.Errorf("no manager provider is configured")
+	}
+	manager, err := provider.GetManager(userId)
+	if err != nil {
+		return "", fmt.Errorf("failed to get manager of %s: %v", userId, err)
+	}
+	return manager, nil
+}
+
+// 按名称 assigneeKey 就是负责人
+func resolveByName(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	return Assignment{Assignee: assigneeKey}, nil
+}
+
+// 按上级公司 assigneeKey 的上级，没有配置组织架构时保持原来的行为 直接返回 assigneeKey
+func resolveByParentCompany(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	managerMutex.RLock()
+	configured := currentManagerProvider != nil
+	managerMutex.RUnlock()
+	if !configured {
+		return Assignment{Assignee: assigneeKey}, nil
+	}
+	manager, err := getManager(assigneeKey)
+	if err != nil {
+		return Assignment{}, err
+	}
+	return Assignment{Assignee: manager}, nil
+}
+
+// 流程发起人 在当前事务里读取流程实例，启动流程时流程实例还没有提交
+func processInitiator(ctx *WorkflowContext) (string, error) {
+	if ctx.Tx == nil {
+		return "", fmt.Errorf("failed to get transaction from ctx")
+	}
+	instance, err := GetServiceFactory().GetRuntimeService().LockProcessInstance(ctx.Tx, ctx.ProcessInstanceId)
+	if err != nil {
+		return "", err
+	}
+	if instance == nil {
+		return "", fmt.Errorf("%w: id %d", ErrProcessInstanceNotFound, ctx.ProcessInstanceId)
+	}
+	return instance.CreatedBy, nil
+}
+
+func resolveByInitiator(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	initiator, err := processInitiator(ctx)
+	if err != nil {
+		return Assignment{}, err
+	}
+	return Assignment{Assignee: initiator}, nil
+}
+
+func resolveByInitiatorManager(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	initiator, err := processInitiator(ctx)
+	if err != nil {
+		return Assignment{}, err
+	}
+	manager, err := getManager(initiator)
+	if err != nil {
+		return Assignment{}, err
+	}
+	return Assignment{Assignee: manager}, nil
+}
+
+// 按角色 角色作为候选组，角色里的成员通过 UserGroupsProvider 认领
+func resolveByRole(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	return Assignment{CandidateGroups: splitCandidates(assigneeKey)}, nil
+}
+
+// 按表达式 assigneeKey 是基于流程变量的表达式，结果是一个人时直接分配，是数组时作为候选人
+type expressionAssigneeResolver struct{}
+
+func (expressionAssigneeResolver) ResolveAssignee(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	parameters, err := resolveExpressionParameters(ctx, assigneeKey)
+	if err != nil {
+		return Assignment{}, err
+	}
+	result, err := EvaluateExpression(assigneeKey, parameters)
+	if err != nil {
+		return Assignment{}, err
+	}
+	switch value := result.(type) {
+	case string:
+		return Assignment{Assignee: value}, nil
+	case expressionList:
+		var users []string
+		for _, item := range value {
+			user, ok := item.(string)
+			if !ok {
+				return Assignment{}, fmt.Errorf("expression %s contains invalid user %v", assigneeKey, item)
+			}
+			users = append(users, user)
+		}
+		if len(users) == 1 {
+			return Assignment{Assignee: users[0]}, nil
+		}
+		return Assignment{CandidateUsers: users}, nil
+	}
+	return Assignment{}, fmt.Errorf("expression %s is %T, expected a user or an array of users", assigneeKey, result)
+}
+
+func (expressionAssigneeResolver) ValidateAssigneeKey(model *Model, assigneeKey string) error {
+	_, err := parseExpression(assigneeKey)
+	return err
+}
+
+// 和某个节点同一个负责人 assigneeKey 是节点的结构id，取这个节点最近一次完成时的负责人
+type sameAsNodeAssigneeResolver struct{}
+
+func (sameAsNodeAssigneeResolver) ResolveAssignee(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+	node, err := GetServiceFactory().GetNodeService().GetLatestCompletedNodeInstance(ctx.Tx, ctx.ProcessInstanceId, assigneeKey)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if node == nil {
+		return Assignment{}, fmt.Errorf("node %s has not been completed", assigneeKey)
+	}
+	return Assignment{Assignee: node.Assignee}, nil
+}
+
+func (sameAsNodeAssigneeResolver) ValidateAssigneeKey(model *Model, assigneeKey string) error {
+	if _, ok := model.Tasks[assigneeKey]; !ok {
+		return fmt.Errorf("%s is not a task of the process", assigneeKey)
+	}
+	return nil
+}
+
+// 部署时检查每个审批节点和定时器的负责人指定方式都注册了解析器
+func assigneeProblems(model *Model) []ModelProblem {
+	var problems []ModelProblem
+	check := func(executionId string, assigneeType string, assigneeKey string) {
+		resolver, ok := getAssigneeResolver(assigneeType)
+		if !ok {
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_ASSIGNEE, Message: fmt.Sprintf("assignee resolver %s is not registered", assigneeType)})
+			return
+		}
+		if validator, ok := resolver.(AssigneeKeyValidator); ok {
+			if err := validator.ValidateAssigneeKey(model, assigneeKey); err != nil {
+				problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_ASSIGNEE, Message: err.Error()})
+			}
+		}
+	}
+	for executionId, task := range model.Tasks {
+		switch {
+		case task.AssigneeType != "":
+			check(executionId, task.AssigneeType, task.AssigneeKey)
+		case task.MultiInstance == nil && !task.hasCandidates():
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_ASSIGNEE, Message: "task has no assigneeType and no candidates"})
+		}
+		for _, timer := range task.BoundaryTimers {
+			if timer.AssigneeType != "" {
+				check(timer.ExecutionId, timer.AssigneeType, timer.AssigneeKey)
+			}
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].ExecutionId < problems[j].ExecutionId })
+	return problems
+}
@@ -0,0 +1,122 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// t0 固定由 asg-first 审批，t1 按给定的方式指定负责人
+func assigneeXML(name string, assigneeType string, assigneeKey string) []byte {
+	return []byte(fmt.Sprintf(`<Process name="%s">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="asg-first"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <Task executionId="t1" name="T1" assigneeType="%s" assigneeKey="%s"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="t1"/>
+  <SequenceFlow executionId="f2" sourceRef="t1" targetRef="e"/>
+</Process>`, name, assigneeType, assigneeKey))
+}
+
+// 部署并启动流程 完成 t0 之后返回 t1
+func resolveSecondTask(t *testing.T, name string, assigneeType string, assigneeKey string, initiator string, formParams string) *NodeInstance {
+	t.Helper()
+	deployXML(t, name, assigneeXML(name, assigneeType, assigneeKey))
+	id := startProcess(t, name, initiator, formParams)
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "asg-first", nil)
+	return activeTask(t, id, "t1")
+}
+
+func TestResolveByInitiator(t *testing.T) {
+	task := resolveSecondTask(t, "assigneeInitiator", ASSIGNEETYPE_INITIATOR, "", "asg-ann", "")
+	if task.Assignee != "asg-ann" {
+		t.Fatalf("assignee = %s", task.Assignee)
+	}
+}
+
+func TestResolveByInitiatorManager(t *testing.T) {
+	SetManagerProvider(ManagerFunc(func(userId string) (string, error) {
+		if userId == "asg-ann" {
+			return "asg-boss", nil
+		}
+		return "", fmt.Errorf("%s has no manager", userId)
+	}))
+	t.Cleanup(func() { SetManagerProvider(nil) })
+
+	task := resolveSecondTask(t, "assigneeManager", ASSIGNEETYPE_INITIATOR_MANAGER, "", "asg-ann", "")
+	if task.Assignee != "asg-boss" {
+		t.Fatalf("assignee = %s", task.Assignee)
+	}
+}
+
+// 角色作为候选组 角色里的成员认领
+func TestResolveByRole(t *testing.T) {
+	SetUserGroupsProvider(UserGroupsFunc(func(userId string) ([]string, error) {
+		if userId == "asg-carl" {
+			return []string{"asg-auditor"}, nil
+		}
+		return nil, nil
+	}))
+	t.Cleanup(func() { SetUserGroupsProvider(nil) })
+
+	task := resolveSecondTask(t, "assigneeRole", ASSIGNEETYPE_ROLE, "asg-auditor", "asg-ann", "")
+	if task.Assignee != "" {
+		t.Fatalf("role task assignee = %s", task.Assignee)
+	}
+	if !hasUserTask(t, "asg-carl", task.Id) || hasUserTask(t, "asg-dave", task.Id) {
+		t.Fatal("role task is not offered to exactly the role members")
+	}
+	if err := GetServiceFactory().GetRuntimeService().ClaimTask(task.Id, "asg-carl"); err != nil {
+		t.Fatal(err)
+	}
+	completeTaskAs(t, task.Id, "asg-carl", nil)
+}
+
+// 表达式结果是一个人时直接分配 是数组时作为候选人
+func TestResolveByExpression(t *testing.T) {
+	task := resolveSecondTask(t, "assigneeExpression", ASSIGNEETYPE_EXPRESSION, "approver", "asg-ann", `{"approver":"asg-eve"}`)
+	if task.Assignee != "asg-eve" {
+		t.Fatalf("assignee = %s", task.Assignee)
+	}
+
+	task = resolveSecondTask(t, "assigneeExpression", ASSIGNEETYPE_EXPRESSION, "reviewers", "asg-ann", `{"reviewers":["asg-eve","asg-fay"]}`)
+	if task.Assignee != "" {
+		t.Fatalf("candidate task assignee = %s", task.Assignee)
+	}
+	if !hasUserTask(t, "asg-eve", task.Id) || !hasUserTask(t, "asg-fay", task.Id) {
+		t.Fatal("expression candidates do not see the task")
+	}
+}
+
+// 取 t0 实际完成时的负责人 转办之后是新的负责人
+func TestResolveBySameAsNode(t *testing.T) {
+	deployXML(t, "assigneeSameAsNode", assigneeXML("assigneeSameAsNode", ASSIGNEETYPE_SAME_AS_NODE, "t0"))
+	id := startProcess(t, "assigneeSameAsNode", "asg-ann", "")
+	t0 := activeTask(t, id, "t0")
+	if err := GetServiceFactory().GetRuntimeService().TransferTask(t0.Id, "asg-first", "asg-gus", ""); err != nil {
+		t.Fatal(err)
+	}
+	completeTaskAs(t, t0.Id, "asg-gus", nil)
+	if task := activeTask(t, id, "t1"); task.Assignee != "asg-gus" {
+		t.Fatalf("assignee = %s", task.Assignee)
+	}
+}
+
+func TestDeployRejectsUnknownAssigneeResolver(t *testing.T) {
+	cases := map[string][]byte{
+		"unregistered type": assigneeXML("assigneeUnknown", "ByAstrology", "asg-ann"),
+		"unknown node":      assigneeXML("assigneeUnknown", ASSIGNEETYPE_SAME_AS_NODE, "missing"),
+	}
+	for name, xmlContent := range cases {
+		err := tryDeployXML("assigneeUnknown", xmlContent)
+		var validationErr *ModelValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: deploy error = %v", name, err)
+			continue
+		}
+		if problems := validationErr.Problems; len(problems) != 1 || problems[0].Code != PROBLEM_ASSIGNEE || problems[0].ExecutionId != "t1" {
+			t.Errorf("%s: problems = %+v", name, problems)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package components
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 异步执行器的默认配置
+const (
+	DEFAULT_ASYNC_BATCH_SIZE   = 100
+	DEFAULT_ASYNC_MAX_ATTEMPTS = 3                // 失败这么多次后移到死信表
+	DEFAULT_ASYNC_BACKOFF      = 10 * time.Second // 第一次失败后的等待时间 之后每次翻倍
+	MAX_ASYNC_BACKOFF          = time.Hour
+)
+
+// 流程走到异步节点 记录一条异步任务后当前事务就可以提交了
+func scheduleAsyncJob(ctx *WorkflowContext, executionId string) error {
+	if ctx.Tx == nil {
+		return errNilTransaction(executionId)
+	}
+	jobService := GetServiceFactory().GetJobService()
+	_, err := jobService.CreateAsyncJob(ctx.Tx, &AsyncJob{
+		ProcessInstanceId:   ctx.ProcessInstanceId,
+		ExecutionId:         executionId,
+		PreviousExecutionId: ctx.CurrentExecutionId,
+		DueTime:             now(),
+	})
+	if err != nil {
+		return newExecutionError(executionId, ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// AsyncExecutor 异步任务执行器 一个协程扫描到期的异步任务，分给固定数量的工作协程执行，每个异步任务一个事务
+// 执行失败时回滚，记录错误后按指数退避推迟，失败次数用完移到死信表；也可以不启动协程，由外部调用 RunDueJobs
+type AsyncExecutor struct {
+	workers     int
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	backoff     time.Duration
+
+	mu       sync.Mutex
+	stop     chan struct{}
+	done     sync.WaitGroup
+	inflight map[int]bool // 已经分给工作协程还没有执行完的异步任务 避免下一轮重复分发
+}
+
+// NewAsyncExecutor 创建异步执行器 workers 是工作协程数量，interval 是扫描间隔
+func NewAsyncExecutor(workers int, interval time.Duration) *AsyncExecutor {
+	if workers <= 0 {
+		workers = 1
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &AsyncExecutor{
+		workers:     workers,
+		interval:    interval,
+		batchSize:   DEFAULT_ASYNC_BATCH_SIZE,
+		maxAttempts: DEFAULT_ASYNC_MAX_ATTEMPTS,
+		backoff:     DEFAULT_ASYNC_BACKOFF,
+		inflight:    make(map[int]bool),
+	}
+}
+
+// SetRetryPolicy 设置最多失败几次以及第一次失败后的等待时间，需要在 Start 之前调用
+func (executor *AsyncExecutor) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if maxAttempts > 0 {
+		executor.maxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		executor.backoff = backoff
+	}
+}
+
+// Start 启动扫描协程和工作协程 已经启动时什么都不做
+func (executor *AsyncExecutor) Start() {
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if executor.stop != nil {
+		return
+	}
+	executor.stop = make(chan struct{})
+	jobs := make(chan AsyncJob)
+	executor.done.Add(executor.workers + 1)
+	for i := 0; i < executor.workers; i++ {
+		go executor.work(jobs)
+	}
+	go executor.poll(executor.stop, jobs)
+}
+
+// Stop 停止扫描 等待工作协程执行完手上的异步任务后返回
+func (executor *AsyncExecutor) Stop() {
+	executor.mu.Lock()
+	stop := executor.stop
+	executor.stop = nil
+	executor.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	executor.done.Wait()
+}
+
+func (executor *AsyncExecutor) poll(stop chan struct{}, jobs chan AsyncJob) {
+	defer executor.done.Done()
+	defer close(jobs)
+	ticker := time.NewTicker(executor.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		due, err := GetServiceFactory().GetJobService().GetDueAsyncJobs(now(), executor.batchSize)
+		if err != nil {
+			log.Println("Failed to get due async jobs: ", err)
+			continue
+		}
+		for _, job := range due {
+			if !executor.claim(job.Id) {
+				continue
+			}
+			select {
+			case jobs <- job:
+			case <-stop:
+				executor.release(job.Id)
+				return
+			}
+		}
+	}
+}
+
+func (executor *AsyncExecutor) work(jobs chan AsyncJob) {
+	defer executor.done.Done()
+	for job := range jobs {
+		if _, err := executor.execute(job); err != nil {
+			log.Printf("Async job %d failed: %v", job.Id, err)
+		}
+		executor.release(job.Id)
+	}
+}
+
+func (executor *AsyncExecutor) claim(id int) bool {
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if executor.inflight[id] {
+		return false
+	}
+	executor.inflight[id] = true
+	return true
+}
+
+func (executor *AsyncExecutor) release(id int) {
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	delete(executor.inflight, id)
+}
+
+// RunDueJobs 在当前协程里执行已经到期的异步任务，返回执行成功的数量
+func (executor *AsyncExecutor) RunDueJobs() (int, error) {
+	jobService := GetServiceFactory().GetJobService()
+	jobs, err := jobService.GetDueAsyncJobs(now(), executor.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	executed := 0
+	var errs []error
+	for _, job := range jobs {
+		ok, err := executor.execute(job)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("async job %d: %w", job.Id, err))
+			continue
+		}
+		if ok {
+			executed++
+		}
+	}
+	return executed, errors.Join(errs...)
+}
+
+// 执行一个异步任务 失败时在新的事务里记录失败，返回节点执行的错误
+func (executor *AsyncExecutor) execute(job AsyncJob) (bool, error) {
+	jobService := GetServiceFactory().GetJobService()
+	tx, err := jobService.GetTransaction()
+	if err != nil {
+		return false, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	executed, err := executeAsyncJobInTx(jobService, tx, job)
+	if err = finishTransaction(tx, err); err == nil {
+		return executed, nil
+	}
+	if errors.Is(err, ErrNoMatchingFlow) {
+		// 重试也走不下去 异步任务保留，流程实例标记为故障，恢复之后再执行
+		return false, raiseIncident(GetServiceFactory().GetRuntimeService(), job.ProcessInstanceId, err)
+	}
+	if recordErr := executor.recordFailure(jobService, job, err); recordErr != nil {
+		return false, errors.Join(err, recordErr)
+	}
+	return false, err
+}
+
+// 加锁顺序和完成审批节点一致：先流程实例 再异步任务
+func executeAsyncJobInTx(jobService JobService, tx *sql.Tx, job AsyncJob) (bool, error) {
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	instance, err := runtimeService.LockProcessInstance(tx, job.ProcessInstanceId)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance != nil && processInstancePaused(instance.Status) {
+		return false, nil
+	}
+	locked, err := jobService.LockAsyncJob(tx, job.Id)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if locked == nil || locked.DueTime.After(now()) {
+		// 已经被其他执行器处理 或者刚刚失败被推迟了
+		return false, nil
+	}
+	if err := jobService.DeleteAsyncJob(tx, locked.Id); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance == nil || instance.Status != PROCESS_STATUS_RUNNING {
+		return false, nil
+	}
+
+	model, err := getModelByVersion(instance.ProcessDefinitionName, instance.Version)
+	if err != nil {
+		return false, err
+	}
+	node, ok := model.AllData[locked.ExecutionId]
+	if !ok {
+		return false, newExecutionError(locked.ExecutionId, ErrNodeNotFound, fmt.Errorf("node is not defined in process %s", model.ProcessDefinitionName))
+	}
+	ctx := &WorkflowContext{
+		Model:                 model,
+		ProcessInstanceId:     instance.Id,
+		ProcessDefinitionName: instance.ProcessDefinitionName,
+		CurrentUserId:         SYSTEM_USER_NOBODY,
+		CurrentExecutionId:    locked.PreviousExecutionId,
+		StartTime:             instance.StartTime,
+		Tx:                    tx,
+	}
+	// 直接执行节点 不再经过 executeNode 的异步判断
+	return true, node.Execute(ctx)
+}
+
+// 记录失败 次数用完时移到死信表
+func (executor *AsyncExecutor) recordFailure(jobService JobService, job AsyncJob, cause error) error {
+	tx, err := jobService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, executor.recordFailureInTx(jobService, tx, job, cause))
+}
+
+func (executor *AsyncExecutor) recordFailureInTx(jobService JobService, tx *sql.Tx, job AsyncJob, cause error) error {
+	if _, err := GetServiceFactory().GetRuntimeService().LockProcessInstance(tx, job.ProcessInstanceId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	locked, err := jobService.LockAsyncJob(tx, job.Id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if locked == nil {
+		return nil
+	}
+	executor.mu.Lock()
+	maxAttempts, backoff := executor.maxAttempts, executor.backoff
+	executor.mu.Unlock()
+
+	attempts := locked.Attempts + 1
+	if err := jobService.UpdateAsyncJobFailure(tx, locked.Id, attempts, now().Add(retryDelay(backoff, attempts)), cause.Error()); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if attempts >= maxAttempts {
+		log.Printf("Async job %d (%s) moved to dead letter after %d attempts: %v", locked.Id, locked.ExecutionId, attempts, cause)
+		if err := jobService.MoveAsyncJobToDeadLetter(tx, locked.Id); err != nil {
+			return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+		}
+	}
+	return nil
+}
+
+// 第 attempts 次失败后等待的时间 从 backoff 开始每次翻倍，最多等 MAX_ASYNC_BACKOFF，定时任务的重试也用它
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff << (attempts - 1)
+	if delay <= 0 || delay > MAX_ASYNC_BACKOFF {
+		delay = MAX_ASYNC_BACKOFF
+	}
+	return delay
+}
+
+// RetryDeadLetterJob 把死信任务放回异步任务表，失败次数清零并立即到期，返回新的异步任务id
+// 一般在修复了处理函数或者外部系统之后调用
+func RetryDeadLetterJob(deadLetterJobId int) (int, error) {
+	jobService := GetServiceFactory().GetJobService()
+	tx, err := jobService.GetTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	id, err := retryDeadLetterJobInTx(jobService, tx, deadLetterJobId)
+	if err := finishTransaction(tx, err); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func retryDeadLetterJobInTx(jobService JobService, tx *sql.Tx, deadLetterJobId int) (int, error) {
+	job, err := jobService.LockDeadLetterJob(tx, deadLetterJobId)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if job == nil {
+		return 0, fmt.Errorf("%w: dead letter job %d", ErrJobNotFound, deadLetterJobId)
+	}
+	id, err := jobService.CreateAsyncJob(tx, &AsyncJob{
+		ProcessInstanceId:   job.ProcessInstanceId,
+		ExecutionId:         job.ExecutionId,
+		PreviousExecutionId: job.PreviousExecutionId,
+		DueTime:             now(),
+		LastError:           job.LastError,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if err := jobService.DeleteDeadLetterJob(tx, deadLetterJobId); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return id, nil
+}
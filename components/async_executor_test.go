@@ -0,0 +1,129 @@
+package components
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	asyncHandlerMu    = make(chan struct{}, 1)
+	asyncHandlerError error
+)
+
+func init() {
+	RegisterServiceHandler("asyncTestHandler", func(ctx *WorkflowContext, variables map[string]any) (map[string]any, error) {
+		asyncHandlerMu <- struct{}{}
+		defer func() { <-asyncHandlerMu }()
+		if asyncHandlerError != nil {
+			return nil, asyncHandlerError
+		}
+		return map[string]any{"done": true}, nil
+	})
+}
+
+func setAsyncHandlerError(err error) {
+	asyncHandlerMu <- struct{}{}
+	defer func() { <-asyncHandlerMu }()
+	asyncHandlerError = err
+}
+
+const asyncServiceXML = `<Process name="asyncService">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <ServiceTask executionId="sv" name="SV" handler="asyncTestHandler" async="true"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></ServiceTask>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="async-user"><Incoming>f2</Incoming><Outgoing>f3</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f3</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="sv"/>
+  <SequenceFlow executionId="f2" sourceRef="sv" targetRef="t"/>
+  <SequenceFlow executionId="f3" sourceRef="t" targetRef="e"/>
+</Process>`
+
+// 流程实例还没有执行的异步任务
+func asyncJobsOf(t *testing.T, processInstanceId int) []AsyncJob {
+	t.Helper()
+	jobs, err := GetServiceFactory().GetJobService().GetDueAsyncJobs(now().Add(1000*time.Hour), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []AsyncJob
+	for _, job := range jobs {
+		if job.ProcessInstanceId == processInstanceId {
+			result = append(result, job)
+		}
+	}
+	return result
+}
+
+func TestAsyncServiceTaskRetriesAndMovesToDeadLetter(t *testing.T) {
+	clock := useManualClock(t)
+	setAsyncHandlerError(errors.New("remote system down"))
+	t.Cleanup(func() { setAsyncHandlerError(nil) })
+	deployXML(t, "asyncService", []byte(asyncServiceXML))
+	id := startProcess(t, "asyncService", "async-ann", "")
+	terminateOnCleanup(t, id)
+
+	// 启动的事务里只登记异步任务 不调用处理函数
+	if jobs := asyncJobsOf(t, id); len(jobs) != 1 || jobs[0].ExecutionId != "sv" {
+		t.Fatalf("async jobs after start = %+v", jobs)
+	}
+	executor := NewAsyncExecutor(1, time.Hour)
+	executor.SetRetryPolicy(2, time.Minute)
+	if _, err := executor.RunDueJobs(); !errors.Is(err, ErrHandlerFailed) {
+		t.Fatalf("first run error = %v", err)
+	}
+	jobs := asyncJobsOf(t, id)
+	if len(jobs) != 1 || jobs[0].Attempts != 1 || !jobs[0].DueTime.Equal(now().Add(time.Minute)) {
+		t.Fatalf("async jobs after first failure = %+v", jobs)
+	}
+	if done, err := executor.RunDueJobs(); done != 0 || err != nil {
+		t.Fatalf("run during backoff = %d %v", done, err)
+	}
+
+	clock.advance(2 * time.Minute)
+	if _, err := executor.RunDueJobs(); !errors.Is(err, ErrHandlerFailed) {
+		t.Fatalf("second run error = %v", err)
+	}
+	if jobs := asyncJobsOf(t, id); len(jobs) != 0 {
+		t.Fatalf("async jobs after dead letter = %+v", jobs)
+	}
+	deadLetters, err := GetServiceFactory().GetJobService().GetDeadLetterJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deadLetter *DeadLetterJob
+	for i := range deadLetters {
+		if deadLetters[i].ProcessInstanceId == id {
+			deadLetter = &deadLetters[i]
+		}
+	}
+	if deadLetter == nil || deadLetter.Attempts != 2 || deadLetter.LastError == "" {
+		t.Fatalf("dead letter jobs = %+v", deadLetters)
+	}
+
+	setAsyncHandlerError(nil)
+	if _, err := RetryDeadLetterJob(deadLetter.Id); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := executor.RunDueJobs(); done != 1 || err != nil {
+		t.Fatalf("run after retry = %d %v", done, err)
+	}
+	activeTask(t, id, "t")
+}
+
+func TestAsyncOnlyAllowedOnServiceTask(t *testing.T) {
+	xmlContent := `<Process name="asyncOnTask">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="async-user" async="true"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>
+  <SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>
+</Process>`
+	err := tryDeployXML("asyncOnTask", []byte(xmlContent))
+	var validationErr *ModelValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("deploy error = %v", err)
+	}
+	if len(validationErr.Problems) != 1 || validationErr.Problems[0].Code != PROBLEM_ASYNC || validationErr.Problems[0].ExecutionId != "t" {
+		t.Fatalf("problems = %+v", validationErr.Problems)
+	}
+}
@@ -0,0 +1,38 @@
+package components
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 定时器计算到期时间和调度器判断是否到期用的时钟，测试里可以换成手动拨动的时钟
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+var (
+	currentClock Clock = systemClock{}
+	clockMutex   sync.RWMutex
+)
+
+// SetClock 替换定时器使用的时钟 传 nil 恢复为系统时钟
+func SetClock(clock Clock) {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	if clock == nil {
+		clock = systemClock{}
+	}
+	currentClock = clock
+}
+
+func now() time.Time {
+	clockMutex.RLock()
+	defer clockMutex.RUnlock()
+	return currentClock.Now()
+}
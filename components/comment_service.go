@@ -0,0 +1,143 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Comment 流程实例或者审批节点上的评论 NodeInstanceId 为 0 时是流程实例上的评论
+// 节点迁移到历史表时 id 不变，评论和附件不删除，依然通过 id 关联到历史节点
+type Comment struct {
+	Id                int
+	ProcessInstanceId int
+	NodeInstanceId    int
+	UserId            string
+	Message           string
+	CreatedAt         time.Time
+}
+
+// Attachment 附件的元数据 文件本身由业务服务存储，这里只记录存储地址和校验和
+type Attachment struct {
+	Id                int
+	ProcessInstanceId int
+	NodeInstanceId    int // 为 0 时是流程实例上的附件
+	Name              string
+	ContentType       string
+	URI               string // 文件的存储地址
+	Checksum          string // 文件的校验和 例如 sha256:...
+	CreatedBy         string
+	CreatedAt         time.Time
+}
+
+// CommentService 提供了操作评论表和附件表的接口
+type CommentService interface {
+	GetTransaction() (*sql.Tx, error)
+	//新增评论 返回自增id
+	AddComment(tx *sql.Tx, comment *Comment) (int, error)
+	//查询评论 nodeInstanceId 为 0 时查询流程实例的全部评论，按id排序
+	GetComments(processInstanceId int, nodeInstanceId int) ([]Comment, error)
+	//新增附件 返回自增id
+	AddAttachment(tx *sql.Tx, attachment *Attachment) (int, error)
+	//查询附件 nodeInstanceId 为 0 时查询流程实例的全部附件，按id排序
+	GetAttachments(processInstanceId int, nodeInstanceId int) ([]Attachment, error)
+}
+
+// 校验评论和附件关联的流程实例和审批节点 流程结束之后还可以在流程实例和已经归档的审批节点上补充
+func checkCommentTarget(runtimeService RuntimeService, processInstanceId int, taskId int) error {
+	if taskId != 0 {
+		node, err := GetServiceFactory().GetNodeService().GetNodeInstanceById(taskId)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+		}
+		if node == nil {
+			// 流程结束之后运行表被清理，审批节点只在历史表里
+			node, err = GetServiceFactory().GetHistoryService().GetHistoricNodeInstanceById(taskId)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+			}
+		}
+		if node == nil || node.ProcessInstanceId != processInstanceId {
+			return fmt.Errorf("%w: id %d in process instance %d", ErrTaskNotFound, taskId, processInstanceId)
+		}
+		return nil
+	}
+	instance, err := runtimeService.GetProcessInstanceById(processInstanceId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance != nil {
+		return nil
+	}
+	historic, err := GetServiceFactory().GetHistoryService().GetHistoricProcessInstanceById(processInstanceId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if historic == nil {
+		return fmt.Errorf("%w: id %d", ErrProcessInstanceNotFound, processInstanceId)
+	}
+	return nil
+}
+
+// 各数据库实现共用的新增评论逻辑
+func addComment(runtimeService RuntimeService, processInstanceId int, taskId int, userId string, message string) (int, error) {
+	if strings.TrimSpace(userId) == "" || strings.TrimSpace(message) == "" {
+		return 0, fmt.Errorf("%w: user and message of comment must not be empty", ErrInvalidInput)
+	}
+	if err := checkCommentTarget(runtimeService, processInstanceId, taskId); err != nil {
+		return 0, err
+	}
+	commentService := GetServiceFactory().GetCommentService()
+	tx, err := commentService.GetTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	id, err := commentService.AddComment(tx, &Comment{ProcessInstanceId: processInstanceId, NodeInstanceId: taskId, UserId: userId, Message: message, CreatedAt: time.Now()})
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return id, finishTransaction(tx, err)
+}
+
+// 各数据库实现共用的查询评论逻辑
+func listComments(processInstanceId int, taskId int) ([]Comment, error) {
+	comments, err := GetServiceFactory().GetCommentService().GetComments(processInstanceId, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return comments, nil
+}
+
+// 各数据库实现共用的新增附件逻辑
+func addAttachment(runtimeService RuntimeService, attachment Attachment) (int, error) {
+	if strings.TrimSpace(attachment.Name) == "" || strings.TrimSpace(attachment.URI) == "" {
+		return 0, fmt.Errorf("%w: name and uri of attachment must not be empty", ErrInvalidInput)
+	}
+	if strings.TrimSpace(attachment.CreatedBy) == "" {
+		return 0, fmt.Errorf("%w: creator of attachment must not be empty", ErrInvalidInput)
+	}
+	if err := checkCommentTarget(runtimeService, attachment.ProcessInstanceId, attachment.NodeInstanceId); err != nil {
+		return 0, err
+	}
+	commentService := GetServiceFactory().GetCommentService()
+	tx, err := commentService.GetTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	attachment.CreatedAt = time.Now()
+	id, err := commentService.AddAttachment(tx, &attachment)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return id, finishTransaction(tx, err)
+}
+
+// 各数据库实现共用的查询附件逻辑
+func listAttachments(processInstanceId int, taskId int) ([]Attachment, error) {
+	attachments, err := GetServiceFactory().GetCommentService().GetAttachments(processInstanceId, taskId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return attachments, nil
+}
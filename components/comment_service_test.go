@@ -0,0 +1,93 @@
+package components
+
+import (
+	"errors"
+	"testing"
+)
+
+const commentXML = `<Process name="commentArchived">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="cmt-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f1</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="e"/>
+</Process>`
+
+// 流程结束之后运行表被清理 还可以在已经归档的审批节点上评论
+func TestCommentOnArchivedTask(t *testing.T) {
+	deployXML(t, "commentArchived", []byte(commentXML))
+	id := startProcess(t, "commentArchived", "cmt-ann", "")
+	task := activeTask(t, id, "t0")
+	completeTaskAs(t, task.Id, "cmt-user", map[string]any{"approved": true})
+
+	node, err := GetServiceFactory().GetNodeService().GetNodeInstanceById(task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node != nil {
+		t.Fatalf("task %d is still in the runtime table", task.Id)
+	}
+
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if _, err := runtimeService.AddComment(id, task.Id, "cmt-ann", "looks good"); err != nil {
+		t.Fatalf("comment on archived task: %v", err)
+	}
+	comments, err := runtimeService.ListComments(id, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 1 || comments[0].Message != "looks good" || comments[0].UserId != "cmt-ann" {
+		t.Fatalf("comments = %+v", comments)
+	}
+
+	// 归档的审批节点也要属于这个流程实例
+	if _, err := runtimeService.AddComment(id+1000000, task.Id, "cmt-ann", "wrong instance"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("comment with wrong process instance error = %v", err)
+	}
+}
+
+// 附件挂在流程实例或者审批节点上 审批节点归档之后还能查询和继续添加
+func TestAttachmentsOnInstanceAndTask(t *testing.T) {
+	deployXML(t, "commentArchived", []byte(commentXML))
+	id := startProcess(t, "commentArchived", "cmt-ann", "")
+	task := activeTask(t, id, "t0")
+	runtimeService := GetServiceFactory().GetRuntimeService()
+
+	if _, err := runtimeService.AddAttachment(Attachment{ProcessInstanceId: id, Name: "invoice.pdf", CreatedBy: "cmt-ann"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("attachment without uri error = %v", err)
+	}
+	if _, err := runtimeService.AddAttachment(Attachment{ProcessInstanceId: id + 1000000, NodeInstanceId: task.Id, Name: "x", URI: "s3://x", CreatedBy: "cmt-ann"}); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("attachment with wrong process instance error = %v", err)
+	}
+	instanceAttachment, err := runtimeService.AddAttachment(Attachment{ProcessInstanceId: id, Name: "contract.pdf", ContentType: "application/pdf", URI: "s3://docs/contract.pdf", Checksum: "sha256:aa", CreatedBy: "cmt-ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskAttachment, err := runtimeService.AddAttachment(Attachment{ProcessInstanceId: id, NodeInstanceId: task.Id, Name: "receipt.png", URI: "s3://docs/receipt.png", CreatedBy: "cmt-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := runtimeService.ListAttachments(id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Id != instanceAttachment || all[1].Id != taskAttachment {
+		t.Fatalf("process attachments = %+v", all)
+	}
+	if all[0].NodeInstanceId != 0 || all[0].Checksum != "sha256:aa" || all[0].CreatedAt.IsZero() {
+		t.Fatalf("instance attachment = %+v", all[0])
+	}
+
+	completeTaskAs(t, task.Id, "cmt-user", nil)
+	if _, err := runtimeService.AddAttachment(Attachment{ProcessInstanceId: id, NodeInstanceId: task.Id, Name: "signed.pdf", URI: "s3://docs/signed.pdf", CreatedBy: "cmt-ann"}); err != nil {
+		t.Fatalf("attachment on archived task: %v", err)
+	}
+	onTask, err := runtimeService.ListAttachments(id, task.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(onTask) != 2 || onTask[0].Id != taskAttachment || onTask[1].Name != "signed.pdf" {
+		t.Fatalf("task attachments after archiving = %+v", onTask)
+	}
+}
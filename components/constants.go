@@ -3,13 +3,40 @@ package components
 // 定义包级常量
 const (
 	//数据库名称
-	MYSQL_DBNAME = "mysql"
+	MYSQL_DBNAME    = "mysql"
+	POSTGRES_DBNAME = "postgres"
+	SQLITE_DBNAME   = "sqlite3"
+	MEMORY_DBNAME   = "memory"
 	//获取委托人的方式
-	ASSIGNEETYPE_NAME    = "ByAssigneeName"
-	ASSIGNEETYPE_COMPANY = "ByParentCompany"
+	ASSIGNEETYPE_NAME              = "ByAssigneeName"     // assigneeKey 就是负责人
+	ASSIGNEETYPE_COMPANY           = "ByParentCompany"    // assigneeKey 的上级
+	ASSIGNEETYPE_INITIATOR         = "ByInitiator"        // 流程发起人
+	ASSIGNEETYPE_INITIATOR_MANAGER = "ByInitiatorManager" // 流程发起人的上级
+	ASSIGNEETYPE_ROLE              = "ByRole"             // assigneeKey 是角色 角色里的成员认领
+	ASSIGNEETYPE_EXPRESSION        = "ByExpression"       // assigneeKey 是基于流程变量的表达式
+	ASSIGNEETYPE_SAME_AS_NODE      = "BySameAsNode"       // 和 assigneeKey 这个节点同一个负责人
 	//系统角色
 	SYSTEM_USER_NOBODY = "nobody"
 	//组件名称
 	PARALLEL_GATEWAY  = "parallelGateway"
 	EXCLUSIVE_GATEWAY = "exclusiveGateway"
+	INCLUSIVE_GATEWAY = "inclusiveGateway"
+	//流程实例的状态
+	PROCESS_STATUS_RUNNING    = "running"
+	PROCESS_STATUS_SUSPENDED  = "suspended"
+	PROCESS_STATUS_COMPLETE   = "complete"
+	PROCESS_STATUS_TERMINATED = "terminated"
+	PROCESS_STATUS_INCIDENT   = "incident" // 流程走不下去了 比如互斥网关没有条件成立的出线，需要人工处理后恢复
+	//历史节点的状态
+	NODE_STATUS_COMPLETED  = "completed"  // 正常完成
+	NODE_STATUS_SENT_BACK  = "sentBack"   // 负责人打回
+	NODE_STATUS_CANCELLED  = "cancelled"  // 打回时被一起取消的下游审批节点
+	NODE_STATUS_TERMINATED = "terminated" // 流程实例被终止时还没有完成的审批节点
+	NODE_STATUS_TIMED_OUT  = "timedOut"   // 边界定时器到期 审批节点被跳过走定时器的出线
+	NODE_STATUS_FAILED     = "failed"     // 服务节点的处理函数重试后依然失败 走错误出线
+	//定时器到期后的动作
+	TIMER_ACTION_COMPLETE = "complete" // 自动完成审批节点 输出 {"timedOut":true}
+	TIMER_ACTION_ESCALATE = "escalate" // 升级给上级 没有配置负责人时交给当前负责人的上级，需要 SetManagerProvider
+	TIMER_ACTION_REASSIGN = "reassign" // 转派给配置的负责人
+	TIMER_ACTION_FLOW     = "flow"     // 走定时器的出线 审批节点以超时状态结束
 )
@@ -3,11 +3,10 @@ package components
 import (
 	"encoding/xml"
 	"fmt"
-	"io/ioutil"
 	"os"
 )
 
-// ParseXML 解析BPMN XML内容并将其转换为Model对象
+// ParseXMLByte 解析BPMN XML内容并将其转换为Model对象
 func ParseXMLByte(byteValue []byte) (*Model, error) {
 	// Unmarshal XML数据
 	var process Process // 假设在 elements 包中定义了 Process 结构体
@@ -41,70 +40,40 @@ func ParseXMLByte(byteValue []byte) (*Model, error) {
 		model.AddExclusiveGateway(gateway.ExecutionId, gateway)
 	}
 
+	// 添加 InclusiveGateway 包容网关 到 Model
+	for _, gateway := range process.InclusiveGateways {
+		model.AddInclusiveGateway(gateway.ExecutionId, gateway)
+	}
+
 	// 添加 EndEvent 结束事件 到 Model
 	for _, endEvent := range process.EndEvents {
 		model.AddEndEvent(endEvent.ExecutionId, endEvent)
 	}
 
+	// 添加 IntermediateTimerEvent 中间定时事件 到 Model
+	for _, timerEvent := range process.IntermediateTimerEvents {
+		model.AddIntermediateTimerEvent(timerEvent.ExecutionId, timerEvent)
+	}
+
+	// 添加 ServiceTask 服务节点 到 Model
+	for _, serviceTask := range process.ServiceTasks {
+		model.AddServiceTask(serviceTask.ExecutionId, serviceTask)
+	}
+
 	// 添加 SequenceFlow 序列流 到 Model
 	for _, flow := range process.SequenceFlows {
 		model.AddSequenceFlow(flow.ExecutionId, flow)
 	}
 
+	model.analyzeBranches()
 	return model, nil
 }
 
 // ParseXML 解析BPMN XML文件并将其转换为Model对象
 func ParseXML(filename string) (*Model, error) {
-	// 读取XML文件
-	xmlFile, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open XML file: %v", err)
-	}
-	defer xmlFile.Close()
-
-	byteValue, _ := ioutil.ReadAll(xmlFile)
-
-	// Unmarshal XML数据
-	var process Process
-	err = xml.Unmarshal(byteValue, &process)
+	byteValue, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal XML: %v", err)
-	}
-
-	// 使用流程的名称创建一个新的Model
-	model := NewModel(process.Name)
-	// 遍历所有的XML元素并添加到Model中
-
-	// 添加 StartEvent 到 Model
-	for _, startEvent := range process.StartEvents {
-		model.AddStartEvent(startEvent.ExecutionId, startEvent)
-	}
-
-	// 添加 Task 到 Model
-	for _, task := range process.Tasks {
-		model.AddTask(task.ExecutionId, task)
-	}
-
-	// 添加 ParallelGateway 到 Model
-	for _, gateway := range process.ParallelGateways {
-		model.AddParallelGateway(gateway.ExecutionId, gateway)
-	}
-
-	// 添加 ExclusiveGateway 到 Model
-	for _, gateway := range process.ExclusiveGateways {
-		model.AddExclusiveGateway(gateway.ExecutionId, gateway)
+		return nil, fmt.Errorf("failed to read XML file: %v", err)
 	}
-
-	// 添加 EndEvent 到 Model
-	for _, endEvent := range process.EndEvents {
-		model.AddEndEvent(endEvent.ExecutionId, endEvent)
-	}
-
-	// 添加 SequenceFlow 到 Model
-	for _, flow := range process.SequenceFlows {
-		model.AddSequenceFlow(flow.ExecutionId, flow)
-	}
-
-	return model, nil
+	return ParseXMLByte(byteValue)
 }
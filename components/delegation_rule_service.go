@@ -0,0 +1,137 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DelegationRule 代理规则 用户不在的时间段里分配给这个用户的审批节点自动交给代理人
+type DelegationRule struct {
+	Id                    int
+	UserId                string    // 不在的用户
+	Substitute            string    // 代理人
+	StartTime             time.Time // 开始时间 包含
+	EndTime               time.Time // 结束时间 不包含
+	ProcessDefinitionName string    // 只对这个流程生效 为空时对全部流程生效
+	CreatedBy             string
+	CreatedAt             time.Time
+}
+
+// DelegationRuleService 提供了操作代理规则表的接口
+type DelegationRuleService interface {
+	GetTransaction() (*sql.Tx, error)
+	//新增代理规则 返回自增id
+	AddDelegationRule(rule *DelegationRule) (int, error)
+	//删除代理规则 提前回来时使用，已经交给代理人的审批节点不会收回
+	DeleteDelegationRule(id int) error
+	//查询用户的全部代理规则 按开始时间排序
+	GetDelegationRules(userId string) ([]DelegationRule, error)
+	//在事务里查询某个时间点对某个流程生效的代理规则 按id排序
+	GetActiveDelegationRules(tx *sql.Tx, userId string, processDefinitionName string, at time.Time) ([]DelegationRule, error)
+}
+
+// 各数据库实现共用的代理规则校验
+func validateDelegationRule(rule *DelegationRule) error {
+	rule.UserId = strings.TrimSpace(rule.UserId)
+	rule.Substitute = strings.TrimSpace(rule.Substitute)
+	rule.ProcessDefinitionName = strings.TrimSpace(rule.ProcessDefinitionName)
+	if rule.UserId == "" || rule.Substitute == "" {
+		return fmt.Errorf("%w: user and substitute must not be empty", ErrInvalidInput)
+	}
+	if rule.UserId == rule.Substitute {
+		return fmt.Errorf("%w: user %s cannot be their own substitute", ErrInvalidInput, rule.UserId)
+	}
+	if !rule.EndTime.After(rule.StartTime) {
+		return fmt.Errorf("%w: end time of delegation rule must be after start time", ErrInvalidInput)
+	}
+	return nil
+}
+
+// 查找用户当前的代理人 只对某个流程生效的规则优先，同样范围的规则新建的优先
+// 代理人自己也不在时继续往下找，出现循环时停在循环之前的最后一个人
+func findSubstitute(tx *sql.Tx, userId string, processDefinitionName string, at time.Time) (string, *DelegationRule, error) {
+	service := GetServiceFactory().GetDelegationRuleService()
+	visited := []string{userId}
+	var first *DelegationRule
+	current := userId
+	for {
+		rules, err := service.GetActiveDelegationRules(tx, current, processDefinitionName, at)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(rules) == 0 {
+			break
+		}
+		rule := rules[len(rules)-1]
+		for _, candidate := range rules {
+			if candidate.ProcessDefinitionName != "" && (rule.ProcessDefinitionName == "" || candidate.Id > rule.Id) {
+				rule = candidate
+			}
+		}
+		if slices.Contains(visited, rule.Substitute) {
+			break
+		}
+		if first == nil {
+			first = &rule
+		}
+		visited = append(visited, rule.Substitute)
+		current = rule.Substitute
+	}
+	return current, first, nil
+}
+
+// 按代理规则把刚分配的审批节点交给代理人 原来的负责人记录在 OriginalAssignee，同时记录一条操作历史
+func applyDelegationRules(ctx *WorkflowContext, node *NodeInstance) error {
+	return applyDelegationRulesInTx(ctx.Tx, ctx.ProcessDefinitionName, node)
+}
+
+// 认领、委派、转办和前加签换了负责人之后 新的负责人不在时同样交给代理人
+func substituteAssignee(tx *sql.Tx, node *NodeInstance, assignee string) error {
+	changed := *node
+	changed.Assignee = assignee
+	if err := applyDelegationRulesInTx(tx, node.ProcessDefinitionName, &changed); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// OriginalAssignee 只记录第一次被代理的负责人 之后再被代理也不覆盖
+func applyDelegationRulesInTx(tx *sql.Tx, processDefinitionName string, node *NodeInstance) error {
+	if node.Assignee == "" {
+		return nil
+	}
+	substitute, rule, err := findSubstitute(tx, node.Assignee, processDefinitionName, now())
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return nil
+	}
+	originalAssignee := node.OriginalAssignee
+	if originalAssignee == "" {
+		originalAssignee = node.Assignee
+	}
+	if err := GetServiceFactory().GetNodeService().UpdateNodeInstanceSubstitute(tx, node.Id, substitute, originalAssignee); err != nil {
+		return err
+	}
+	err = GetServiceFactory().GetHistoryService().AddTaskOperation(tx, TaskOperation{
+		ProcessInstanceId: node.ProcessInstanceId,
+		NodeInstanceId:    node.Id,
+		ExecutionId:       node.ExecutionId,
+		Operation:         TASK_OPERATION_AUTO_DELEGATE,
+		FromUser:          node.Assignee,
+		ToUser:            substitute,
+		Reason:            fmt.Sprintf("delegation rule %d", rule.Id),
+		CreatedBy:         SYSTEM_USER_NOBODY,
+		CreatedAt:         time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	node.OriginalAssignee = originalAssignee
+	node.Assignee = substitute
+	return nil
+}
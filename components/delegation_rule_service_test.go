@@ -0,0 +1,109 @@
+package components
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const outOfOfficeXML = `<Process name="outOfOffice">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="ooo-alice"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>
+  <SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>
+</Process>`
+
+// 添加代理规则 测试结束时删除
+func addDelegationRule(t *testing.T, rule DelegationRule) {
+	t.Helper()
+	rules := GetServiceFactory().GetDelegationRuleService()
+	id, err := rules.AddDelegationRule(&rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rules.DeleteDelegationRule(id) })
+}
+
+func nodeInstance(t *testing.T, id int) *NodeInstance {
+	t.Helper()
+	node, err := GetServiceFactory().GetNodeService().GetNodeInstanceById(id)
+	if err != nil || node == nil {
+		t.Fatalf("get node instance %d: %v", id, err)
+	}
+	return node
+}
+
+func TestOutOfOfficeOwnerSurvivesDelegation(t *testing.T) {
+	addDelegationRule(t, DelegationRule{UserId: "ooo-alice", Substitute: "ooo-bob", ProcessDefinitionName: "outOfOffice", StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)})
+	deployXML(t, "outOfOffice", []byte(outOfOfficeXML))
+	id := startProcess(t, "outOfOffice", "ooo-ann", "")
+	terminateOnCleanup(t, id)
+
+	task := activeTask(t, id, "t")
+	if task.Assignee != "ooo-bob" || task.OriginalAssignee != "ooo-alice" || task.Owner != "" {
+		t.Fatalf("task after auto delegation = %s %s %q", task.Assignee, task.OriginalAssignee, task.Owner)
+	}
+
+	// 代理人再委派 委派的所有人是代理人，不覆盖原来的负责人
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.DelegateTask(task.Id, "ooo-bob", "ooo-carol", "please check"); err != nil {
+		t.Fatal(err)
+	}
+	node := nodeInstance(t, task.Id)
+	if node.Assignee != "ooo-carol" || node.Owner != "ooo-bob" || node.OriginalAssignee != "ooo-alice" {
+		t.Fatalf("task after delegation = %s %s %s", node.Assignee, node.Owner, node.OriginalAssignee)
+	}
+	if err := runtimeService.ResolveTask(task.Id, "ooo-carol", "checked"); err != nil {
+		t.Fatal(err)
+	}
+	if node := nodeInstance(t, task.Id); node.Assignee != "ooo-bob" || node.OriginalAssignee != "ooo-alice" {
+		t.Fatalf("task after resolve = %s %s", node.Assignee, node.OriginalAssignee)
+	}
+
+	operations, err := GetServiceFactory().GetHistoryService().GetTaskOperations(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 3 || operations[0].Operation != TASK_OPERATION_AUTO_DELEGATE || operations[0].FromUser != "ooo-alice" {
+		t.Fatalf("task operations = %+v", operations)
+	}
+	completeTaskAs(t, task.Id, "ooo-bob", nil)
+}
+
+func TestExpiredDelegationRuleIsIgnored(t *testing.T) {
+	addDelegationRule(t, DelegationRule{UserId: "ooo-dave", Substitute: "ooo-erin", StartTime: time.Now().Add(-2 * time.Hour), EndTime: time.Now().Add(-time.Hour)})
+	deployXML(t, "outOfOfficeExpired", []byte(strings.NewReplacer(`name="outOfOffice"`, `name="outOfOfficeExpired"`, "ooo-alice", "ooo-dave").Replace(outOfOfficeXML)))
+	id := startProcess(t, "outOfOfficeExpired", "ooo-ann", "")
+	terminateOnCleanup(t, id)
+	if task := activeTask(t, id, "t"); task.Assignee != "ooo-dave" || task.OriginalAssignee != "" {
+		t.Fatalf("task with expired rule = %s %s", task.Assignee, task.OriginalAssignee)
+	}
+}
+
+// 转办给不在的人 同样交给他的代理人，原来的负责人记录转办的目标
+func TestTransferToAbsentUserGoesToSubstitute(t *testing.T) {
+	addDelegationRule(t, DelegationRule{UserId: "ooo-fred", Substitute: "ooo-gina", StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)})
+	deployXML(t, "outOfOfficeTransfer", []byte(strings.NewReplacer(`name="outOfOffice"`, `name="outOfOfficeTransfer"`, "ooo-alice", "ooo-dan").Replace(outOfOfficeXML)))
+	id := startProcess(t, "outOfOfficeTransfer", "ooo-ann", "")
+	terminateOnCleanup(t, id)
+
+	task := activeTask(t, id, "t")
+	if err := GetServiceFactory().GetRuntimeService().TransferTask(task.Id, "ooo-dan", "ooo-fred", "on leave"); err != nil {
+		t.Fatal(err)
+	}
+	if node := nodeInstance(t, task.Id); node.Assignee != "ooo-gina" || node.OriginalAssignee != "ooo-fred" || node.Owner != "" {
+		t.Fatalf("task after transfer = %s %s %q", node.Assignee, node.OriginalAssignee, node.Owner)
+	}
+	operations, err := GetServiceFactory().GetHistoryService().GetTaskOperations(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[0].Operation != TASK_OPERATION_TRANSFER || operations[1].Operation != TASK_OPERATION_AUTO_DELEGATE || operations[1].FromUser != "ooo-fred" || operations[1].ToUser != "ooo-gina" {
+		t.Fatalf("task operations = %+v", operations)
+	}
+	if _, err := GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "ooo-fred", nil); err == nil {
+		t.Fatal("absent transfer target completed the task")
+	}
+	completeTaskAs(t, task.Id, "ooo-gina", nil)
+}
@@ -1,7 +1,5 @@
 package components
 
-import "log"
-
 type EndEvent struct {
 	ExecutionId string `xml:"executionId,attr"` // 绑定 id 属性
 	Name        string `xml:"name,attr"`
@@ -14,44 +12,48 @@ type EndEvent struct {
 }
 
 // 方法接收器是 *StartEvent，允许修改 StartEvent 的字段
-func (endEvent EndEvent) Execute(ctx *WorkflowContext) {
+func (endEvent EndEvent) Execute(ctx *WorkflowContext) error {
 	//更新数据库流程实例状态 迁移数据到历史表
 	nodeService := GetServiceFactory().GetNodeService()
 	tx := ctx.Tx
 	if tx == nil {
-		log.Println("Failed to get transaction from ctx ")
-		return
+		return errNilTransaction(endEvent.ExecutionId)
 	}
 	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, endEvent.Name, endEvent.ExecutionId, ctx.CurrentExecutionId, "")
 	if initerr != nil {
-		log.Println("Failed to insert endEvent to database: ", initerr)
-		tx.Rollback()
-		return
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, initerr)
 	}
 	//迁徙数据到历史库
 	historyService := GetServiceFactory().GetHistoryService()
 	_, he := historyService.CopyNodeInstance(tx, nodeId, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, endEvent.Name, endEvent.ExecutionId, ctx.CurrentExecutionId, "")
 	if he != nil {
-		log.Println("Failed to insert endEvent to history: ", he)
-		tx.Rollback()
-		return
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, he)
 	}
 	//更新数据库任务状态
 	runtimeService := GetServiceFactory().GetRuntimeService()
 	completeerr := runtimeService.CompleteProcessInstance(tx, ctx.ProcessInstanceId)
 	if completeerr != nil {
-		log.Println("Failed to complete: ", completeerr)
-		tx.Rollback()
-		return
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, completeerr)
 	}
-	//删除当前流程实例的数据
+	//删除当前流程实例的数据 包括还没有触发的定时任务和异步任务
 	clearerr := nodeService.ClearProcessData(tx, ctx.ProcessInstanceId)
 	if clearerr != nil {
-		log.Println("Failed to ClearProcessData from database: ", clearerr)
-		tx.Rollback()
-		return
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, clearerr)
+	}
+	if joberr := clearProcessJobs(tx, ctx.ProcessInstanceId); joberr != nil {
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, joberr)
+	}
+	//运行中的变量也一起删除 变量历史表里有每一次修改的记录
+	if varerr := GetServiceFactory().GetVariableService().DeleteVariablesByProcessInstance(tx, ctx.ProcessInstanceId); varerr != nil {
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, varerr)
+	}
+	//流程实例归档到历史表 记录走到的结束事件
+	if archiveerr := archiveProcessInstance(runtimeService, tx, ctx.ProcessInstanceId, endEvent.ExecutionId); archiveerr != nil {
+		return newExecutionError(endEvent.ExecutionId, ErrPersistenceFailed, archiveerr)
 	}
 
-	RunListener(endEvent.Listener, ctx)
-	tx.Commit()
+	if listenerErr := RunListener(endEvent.Listener, ctx); listenerErr != nil {
+		return newExecutionError(endEvent.ExecutionId, ErrListenerFailed, listenerErr)
+	}
+	return nil
 }
@@ -0,0 +1,66 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 节点执行失败的错误类别 调用方用 errors.Is 判断属于哪一类
+var (
+	ErrNodeNotFound      = errors.New("node not found")
+	ErrExpressionFailed  = errors.New("expression failed")
+	ErrPersistenceFailed = errors.New("persistence failed")
+	ErrListenerFailed    = errors.New("listener failed")
+	ErrHandlerFailed     = errors.New("service handler failed")
+	ErrNoMatchingFlow    = errors.New("no matching sequence flow")
+	ErrAssigneeFailed    = errors.New("assignee resolution failed")
+	ErrInvalidInput      = errors.New("invalid input")
+)
+
+// 操作审批任务时的错误
+var (
+	ErrTaskNotFound         = errors.New("task not found")
+	ErrTaskAlreadyCompleted = errors.New("task already completed")
+	ErrNotTaskAssignee      = errors.New("user is not the task assignee")
+	ErrInvalidSendBack      = errors.New("invalid send back target")
+	ErrTaskAlreadyClaimed   = errors.New("task already claimed")
+	ErrNotTaskCandidate     = errors.New("user is not a task candidate")
+	ErrTaskDelegated        = errors.New("task is delegated")
+)
+
+// 操作流程实例时的错误
+var (
+	ErrProcessInstanceNotFound = errors.New("process instance not found")
+	ErrProcessSuspended        = errors.New("process instance is suspended")
+	ErrProcessNotRunning       = errors.New("process instance is not running")
+	ErrProcessIncident         = errors.New("process instance has an incident")
+)
+
+// 操作定时任务和异步任务时的错误
+var (
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// ExecutionError 节点执行失败时返回的错误，记录出错的节点结构id，用 errors.As 取出
+type ExecutionError struct {
+	ExecutionId string // 出错节点的结构id
+	Kind        error  // 错误类别 上面定义的 Err* 之一
+	Err         error  // 原始错误
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.ExecutionId, e.Kind, e.Err)
+}
+
+func (e *ExecutionError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func newExecutionError(executionId string, kind error, err error) error {
+	return &ExecutionError{ExecutionId: executionId, Kind: kind, Err: err}
+}
+
+// 事务为空时统一返回的错误
+func errNilTransaction(executionId string) error {
+	return newExecutionError(executionId, ErrPersistenceFailed, errors.New("failed to get transaction from ctx"))
+}
@@ -0,0 +1,93 @@
+package components
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 错误里取出 ExecutionError 核对出错的节点和错误类别
+func requireExecutionError(t *testing.T, err error, executionId string, kind error) {
+	t.Helper()
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("error %v is not an execution error", err)
+	}
+	if execErr.ExecutionId != executionId || execErr.Kind != kind || execErr.Err == nil {
+		t.Fatalf("execution error = %s %v %v, want %s %v", execErr.ExecutionId, execErr.Kind, execErr.Err, executionId, kind)
+	}
+	if !errors.Is(err, kind) {
+		t.Fatalf("errors.Is(%v, %v) = false", err, kind)
+	}
+}
+
+// 修改已经部署的流程定义 模拟校验之前部署的旧版本
+func patchDeployedXML(t *testing.T, name string, old string, new string) {
+	t.Helper()
+	tx, err := GetServiceFactory().GetRepositoryService().GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = memoryExec(tx, func(data *memoryData) error {
+		for definitionId, pd := range data.processDefinitions {
+			if pd.ProcessDefinitionName == name {
+				pd.XMLContent = []byte(strings.Replace(string(pd.XMLContent), old, new, 1))
+				data.processDefinitions[definitionId] = pd
+			}
+		}
+		return nil
+	})
+	if err := FinishTransaction(tx, err); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNoMatchingFlowExecutionError(t *testing.T) {
+	deployXML(t, "errorsNoMatchStart", []byte(exclusiveAfterStartXML))
+	_, err := tryStartProcess("errorsNoMatchStart", "err-ann", `{"amount":0}`)
+	requireExecutionError(t, err, "x1", ErrNoMatchingFlow)
+	if errors.Is(err, ErrExpressionFailed) {
+		t.Fatalf("no matching flow is reported as an expression failure: %v", err)
+	}
+
+	deployXML(t, "errorsNoMatchTask", exclusiveGatewayXML("errorsNoMatchTask", ""))
+	id := startProcess(t, "errorsNoMatchTask", "err-ann", "")
+	terminateOnCleanup(t, id)
+	_, err = GetServiceFactory().GetRuntimeService().CompleteTask(activeTask(t, id, "t0").Id, "exc-user", map[string]any{"amount": 0})
+	requireExecutionError(t, err, "x1", ErrNoMatchingFlow)
+}
+
+// 条件里的数据不能比较 报出错的序列流，不标记故障
+func TestExpressionFailureExecutionError(t *testing.T) {
+	deployXML(t, "errorsExpressionStart", []byte(exclusiveAfterStartXML))
+	_, err := tryStartProcess("errorsExpressionStart", "err-ann", `{"amount":"many"}`)
+	requireExecutionError(t, err, "fa", ErrExpressionFailed)
+
+	deployXML(t, "errorsExpressionTask", exclusiveGatewayXML("errorsExpressionTask", "fc"))
+	id := startProcess(t, "errorsExpressionTask", "err-ann", "")
+	terminateOnCleanup(t, id)
+	task := activeTask(t, id, "t0")
+	_, err = GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "exc-user", map[string]any{"amount": "many"})
+	requireExecutionError(t, err, "fa", ErrExpressionFailed)
+	if status := processStatus(t, id); status != PROCESS_STATUS_RUNNING {
+		t.Fatalf("status = %s", status)
+	}
+	if activeTask(t, id, "t0").Id != task.Id {
+		t.Fatal("task was completed although the expression failed")
+	}
+}
+
+// 序列流指向模型里没有的节点 报缺少的节点
+func TestMissingNodeExecutionError(t *testing.T) {
+	deployXML(t, "errorsMissingNode", []byte(commentXML))
+	id := startProcess(t, "errorsMissingNode", "err-ann", "")
+	terminateOnCleanup(t, id)
+	patchDeployedXML(t, "errorsMissingNode", `sourceRef="t0" targetRef="e"`, `sourceRef="t0" targetRef="missing"`)
+
+	task := activeTask(t, id, "t0")
+	_, err := GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "cmt-user", nil)
+	requireExecutionError(t, err, "missing", ErrNodeNotFound)
+	if activeTask(t, id, "t0").Id != task.Id {
+		t.Fatal("task was completed although the next node is missing")
+	}
+}
@@ -1,6 +1,6 @@
 package components
 
-import "log"
+import "fmt"
 
 type ExclusiveGateway struct {
 	ExecutionId string   `xml:"executionId,attr"` // 绑定 id 属性
@@ -10,43 +10,67 @@ type ExclusiveGateway struct {
 	Y           string   `XML:"y,attr"`
 	H           string   `XML:"h,attr"`
 	W           string   `XML:"w,attr"`
-	Listener    string   `xml:"Listener"` // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
+	Default     string   `xml:"default,attr"` // 默认出线的结构id 其他出线的条件都不成立时走这一条，默认出线上的条件不计算
+	Listener    string   `xml:"Listener"`     // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
 }
 
-// 方法接收器是 *StartEvent，允许修改 StartEvent 的字段
-// 逻辑根并行网关是一样的 除了不需要等待所有的Incoming 到齐，触发一次就全部执行一次outgoing，因为互斥网关后所有的序列流是有条件表达式的，可以自行控制是否继续走
-func (exclusiveGateway ExclusiveGateway) Execute(ctx *WorkflowContext) {
+// 进入互斥网关后按 Outgoing 的顺序计算条件，只走第一条条件成立的序列流
+// 都不成立时走默认出线，没有默认出线时返回错误，事务回滚后流程实例被标记为故障
+// 启动流程时就走不下去的话流程实例随事务一起回滚，不标记故障
+func (exclusiveGateway ExclusiveGateway) Execute(ctx *WorkflowContext) error {
 	//序列流进入该方法 记录入库
 	nodeService := GetServiceFactory().GetNodeService()
 	tx := ctx.Tx
 	if tx == nil {
-		log.Println("Failed to get transaction from ctx ")
-		return
+		return errNilTransaction(exclusiveGateway.ExecutionId)
 	}
 	//StartNodeInstance(processInstanceId int, nodeName string, executionId string, previousExecutionId string, assignee string) (int, error)
 	//进入互斥网关的序列流只有一条 直接根据表达式条件判断 走下一步 流程不会停止
 	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, EXCLUSIVE_GATEWAY, exclusiveGateway.ExecutionId, ctx.CurrentExecutionId, SYSTEM_USER_NOBODY)
 	if initerr != nil {
-		log.Println("Failed to insert exclusiveGateway to database: ", initerr)
-		tx.Rollback()
-		return
+		return newExecutionError(exclusiveGateway.ExecutionId, ErrPersistenceFailed, initerr)
 	}
 
 	//网关数据 只要插入一条 就往历史表里同步一条
 	historyService := GetServiceFactory().GetHistoryService()
 	_, copyerr := historyService.CopyNodeInstance(tx, nodeId, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, EXCLUSIVE_GATEWAY, exclusiveGateway.ExecutionId, ctx.CurrentExecutionId, SYSTEM_USER_NOBODY)
 	if copyerr != nil {
-		log.Println("Failed to copy history: ", copyerr)
-		tx.Rollback()
-		return
+		return newExecutionError(exclusiveGateway.ExecutionId, ErrPersistenceFailed, copyerr)
 	}
 
 	//执行监听
-	RunListener(exclusiveGateway.Listener, ctx)
+	if listenerErr := RunListener(exclusiveGateway.Listener, ctx); listenerErr != nil {
+		return newExecutionError(exclusiveGateway.ExecutionId, ErrListenerFailed, listenerErr)
+	}
 
 	ctx.CurrentExecutionId = exclusiveGateway.ExecutionId
+	sequenceFlow, err := exclusiveGateway.selectFlow(ctx)
+	if err != nil {
+		return err
+	}
+	return sequenceFlow.Take(ctx)
+}
+
+// 按顺序找到第一条条件成立的出线 后面的条件不再计算
+func (exclusiveGateway ExclusiveGateway) selectFlow(ctx *WorkflowContext) (SequenceFlow, error) {
 	for _, value := range exclusiveGateway.Outgoing {
-		model := *(ctx.Model)
-		model.SequenceFlows[value].Execute(ctx)
+		if value == exclusiveGateway.Default {
+			continue
+		}
+		sequenceFlow, err := lookupSequenceFlow(ctx, value)
+		if err != nil {
+			return sequenceFlow, err
+		}
+		pass, err := sequenceFlow.Evaluate(ctx)
+		if err != nil {
+			return sequenceFlow, err
+		}
+		if pass {
+			return sequenceFlow, nil
+		}
+	}
+	if exclusiveGateway.Default != "" {
+		return lookupSequenceFlow(ctx, exclusiveGateway.Default)
 	}
+	return SequenceFlow{}, newExecutionError(exclusiveGateway.ExecutionId, ErrNoMatchingFlow, fmt.Errorf("no outgoing sequence flow condition is true and no default flow is defined"))
 }
@@ -0,0 +1,100 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// 审批节点之后的互斥网关 fa 和 fb 的条件可能同时成立，fc 是默认出线
+func exclusiveGatewayXML(name string, defaultFlow string) []byte {
+	return []byte(fmt.Sprintf(`<Process name="%s">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="exc-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <ExclusiveGateway executionId="x1" default="%s"><Incoming>f1</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing><Outgoing>fc</Outgoing></ExclusiveGateway>
+  <Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="exc-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing></Task>
+  <Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="exc-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>
+  <Task executionId="c" name="C" assigneeType="ByAssigneeName" assigneeKey="exc-user"><Incoming>fc</Incoming><Outgoing>fc2</Outgoing></Task>
+  <EndEvent executionId="ea"><Incoming>fa2</Incoming></EndEvent>
+  <EndEvent executionId="eb"><Incoming>fb2</Incoming></EndEvent>
+  <EndEvent executionId="ec"><Incoming>fc2</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="x1"/>
+  <SequenceFlow executionId="fa" sourceRef="x1" targetRef="a"><ConditionExpression>t0.amount &gt; 3</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fb" sourceRef="x1" targetRef="b"><ConditionExpression>t0.amount &gt; 1</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fc" sourceRef="x1" targetRef="c"><ConditionExpression>t0.amount &gt; 100</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fa2" sourceRef="a" targetRef="ea"/>
+  <SequenceFlow executionId="fb2" sourceRef="b" targetRef="eb"/>
+  <SequenceFlow executionId="fc2" sourceRef="c" targetRef="ec"/>
+</Process>`, name, defaultFlow))
+}
+
+func TestExclusiveGatewayTakesFirstMatchingFlow(t *testing.T) {
+	deployXML(t, "exclusiveFirst", exclusiveGatewayXML("exclusiveFirst", "fc"))
+	id := startProcess(t, "exclusiveFirst", "exc-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "exc-user", map[string]any{"amount": 5})
+
+	activeTask(t, id, "a")
+	if findActiveNode(t, id, "b") != nil || findActiveNode(t, id, "c") != nil {
+		t.Fatal("more than one outgoing flow was taken")
+	}
+}
+
+func TestExclusiveGatewayTakesDefaultFlow(t *testing.T) {
+	deployXML(t, "exclusiveDefault", exclusiveGatewayXML("exclusiveDefault", "fc"))
+	id := startProcess(t, "exclusiveDefault", "exc-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "exc-user", map[string]any{"amount": 0})
+
+	// 默认出线上的条件不计算
+	activeTask(t, id, "c")
+}
+
+func TestExclusiveGatewayWithoutMatchRaisesIncident(t *testing.T) {
+	deployXML(t, "exclusiveNone", exclusiveGatewayXML("exclusiveNone", ""))
+	id := startProcess(t, "exclusiveNone", "exc-ann", "")
+	task := activeTask(t, id, "t0")
+	_, err := GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "exc-user", map[string]any{"amount": 0})
+	if !errors.Is(err, ErrNoMatchingFlow) {
+		t.Fatalf("complete error = %v", err)
+	}
+	if status := processStatus(t, id); status != PROCESS_STATUS_INCIDENT {
+		t.Fatalf("status = %s", status)
+	}
+
+	// 修正流程变量后恢复 重新完成审批节点
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.ResolveIncident(id); err != nil {
+		t.Fatal(err)
+	}
+	completeTaskAs(t, task.Id, "exc-user", map[string]any{"amount": 2})
+	activeTask(t, id, "b")
+}
+
+const exclusiveAfterStartXML = `<Process name="exclusiveAfterStart">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <ExclusiveGateway executionId="x1"><Incoming>f0</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing></ExclusiveGateway>
+  <Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="exc-start-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing></Task>
+  <Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="exc-start-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>
+  <EndEvent executionId="ea"><Incoming>fa2</Incoming></EndEvent>
+  <EndEvent executionId="eb"><Incoming>fb2</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="x1"/>
+  <SequenceFlow executionId="fa" sourceRef="x1" targetRef="a"><ConditionExpression>amount &gt; 1000</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fb" sourceRef="x1" targetRef="b"><ConditionExpression>amount &lt;= 1000 &amp;&amp; amount &gt; 0</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fa2" sourceRef="a" targetRef="ea"/>
+  <SequenceFlow executionId="fb2" sourceRef="b" targetRef="eb"/>
+</Process>`
+
+// 启动时就没有可以走的出线 启动直接失败，不留下流程实例
+func TestExclusiveGatewayWithoutMatchFailsStart(t *testing.T) {
+	deployXML(t, "exclusiveAfterStart", []byte(exclusiveAfterStartXML))
+	before := countProcessInstances(t, "exclusiveAfterStart")
+	if _, err := tryStartProcess("exclusiveAfterStart", "exc-start-ann", `{"amount":0}`); !errors.Is(err, ErrNoMatchingFlow) {
+		t.Fatalf("start error = %v", err)
+	}
+	if after := countProcessInstances(t, "exclusiveAfterStart"); after != before {
+		t.Fatalf("process instances after failed start = %d, before %d", after, before)
+	}
+
+	id := startProcess(t, "exclusiveAfterStart", "exc-start-ann", `{"amount":1500}`)
+	activeTask(t, id, "a")
+}
@@ -2,57 +2,139 @@ package components
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
+	"sync"
 )
 
+// Executor 流程里的节点和序列流 执行失败时返回错误，事务由流程的入口统一提交或回滚
 type Executor interface {
-	Execute(ctx *WorkflowContext) // 修改接口以使用 WorkflowContext
+	Execute(ctx *WorkflowContext) error // 修改接口以使用 WorkflowContext
 }
 
-// 执行后续的监听逻辑
-func RunListener(Listener string, ctx *WorkflowContext) string {
-	listenerArr := strings.Split(Listener, ",")
-	var temp string = ""
-	for _, value := range listenerArr {
-		temp += value
+// asyncExecutor 可以标记为异步执行的节点
+type asyncExecutor interface {
+	isAsync() bool
+}
+
+// 根据结构id找到模型里的节点并执行 异步节点只记录一条异步任务，由异步执行器在新的事务里执行
+func executeNode(ctx *WorkflowContext, executionId string) error {
+	node, ok := ctx.Model.AllData[executionId]
+	if !ok {
+		return newExecutionError(executionId, ErrNodeNotFound, fmt.Errorf("node is not defined in process %s", ctx.Model.ProcessDefinitionName))
+	}
+	if async, ok := node.(asyncExecutor); ok && async.isAsync() {
+		return scheduleAsyncJob(ctx, executionId)
 	}
-	testReflect()
-	return temp
+	return node.Execute(ctx)
 }
 
-// 反射测试
-type MyStruct struct{}
+// 根据结构id找到序列流
+func lookupSequenceFlow(ctx *WorkflowContext, flowId string) (SequenceFlow, error) {
+	flow, ok := ctx.Model.SequenceFlows[flowId]
+	if !ok {
+		return flow, newExecutionError(flowId, ErrNodeNotFound, fmt.Errorf("sequence flow is not defined in process %s", ctx.Model.ProcessDefinitionName))
+	}
+	return flow, nil
+}
 
-func (m MyStruct) Hello() {
-	fmt.Println("Hello, World!")
+// 根据结构id找到序列流并执行
+func executeSequenceFlow(ctx *WorkflowContext, flowId string) error {
+	flow, err := lookupSequenceFlow(ctx, flowId)
+	if err != nil {
+		return err
+	}
+	return flow.Execute(ctx)
 }
 
-func (m MyStruct) Greet(name string) {
-	fmt.Println("Hello,", name)
+// ListenerFunc 业务监听函数 节点执行完毕后按照 <Listener> 中配置的名称依次调用 返回错误时当前节点的事务会被回滚
+type ListenerFunc func(ctx *WorkflowContext) error
+
+var (
+	listenerRegistry = make(map[string]ListenerFunc)
+	listenerMutex    sync.RWMutex
+)
+
+// RegisterListener 注册一个具名的监听函数 业务服务在启动时调用 名称重复或者函数为空直接panic
+func RegisterListener(name string, listener ListenerFunc) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		panic("listener name must not be empty")
+	}
+	if listener == nil {
+		panic(fmt.Sprintf("listener %s must not be nil", name))
+	}
+
+	listenerMutex.Lock()
+	defer listenerMutex.Unlock()
+	if _, exists := listenerRegistry[name]; exists {
+		panic(fmt.Sprintf("listener %s is already registered", name))
+	}
+	listenerRegistry[name] = listener
 }
 
-func testReflect() {
+// GetListener 根据名称查找已注册的监听函数
+func GetListener(name string) (ListenerFunc, bool) {
+	listenerMutex.RLock()
+	defer listenerMutex.RUnlock()
+	listener, ok := listenerRegistry[name]
+	return listener, ok
+}
 
-	// 创建结构体实例
-	myStruct := MyStruct{}
+// 把 <Listener> 里逗号隔开的名称拆成数组 忽略空白
+func parseListenerNames(Listener string) []string {
+	var names []string
+	for _, value := range strings.Split(Listener, ",") {
+		name := strings.TrimSpace(value)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
 
-	// 获取反射对象
-	value := reflect.ValueOf(myStruct)
+// 执行后续的监听逻辑 按配置顺序调用 任意一个失败就停止并返回错误，由调用方回滚事务
+func RunListener(Listener string, ctx *WorkflowContext) error {
+	for _, name := range parseListenerNames(Listener) {
+		listener, ok := GetListener(name)
+		if !ok {
+			return fmt.Errorf("listener %s is not registered", name)
+		}
+		if err := listener(ctx); err != nil {
+			return fmt.Errorf("listener %s failed: %v", name, err)
+		}
+	}
+	return nil
+}
 
-	// 动态调用方法
-	methodName := "Hello"
-	method := value.MethodByName(methodName)
-	if method.IsValid() {
-		method.Call(nil) // 无参数
+// ValidateListeners 部署时校验模型中引用的监听是否都已注册，避免运行时才发现配置错误
+func ValidateListeners(model *Model) error {
+	if problems := listenerProblems(model); len(problems) > 0 {
+		return &ModelValidationError{ProcessDefinitionName: model.ProcessDefinitionName, Problems: problems}
 	}
+	return nil
+}
 
-	// 调用带参数的方法
-	methodName = "Greet"
-	method = value.MethodByName(methodName)
-	if method.IsValid() {
-		method.Call([]reflect.Value{reflect.ValueOf("Alice")}) // 带参数
-	} else {
-		fmt.Println("方法未找到")
+// 取出节点上配置的监听
+func nodeListener(node Executor) string {
+	switch element := node.(type) {
+	case StartEvent:
+		return element.Listener
+	case Task:
+		return element.Listener
+	case ParallelGateway:
+		return element.Listener
+	case ExclusiveGateway:
+		return element.Listener
+	case InclusiveGateway:
+		return element.Listener
+	case EndEvent:
+		return element.Listener
+	case IntermediateTimerEvent:
+		return element.Listener
+	case ServiceTask:
+		return element.Listener
+	case SequenceFlow:
+		return element.Listener
 	}
+	return ""
 }
@@ -0,0 +1,314 @@
+package components
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Knetic/govaluate"
+)
+
+// 条件表达式里的属性路径 结构id.字段 或者更深的 JSON 路径，例如 startEvent.applicant.dept
+// 字符串常量和已经用方括号转义的参数原样保留，只有第三个分组是属性路径
+// govaluate 的字符串常量以单引号或双引号开头，遇到任意一种引号就结束，反斜杠转义下一个字符
+var attributePathRegex = regexp.MustCompile(`(['"](?:\\.|[^'"\\])*['"])|(\[[^\]]*\])|\b([A-Za-z_]\w*(?:\.\w+)+)\b`)
+
+// 日期字符串支持的格式 和 govaluate 解析表达式里日期常量的格式一致
+var expressionTimeFormats = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05-07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
+// ExpressionFunction 条件表达式里可以调用的函数
+type ExpressionFunction = govaluate.ExpressionFunction
+
+var (
+	expressionFunctionRegistry = map[string]ExpressionFunction{
+		"contains":    containsFunction,
+		"daysBetween": daysBetweenFunction,
+		"date":        dateFunction,
+		"now":         nowFunction,
+	}
+	expressionFunctionMutex sync.RWMutex
+)
+
+// RegisterExpressionFunction 注册一个条件表达式里可以调用的函数 业务服务在启动时调用 名称重复或者函数为空直接panic
+func RegisterExpressionFunction(name string, function ExpressionFunction) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		panic("expression function name must not be empty")
+	}
+	if function == nil {
+		panic(fmt.Sprintf("expression function %s must not be nil", name))
+	}
+
+	expressionFunctionMutex.Lock()
+	defer expressionFunctionMutex.Unlock()
+	if _, exists := expressionFunctionRegistry[name]; exists {
+		panic(fmt.Sprintf("expression function %s is already registered", name))
+	}
+	expressionFunctionRegistry[name] = function
+}
+
+func expressionFunctions() map[string]govaluate.ExpressionFunction {
+	expressionFunctionMutex.RLock()
+	defer expressionFunctionMutex.RUnlock()
+	functions := make(map[string]govaluate.ExpressionFunction, len(expressionFunctionRegistry))
+	for name, function := range expressionFunctionRegistry {
+		functions[name] = function
+	}
+	return functions
+}
+
+// 解析条件表达式 属性路径转成 govaluate 的方括号参数，值通过参数传入，不再拼接到表达式里
+func parseExpression(expression string) (*govaluate.EvaluableExpression, error) {
+	escaped := attributePathRegex.ReplaceAllStringFunc(expression, func(match string) string {
+		if match[0] == '\'' || match[0] == '"' || match[0] == '[' {
+			return match
+		}
+		return "[" + match + "]"
+	})
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(escaped, expressionFunctions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expression: %v", err)
+	}
+	return expr, nil
+}
+
+// 数组参数 govaluate 会把 []interface{} 类型的参数和逗号分隔的函数参数拼在一起，
+// contains(t0.tags, 'x') 会变成三个参数，所以数组换成单独的类型传进去
+type expressionList []any
+
+// 参与比较的字符串参数转成可以比较的值 见 orderedParameters
+func expressionParameters(expr *govaluate.EvaluableExpression, parameters map[string]any) map[string]any {
+	ordered := orderedParameters(expr)
+	converted := make(map[string]any, len(parameters))
+	for name, value := range parameters {
+		if list, ok := value.([]any); ok {
+			value = expressionList(list)
+		}
+		if ordered[name] {
+			value = comparableValue(value)
+		}
+		converted[name] = value
+	}
+	return converted
+}
+
+// 比较大小的运算符
+var orderingComparators = map[string]bool{">": true, ">=": true, "<": true, "<=": true}
+
+// 相等比较的运算符
+var equalityComparators = map[string]bool{"==": true, "!=": true}
+
+// 需要转换的参数名称 表单提交的数字和日期都是字符串，govaluate 只能比较两个数字或者两个字符串
+// 大小比较时和数字、日期常量、函数或者其他参数比较都要转换，和字符串常量比较时保持按字符串比较
+// 相等比较只在另一边是数字或者日期常量时转换，两个参数之间或者和字符串常量仍然按字符串比较
+func orderedParameters(expr *govaluate.EvaluableExpression) map[string]bool {
+	tokens := expr.Tokens()
+	names := make(map[string]bool)
+	for i := 1; i < len(tokens)-1; i++ {
+		if tokens[i].Kind != govaluate.COMPARATOR {
+			continue
+		}
+		converts := func(other govaluate.ExpressionToken) bool { return other.Kind != govaluate.STRING }
+		switch comparator := fmt.Sprint(tokens[i].Value); {
+		case orderingComparators[comparator]:
+		case equalityComparators[comparator]:
+			converts = func(other govaluate.ExpressionToken) bool {
+				return other.Kind == govaluate.NUMERIC || other.Kind == govaluate.TIME
+			}
+		default:
+			continue
+		}
+		left, right := tokens[i-1], tokens[i+1]
+		if left.Kind == govaluate.VARIABLE && converts(right) {
+			names[fmt.Sprint(left.Value)] = true
+		}
+		if right.Kind == govaluate.VARIABLE && converts(left) {
+			names[fmt.Sprint(right.Value)] = true
+		}
+	}
+	return names
+}
+
+// 数字字符串转成数字 日期字符串转成和表达式里日期常量一样的秒级时间戳，其他值原样返回
+func comparableValue(value any) any {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	if number, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+		return number
+	}
+	if parsed, err := toTime(text); err == nil {
+		return float64(parsed.Unix())
+	}
+	return value
+}
+
+// 按路径从 JSON 值里取出字段 数组用数字下标
+func lookupPath(value any, path []string) (any, bool) {
+	for _, key := range path {
+		switch current := value.(type) {
+		case map[string]any:
+			next, ok := current[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// contains(容器, 元素) 字符串包含子串、数组包含元素、对象包含键
+func containsFunction(arguments ...any) (any, error) {
+	if len(arguments) != 2 {
+		return nil, fmt.Errorf("contains expects 2 arguments, got %d", len(arguments))
+	}
+	switch container := arguments[0].(type) {
+	case string:
+		item, ok := arguments[1].(string)
+		if !ok {
+			item = fmt.Sprint(arguments[1])
+		}
+		return strings.Contains(container, item), nil
+	case expressionList:
+		for _, element := range container {
+			if expressionValuesEqual(element, arguments[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := arguments[1].(string)
+		if !ok {
+			return false, nil
+		}
+		_, exists := container[key]
+		return exists, nil
+	case nil:
+		return false, nil
+	default:
+		return nil, fmt.Errorf("contains does not support %T", arguments[0])
+	}
+}
+
+// 数字统一按 float64 比较 其他类型按值比较
+func expressionValuesEqual(left any, right any) bool {
+	leftNumber, leftIsNumber := toFloat64(left)
+	rightNumber, rightIsNumber := toFloat64(right)
+	if leftIsNumber && rightIsNumber {
+		return leftNumber == rightNumber
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func toFloat64(value any) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case float32:
+		return float64(number), true
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	}
+	return 0, false
+}
+
+// daysBetween(开始, 结束) 两个日期相差的自然日天数 结束早于开始时为负数
+func daysBetweenFunction(arguments ...any) (any, error) {
+	if len(arguments) != 2 {
+		return nil, fmt.Errorf("daysBetween expects 2 arguments, got %d", len(arguments))
+	}
+	from, err := toTime(arguments[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := toTime(arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return math.Round(to.Sub(from).Hours() / 24), nil
+}
+
+// date(值) 把日期字符串转成秒级时间戳 可以和表达式里的日期常量以及 now() 比较
+func dateFunction(arguments ...any) (any, error) {
+	if len(arguments) != 1 {
+		return nil, fmt.Errorf("date expects 1 argument, got %d", len(arguments))
+	}
+	value, err := toTime(arguments[0])
+	if err != nil {
+		return nil, err
+	}
+	return float64(value.Unix()), nil
+}
+
+// now() 当前时间的秒级时间戳 使用和定时器相同的时钟
+func nowFunction(arguments ...any) (any, error) {
+	if len(arguments) != 0 {
+		return nil, fmt.Errorf("now expects no arguments, got %d", len(arguments))
+	}
+	return float64(now().Unix()), nil
+}
+
+// 日期参数可以是字符串、秒级时间戳（表达式里的日期常量会被 govaluate 转成时间戳）或者 time.Time
+func toTime(value any) (time.Time, error) {
+	switch current := value.(type) {
+	case time.Time:
+		return current, nil
+	case string:
+		for _, format := range expressionTimeFormats {
+			if parsed, err := time.ParseInLocation(format, current, time.Local); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as a date", current)
+	}
+	if seconds, ok := toFloat64(value); ok {
+		return time.Unix(int64(seconds), 0).In(time.Local), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot use %T as a date", value)
+}
+
+// 条件表达式在部署前先解析一遍 语法错误或者调用了未注册的函数时报告问题
+func expressionProblems(model *Model) []ModelProblem {
+	var problems []ModelProblem
+	for executionId, sequenceFlow := range model.SequenceFlows {
+		if strings.TrimSpace(sequenceFlow.Expression) == "" {
+			continue
+		}
+		if _, err := parseExpression(sequenceFlow.Expression); err != nil {
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_EXPRESSION, Message: err.Error()})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].ExecutionId < problems[j].ExecutionId })
+	return problems
+}
@@ -0,0 +1,42 @@
+package components
+
+import "testing"
+
+func TestEvaluateExpressionComparesFormStrings(t *testing.T) {
+	cases := []struct {
+		expression string
+		parameters map[string]any
+		expected   bool
+	}{
+		{"startEvent.startDate > '2024-01-01'", map[string]any{"startEvent.startDate": "2024-05-01"}, true},
+		{"startEvent.startDate > '2024-01-01'", map[string]any{"startEvent.startDate": "2023-12-31"}, false},
+		{"startEvent.startDate < startEvent.endDate", map[string]any{"startEvent.startDate": "2024-05-01", "startEvent.endDate": "2024-05-03"}, true},
+		{"t0.amount > 1000", map[string]any{"t0.amount": "1500"}, true},
+		{"1000 >= t0.amount", map[string]any{"t0.amount": "1500"}, false},
+		{"t0.amount > 1000", map[string]any{"t0.amount": float64(999)}, false},
+		// 相等比较和数字、日期常量比较时也转换
+		{"t0.amount == 1500", map[string]any{"t0.amount": "1500"}, true},
+		{"t0.amount == 1500", map[string]any{"t0.amount": "1500.0"}, true},
+		{"1500 == t0.amount", map[string]any{"t0.amount": " 1500 "}, true},
+		{"t0.amount != 1500", map[string]any{"t0.amount": "1500"}, false},
+		{"t0.amount != 1500", map[string]any{"t0.amount": "1499"}, true},
+		{"t0.amount == 1500 && t0.code == '007'", map[string]any{"t0.amount": "1500", "t0.code": "007"}, true},
+		{"startEvent.startDate == '2024-05-01'", map[string]any{"startEvent.startDate": "2024-05-01"}, true},
+		{"startEvent.startDate != '2024-05-01'", map[string]any{"startEvent.startDate": "2024-05-02"}, true},
+		// 和字符串常量、其他参数的相等比较保持按字符串
+		{"t0.code == '007'", map[string]any{"t0.code": "007"}, true},
+		{"t0.code == '7'", map[string]any{"t0.code": "007"}, false},
+		{"t0.code == t1.code", map[string]any{"t0.code": "007", "t1.code": "7"}, false},
+		{"t0.name > 'abc'", map[string]any{"t0.name": "abd"}, true},
+	}
+	for _, c := range cases {
+		result, err := EvaluateExpression(c.expression, c.parameters)
+		if err != nil {
+			t.Errorf("%s with %v: %v", c.expression, c.parameters, err)
+			continue
+		}
+		if result != c.expected {
+			t.Errorf("%s with %v = %v, want %v", c.expression, c.parameters, result, c.expected)
+		}
+	}
+}
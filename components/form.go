@@ -0,0 +1,188 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 表单字段的类型 其他类型的字段前端可以自行渲染，引擎只校验是否必填
+const (
+	FORM_FIELD_TEXT     = "text"
+	FORM_FIELD_TEXTAREA = "textarea"
+	FORM_FIELD_DATE     = "date"     // 日期字符串 格式和条件表达式里的日期一致
+	FORM_FIELD_DROPDOWN = "dropdown" // 下拉框 值必须是 options 之一
+	FORM_FIELD_RADIO    = "radio"    // 单选框 值必须是 options 之一
+)
+
+// Form 开始事件和审批节点 FormData 里的表单定义
+type Form struct {
+	Title    string        `json:"title"`
+	Elements []FormElement `json:"elements"`
+}
+
+// FormElement 表单字段 Id 就是提交数据里的字段名
+type FormElement struct {
+	Id       string   `json:"id"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+// FormFieldError 某个字段没有通过校验的原因
+type FormFieldError struct {
+	Field   string
+	Message string
+}
+
+// FormValidationError 提交的数据不符合表单定义时返回的错误，列出全部不通过的字段，用 errors.As 取出
+// 属于 ErrInvalidInput，审批节点不会被完成，流程实例也不会标记为异常
+// 表单定义本身不能解析时 Definition 是解析错误，Fields 为空，数据不能当作已经校验通过
+type FormValidationError struct {
+	ExecutionId string // 表单所在节点的结构id
+	Fields      []FormFieldError
+	Definition  error
+}
+
+func (e *FormValidationError) Error() string {
+	if e.Definition != nil {
+		return fmt.Sprintf("form of %s cannot be validated: %v", e.ExecutionId, e.Definition)
+	}
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s %s", field.Field, field.Message))
+	}
+	return fmt.Sprintf("form of %s is invalid: %s", e.ExecutionId, strings.Join(messages, "; "))
+}
+
+func (e *FormValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// 解析 FormData 没有配置表单时返回 nil
+func parseForm(formData string) (*Form, error) {
+	if strings.TrimSpace(formData) == "" {
+		return nil, nil
+	}
+	var form Form
+	if err := json.Unmarshal([]byte(formData), &form); err != nil {
+		return nil, fmt.Errorf("failed to parse form data: %v", err)
+	}
+	return &form, nil
+}
+
+// 按节点的 FormData 校验提交的数据 data 是 JSON 对象，没有配置表单时不校验
+// 表单里没有定义的字段原样保留，多实例的汇总结果等数据不受影响
+func validateFormData(executionId string, formData string, data string) error {
+	form, err := parseForm(formData)
+	if err != nil {
+		//部署时已经检查过 这里只可能是检查之前部署的旧版本，不能跳过校验让数据直接进入流程
+		return &FormValidationError{ExecutionId: executionId, Definition: err}
+	}
+	if form == nil {
+		return nil
+	}
+	var fields map[string]any
+	if strings.TrimSpace(data) != "" {
+		if err := json.Unmarshal([]byte(data), &fields); err != nil {
+			return fmt.Errorf("%w: form data of %s must be a JSON object: %v", ErrInvalidInput, executionId, err)
+		}
+	}
+
+	var fieldErrors []FormFieldError
+	for _, element := range form.Elements {
+		if message := validateFormField(element, fields[element.Id]); message != "" {
+			fieldErrors = append(fieldErrors, FormFieldError{Field: element.Id, Message: message})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return &FormValidationError{ExecutionId: executionId, Fields: fieldErrors}
+	}
+	return nil
+}
+
+// 校验单个字段 通过时返回空字符串
+func validateFormField(element FormElement, value any) string {
+	if formValueEmpty(value) {
+		if element.Required {
+			return "is required"
+		}
+		return ""
+	}
+	switch element.Type {
+	case FORM_FIELD_TEXT, FORM_FIELD_TEXTAREA:
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("must be a string, got %T", value)
+		}
+	case FORM_FIELD_DATE:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("must be a date string, got %T", value)
+		}
+		if _, err := toTime(text); err != nil {
+			return err.Error()
+		}
+	case FORM_FIELD_DROPDOWN, FORM_FIELD_RADIO:
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("must be one of the options, got %T", value)
+		}
+		if !slices.Contains(element.Options, text) {
+			return fmt.Sprintf("%q is not one of %s", text, strings.Join(element.Options, ", "))
+		}
+	}
+	return ""
+}
+
+// 没有提交、null、空字符串和空数组都当作没有填写
+func formValueEmpty(value any) bool {
+	switch current := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(current) == ""
+	case []any:
+		return len(current) == 0
+	}
+	return false
+}
+
+// 部署时检查表单定义 JSON 格式、字段id以及下拉框和单选框的选项
+func formProblems(model *Model) []ModelProblem {
+	var problems []ModelProblem
+	check := func(executionId string, formData string) {
+		form, err := parseForm(formData)
+		if err != nil {
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_FORM, Message: err.Error()})
+			return
+		}
+		if form == nil {
+			return
+		}
+		seen := make(map[string]bool)
+		for _, element := range form.Elements {
+			if strings.TrimSpace(element.Id) == "" {
+				problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_FORM, Message: fmt.Sprintf("form element %q has no id", element.Label)})
+				continue
+			}
+			if seen[element.Id] {
+				problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_FORM, Message: fmt.Sprintf("form element %s is duplicated", element.Id)})
+			}
+			seen[element.Id] = true
+			if (element.Type == FORM_FIELD_DROPDOWN || element.Type == FORM_FIELD_RADIO) && len(element.Options) == 0 {
+				problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_FORM, Message: fmt.Sprintf("form element %s has no options", element.Id)})
+			}
+		}
+	}
+	for executionId, startEvent := range model.StartEvents {
+		check(executionId, startEvent.FormData)
+	}
+	for executionId, task := range model.Tasks {
+		check(executionId, task.FormData)
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].ExecutionId < problems[j].ExecutionId })
+	return problems
+}
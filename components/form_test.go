@@ -0,0 +1,153 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// 启动表单有必填字段和下拉框，审批节点的表单有日期和单选框
+const formXML = `<Process name="formCheck">
+  <StartEvent executionId="s">
+    <Outgoing>f0</Outgoing>
+    <FormData>{"title":"Leave","elements":[
+      {"id":"reason","label":"Reason","type":"text","required":true},
+      {"id":"kind","label":"Kind","type":"dropdown","options":["annual","sick"],"required":true},
+      {"id":"note","label":"Note","type":"textarea"}]}</FormData>
+  </StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="form-user">
+    <Incoming>f0</Incoming><Outgoing>f1</Outgoing>
+    <FormData>{"elements":[
+      {"id":"until","label":"Until","type":"date","required":true},
+      {"id":"decision","label":"Decision","type":"radio","options":["approve","reject"],"required":true}]}</FormData>
+  </Task>
+  <EndEvent executionId="e"><Incoming>f1</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="e"/>
+</Process>`
+
+// 校验错误里不通过的字段id
+func invalidFormFields(t *testing.T, err error) []string {
+	t.Helper()
+	var formErr *FormValidationError
+	if !errors.As(err, &formErr) || !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("error = %v, want a form validation error", err)
+	}
+	var fields []string
+	for _, field := range formErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	return fields
+}
+
+func TestStartFormIsValidated(t *testing.T) {
+	deployXML(t, "formCheck", []byte(formXML))
+	before := countProcessInstances(t, "formCheck")
+	_, err := tryStartProcess("formCheck", "form-ann", `{"reason":"  ","kind":"unpaid"}`)
+	if fields := invalidFormFields(t, err); fmt.Sprint(fields) != "[reason kind]" {
+		t.Fatalf("invalid fields = %v", fields)
+	}
+	if after := countProcessInstances(t, "formCheck"); after != before {
+		t.Fatalf("process instances after invalid start = %d, before %d", after, before)
+	}
+
+	// 表单里没有定义的字段原样保留
+	id := startProcess(t, "formCheck", "form-ann", `{"reason":"trip","kind":"annual","days":3}`)
+	variables, err := GetServiceFactory().GetRuntimeService().GetVariables(id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variables["reason"] != "trip" || variables["days"] != float64(3) {
+		t.Fatalf("variables = %v", variables)
+	}
+}
+
+func TestTaskFormIsValidatedOnCompletion(t *testing.T) {
+	deployXML(t, "formCheck", []byte(formXML))
+	id := startProcess(t, "formCheck", "form-ann", `{"reason":"trip","kind":"sick"}`)
+	task := activeTask(t, id, "t0")
+
+	_, err := GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "form-user", map[string]any{"until": "next week", "decision": 1})
+	if fields := invalidFormFields(t, err); fmt.Sprint(fields) != "[until decision]" {
+		t.Fatalf("invalid fields = %v", fields)
+	}
+	// 校验不通过不完成审批节点，流程实例也不标记为异常
+	if activeTask(t, id, "t0").Id != task.Id {
+		t.Fatal("task was completed with invalid form data")
+	}
+	if status := processStatus(t, id); status != PROCESS_STATUS_RUNNING {
+		t.Fatalf("status = %s", status)
+	}
+
+	completeTaskAs(t, task.Id, "form-user", map[string]any{"until": "2026-12-01", "decision": "approve"})
+	if status := historicProcessStatus(t, id); status != PROCESS_STATUS_COMPLETE {
+		t.Fatalf("status = %s", status)
+	}
+}
+
+func TestInvalidFormDefinitionIsRejectedAtDeploy(t *testing.T) {
+	xmlContent := `<Process name="formInvalid">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing><FormData>{"elements":[</FormData></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="form-user">
+    <Incoming>f0</Incoming><Outgoing>f1</Outgoing>
+    <FormData>{"elements":[
+      {"label":"No id","type":"text"},
+      {"id":"a","type":"text"},{"id":"a","type":"text"},
+      {"id":"b","type":"radio"}]}</FormData>
+  </Task>
+  <EndEvent executionId="e"><Incoming>f1</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="e"/>
+</Process>`
+	err := tryDeployXML("formInvalid", []byte(xmlContent))
+	var validationErr *ModelValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("deploy error = %v", err)
+	}
+	counts := make(map[string]int)
+	for _, problem := range validationErr.Problems {
+		if problem.Code != PROBLEM_FORM {
+			t.Errorf("unexpected problem %+v", problem)
+		}
+		counts[problem.ExecutionId]++
+	}
+	// 启动表单不是合法的 JSON，审批节点的表单缺少id、id重复、单选框没有选项
+	if counts["s"] != 1 || counts["t0"] != 3 {
+		t.Fatalf("problems = %+v", validationErr.Problems)
+	}
+}
+
+// 校验之前部署的旧版本里表单定义不能解析 提交的数据不能绕过校验
+func TestUnparsableFormDefinitionRejectsCompletion(t *testing.T) {
+	deployXML(t, "formLegacy", []byte(strings.Replace(formXML, `name="formCheck"`, `name="formLegacy"`, 1)))
+	id := startProcess(t, "formLegacy", "form-ann", `{"reason":"trip","kind":"sick"}`)
+	task := activeTask(t, id, "t0")
+
+	repository := GetServiceFactory().GetRepositoryService()
+	tx, err := repository.GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = memoryExec(tx, func(data *memoryData) error {
+		for definitionId, pd := range data.processDefinitions {
+			if pd.ProcessDefinitionName == "formLegacy" {
+				pd.XMLContent = []byte(strings.Replace(string(pd.XMLContent), `{"elements":[`+"\n      "+`{"id":"until"`, `{"elements":{"id":"until"`, 1))
+				data.processDefinitions[definitionId] = pd
+			}
+		}
+		return nil
+	})
+	if err := FinishTransaction(tx, err); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "form-user", map[string]any{"until": "2026-12-01", "decision": "approve"})
+	var formErr *FormValidationError
+	if !errors.As(err, &formErr) || !errors.Is(err, ErrInvalidInput) || formErr.Definition == nil || formErr.ExecutionId != "t0" {
+		t.Fatalf("complete error = %v", err)
+	}
+	if activeTask(t, id, "t0").Id != task.Id {
+		t.Fatal("task was completed without form validation")
+	}
+}
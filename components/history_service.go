@@ -2,8 +2,31 @@ package components
 
 import (
 	"database/sql"
+	"time"
 )
 
+// HistoricProcessInstance 历史流程实例 流程结束或者终止时从流程实例表复制过来
+type HistoricProcessInstance struct {
+	ProcessInstance
+	Duration       int64  // 持续时间 毫秒
+	EndExecutionId string // 走到的结束事件结构id 终止的流程为空
+}
+
+// TaskOperation 审批节点的委派、转办、加签等操作记录 流程结束后依然保留
+type TaskOperation struct {
+	Id                int
+	ProcessInstanceId int
+	NodeInstanceId    int
+	ExecutionId       string
+	Operation         string // TASK_OPERATION_* 之一
+	FromUser          string // 操作前的负责人
+	ToUser            string // 操作后的负责人
+	Reason            string
+	OutputData        string // 加签的人审批时提交的数据 其他操作为空
+	CreatedBy         string
+	CreatedAt         time.Time
+}
+
 type HistoryService interface {
 	//迁徙节点数据到历史表
 	CopyNodeInstanceById(tx *sql.Tx, nodeId int) error
@@ -11,6 +34,21 @@ type HistoryService interface {
 	CopyNodeInstance(tx *sql.Tx, nodeId int, processInstanceId int, processDefinitionName string, nodeName string, executionId string,
 		previousExecutionId string, assignee string) (int, error)
 
+	//迁徙节点数据到历史表 同时记录状态和说明，用于打回时没有完成就被取消的审批节点
+	ArchiveNodeInstance(tx *sql.Tx, nodeId int, status string, comment string) error
+
+	//把流程实例复制到历史流程实例表 流程结束或者终止时调用，状态和结束时间以流程实例表为准
+	ArchiveProcessInstance(tx *sql.Tx, processInstanceId int, endExecutionId string) error
+	//根据id查询历史流程实例 不存在时返回 nil
+	GetHistoricProcessInstanceById(id int) (*HistoricProcessInstance, error)
+	//根据id查询历史节点实例 不存在时返回 nil，历史表没有委派状态
+	GetHistoricNodeInstanceById(id int) (*NodeInstance, error)
+
+	//记录一条审批节点的操作
+	AddTaskOperation(tx *sql.Tx, operation TaskOperation) error
+	//查询流程实例的审批节点操作记录 按操作顺序排序
+	GetTaskOperations(processInstanceId int) ([]TaskOperation, error)
+
 	//流程进度查询接口
 	GetProcessCompleteTask(ProcessInstanceId int) ([]map[string]interface{}, error)
 	GetTransaction() (*sql.Tx, error)
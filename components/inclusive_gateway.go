@@ -0,0 +1,147 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// InclusiveGateway 包容网关 分支时走所有条件成立的序列流，汇聚时只等待分支时实际激活的那几条
+// 分支网关激活的数量记录在它的节点实例输出里 {"activated":n}，汇聚网关按结构找到对应的分支网关后从数据库读取，重启之后依然可以正确汇聚
+type InclusiveGateway struct {
+	ExecutionId string   `xml:"executionId,attr"` // 绑定 id 属性
+	Outgoing    []string `xml:"Outgoing"`         // 绑定 <Outgoing> 子元素
+	Incoming    []string `xml:"Incoming"`         // 绑定 <Incoming> 子元素
+	X           string   `xml:"x,attr"`
+	Y           string   `xml:"y,attr"`
+	H           string   `xml:"h,attr"`
+	W           string   `xml:"w,attr"`
+	Default     string   `xml:"default,attr"` // 默认出线的结构id 其他出线的条件都不成立时走这一条，默认出线上的条件不计算
+	Listener    string   `xml:"Listener"`     // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
+}
+
+// 分支网关记录在输出里的数据
+type inclusiveGatewayOutput struct {
+	Activated int `json:"activated"`
+}
+
+func (inclusiveGateway InclusiveGateway) Execute(ctx *WorkflowContext) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	tx := ctx.Tx
+	if tx == nil {
+		return errNilTransaction(inclusiveGateway.ExecutionId)
+	}
+	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, INCLUSIVE_GATEWAY, inclusiveGateway.ExecutionId, ctx.CurrentExecutionId, SYSTEM_USER_NOBODY)
+	if initerr != nil {
+		return newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, initerr)
+	}
+
+	//执行监听
+	if listenerErr := RunListener(inclusiveGateway.Listener, ctx); listenerErr != nil {
+		return newExecutionError(inclusiveGateway.ExecutionId, ErrListenerFailed, listenerErr)
+	}
+
+	if len(inclusiveGateway.Incoming) > 1 {
+		arrived, expected, err := inclusiveGateway.countArrivals(ctx)
+		if err != nil {
+			return err
+		}
+		if arrived < expected {
+			//还有激活的分支没有到达 当前分支的记录直接进历史表
+			historyService := GetServiceFactory().GetHistoryService()
+			_, copyerr := historyService.CopyNodeInstance(tx, nodeId, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, INCLUSIVE_GATEWAY, inclusiveGateway.ExecutionId, ctx.CurrentExecutionId, SYSTEM_USER_NOBODY)
+			if copyerr != nil {
+				return newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, copyerr)
+			}
+			return nil
+		}
+	}
+	return inclusiveGateway.Complete(ctx, nodeId)
+}
+
+// 统计已经到达的分支数量 和分支网关激活的数量比较，找不到分支网关时按进线数量等待
+func (inclusiveGateway InclusiveGateway) countArrivals(ctx *WorkflowContext) (int, int, error) {
+	nodeService := GetServiceFactory().GetNodeService()
+	expected, afterId := len(inclusiveGateway.Incoming), 0
+	if forkId := ctx.Model.JoinForks[inclusiveGateway.ExecutionId]; forkId != "" {
+		fork, err := nodeService.GetLatestCompletedNodeInstance(ctx.Tx, ctx.ProcessInstanceId, forkId)
+		if err != nil {
+			return 0, 0, newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if fork != nil {
+			var output inclusiveGatewayOutput
+			if err := json.Unmarshal([]byte(fork.OutputData), &output); err != nil {
+				return 0, 0, newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, fmt.Errorf("failed to parse output of inclusive gateway %s: %v", forkId, err))
+			}
+			expected, afterId = output.Activated, fork.Id
+		}
+	}
+	arrived, err := nodeService.CountInclusiveGatewayIncoming(ctx.Tx, ctx.ProcessInstanceId, inclusiveGateway.ExecutionId, afterId)
+	if err != nil {
+		return 0, 0, newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, err)
+	}
+	return arrived, expected, nil
+}
+
+// Complete 汇聚完成或者不需要汇聚 计算出线的条件，记录激活的数量后走全部条件成立的序列流
+// 都不成立时走默认出线，没有默认出线时返回错误，事务回滚后流程实例被标记为故障
+func (inclusiveGateway InclusiveGateway) Complete(ctx *WorkflowContext, nodeId int) error {
+	ctx.CurrentExecutionId = inclusiveGateway.ExecutionId
+	//先把所有条件都算完再往下走，分支走到结束节点会清理流程数据，之后就取不到表达式里的属性了
+	passed, err := inclusiveGateway.selectFlows(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodeService := GetServiceFactory().GetNodeService()
+	dataBytes, _ := json.Marshal(inclusiveGatewayOutput{Activated: len(passed)})
+	if err := nodeService.UpdateNodeInstanceOutput(ctx.Tx, nodeId, string(dataBytes)); err != nil {
+		return newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, err)
+	}
+	historyService := GetServiceFactory().GetHistoryService()
+	if err := historyService.CopyNodeInstanceById(ctx.Tx, nodeId); err != nil {
+		return newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, err)
+	}
+
+	for _, sequenceFlow := range passed {
+		if err := clearBranchVariables(ctx, sequenceFlow.ExecutionId); err != nil {
+			return newExecutionError(inclusiveGateway.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if err := sequenceFlow.Take(ctx); err != nil {
+			return err
+		}
+		ctx.CurrentExecutionId = inclusiveGateway.ExecutionId
+	}
+	return nil
+}
+
+// 条件成立的全部出线 都不成立时只走默认出线
+func (inclusiveGateway InclusiveGateway) selectFlows(ctx *WorkflowContext) ([]SequenceFlow, error) {
+	var passed []SequenceFlow
+	for _, value := range inclusiveGateway.Outgoing {
+		if value == inclusiveGateway.Default {
+			continue
+		}
+		sequenceFlow, err := lookupSequenceFlow(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		pass, err := sequenceFlow.Evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if pass {
+			passed = append(passed, sequenceFlow)
+		}
+	}
+	if len(passed) > 0 {
+		return passed, nil
+	}
+	if inclusiveGateway.Default != "" {
+		sequenceFlow, err := lookupSequenceFlow(ctx, inclusiveGateway.Default)
+		if err != nil {
+			return nil, err
+		}
+		return []SequenceFlow{sequenceFlow}, nil
+	}
+	return nil, newExecutionError(inclusiveGateway.ExecutionId, ErrNoMatchingFlow, fmt.Errorf("no outgoing sequence flow condition is true and no default flow is defined"))
+}
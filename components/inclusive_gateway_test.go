@@ -0,0 +1,94 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// 包容网关分出 a b c 三条分支再汇聚到 d，defaultFlow 为空时没有默认出线
+// 默认出线上也写了条件 用来确认默认出线的条件不参与计算
+func inclusiveGatewayXML(name string, defaultFlow string) []byte {
+	return []byte(fmt.Sprintf(`<Process name="%s">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="inc-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <InclusiveGateway executionId="i1" default="%s"><Incoming>f1</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing><Outgoing>fc</Outgoing></InclusiveGateway>
+  <Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="inc-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing></Task>
+  <Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="inc-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>
+  <Task executionId="c" name="C" assigneeType="ByAssigneeName" assigneeKey="inc-user"><Incoming>fc</Incoming><Outgoing>fc2</Outgoing></Task>
+  <InclusiveGateway executionId="i2"><Incoming>fa2</Incoming><Incoming>fb2</Incoming><Incoming>fc2</Incoming><Outgoing>f6</Outgoing></InclusiveGateway>
+  <Task executionId="d" name="D" assigneeType="ByAssigneeName" assigneeKey="inc-user"><Incoming>f6</Incoming><Outgoing>f7</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f7</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="i1"/>
+  <SequenceFlow executionId="fa" sourceRef="i1" targetRef="a"><ConditionExpression>t0.x == 1</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fb" sourceRef="i1" targetRef="b"><ConditionExpression>t0.y == 1</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fc" sourceRef="i1" targetRef="c"><ConditionExpression>t0.z == 1</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fa2" sourceRef="a" targetRef="i2"/>
+  <SequenceFlow executionId="fb2" sourceRef="b" targetRef="i2"/>
+  <SequenceFlow executionId="fc2" sourceRef="c" targetRef="i2"/>
+  <SequenceFlow executionId="f6" sourceRef="i2" targetRef="d"/>
+  <SequenceFlow executionId="f7" sourceRef="d" targetRef="e"/>
+</Process>`, name, defaultFlow))
+}
+
+func TestInclusiveGatewayWaitsForActivatedBranches(t *testing.T) {
+	deployXML(t, "inclusiveAll", inclusiveGatewayXML("inclusiveAll", ""))
+	id := startProcess(t, "inclusiveAll", "inc-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "inc-user", map[string]any{"x": 1, "y": 1, "z": 0})
+
+	if findActiveNode(t, id, "c") != nil {
+		t.Fatal("branch c was activated")
+	}
+	completeTaskAs(t, activeTask(t, id, "a").Id, "inc-user", nil)
+	if findActiveNode(t, id, "d") != nil {
+		t.Fatal("join passed before branch b arrived")
+	}
+	completeTaskAs(t, activeTask(t, id, "b").Id, "inc-user", nil)
+	completeTaskAs(t, activeTask(t, id, "d").Id, "inc-user", nil)
+	if status := processStatus(t, id); status != PROCESS_STATUS_COMPLETE {
+		t.Fatalf("status = %s", status)
+	}
+}
+
+func TestInclusiveGatewayTakesDefaultFlow(t *testing.T) {
+	deployXML(t, "inclusiveDefault", inclusiveGatewayXML("inclusiveDefault", "fc"))
+	id := startProcess(t, "inclusiveDefault", "inc-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "inc-user", map[string]any{"x": 0, "y": 0, "z": 0})
+
+	if findActiveNode(t, id, "a") != nil || findActiveNode(t, id, "b") != nil {
+		t.Fatal("a branch whose condition is false was activated")
+	}
+	completeTaskAs(t, activeTask(t, id, "c").Id, "inc-user", nil)
+	activeTask(t, id, "d")
+}
+
+func TestInclusiveGatewayWithoutMatchRaisesIncident(t *testing.T) {
+	deployXML(t, "inclusiveNone", inclusiveGatewayXML("inclusiveNone", ""))
+	id := startProcess(t, "inclusiveNone", "inc-ann", "")
+	task := activeTask(t, id, "t0")
+	_, err := GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "inc-user", map[string]any{"x": 0, "y": 0, "z": 0})
+	if !errors.Is(err, ErrNoMatchingFlow) {
+		t.Fatalf("complete error = %v", err)
+	}
+	if status := processStatus(t, id); status != PROCESS_STATUS_INCIDENT {
+		t.Fatalf("status = %s", status)
+	}
+	// 事务已经回滚 审批节点还在
+	activeTask(t, id, "t0")
+}
+
+func TestInclusiveGatewayDefaultMustBeOutgoing(t *testing.T) {
+	err := tryDeployXML("inclusiveBadDefault", inclusiveGatewayXML("inclusiveBadDefault", "f6"))
+	var validationErr *ModelValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("deploy error = %v", err)
+	}
+	found := false
+	for _, problem := range validationErr.Problems {
+		found = found || problem.Code == PROBLEM_DEFAULT_FLOW
+	}
+	if !found {
+		t.Fatalf("problems = %+v", validationErr.Problems)
+	}
+}
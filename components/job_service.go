@@ -0,0 +1,97 @@
+package components
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TimerJob 定时任务 审批节点上的边界定时器和中间定时事件到期后由调度器触发
+type TimerJob struct {
+	Id                int
+	ProcessInstanceId int
+	NodeInstanceId    int    // 定时器挂在哪个节点实例上
+	ExecutionId       string // 定时器的结构id 边界定时器是 BoundaryTimer 的id，中间定时事件是节点自己的id
+	Action            string // 到期后的动作 TIMER_ACTION_* 之一
+	Attempts          int    // 已经失败的次数
+	DueTime           time.Time
+	LastError         string
+	CreatedAt         time.Time
+}
+
+// AsyncJob 异步任务 标记了 async 的节点不在当前事务里执行，先记录一条异步任务提交事务，由异步执行器在新的事务里执行
+type AsyncJob struct {
+	Id                  int
+	ProcessInstanceId   int
+	ExecutionId         string // 要执行的节点结构id
+	PreviousExecutionId string // 从哪个节点流转过来 执行时作为上下文的 CurrentExecutionId
+	Attempts            int    // 已经失败的次数
+	DueTime             time.Time
+	LastError           string
+	CreatedAt           time.Time
+}
+
+// DeadLetterJob 重试次数用完依然失败的异步任务 人工处理后可以重新放回异步任务表
+type DeadLetterJob struct {
+	AsyncJob
+	FailedAt time.Time
+}
+
+// DeadLetterTimerJob 重试次数用完依然失败的定时任务 人工处理后可以重新放回定时任务表
+type DeadLetterTimerJob struct {
+	TimerJob
+	FailedAt time.Time
+}
+
+// JobService 提供了操作定时任务、异步任务和死信任务表的接口
+type JobService interface {
+	GetTransaction() (*sql.Tx, error)
+	//创建定时任务 返回自增id
+	CreateTimerJob(tx *sql.Tx, job *TimerJob) (int, error)
+	//查询到期的定时任务 按到期时间排序
+	GetDueTimerJobs(now time.Time, limit int) ([]TimerJob, error)
+	//在事务里读取并锁住定时任务 不存在时返回 nil，多个调度器同时运行时只有一个能拿到
+	LockTimerJob(tx *sql.Tx, id int) (*TimerJob, error)
+	//记录定时任务的失败次数和错误 推迟到下一次重试的时间
+	UpdateTimerJobFailure(tx *sql.Tx, id int, attempts int, dueTime time.Time, lastError string) error
+	DeleteTimerJob(tx *sql.Tx, id int) error
+	//节点完成时删除挂在它上面的定时任务
+	DeleteTimerJobsByNodeInstance(tx *sql.Tx, nodeInstanceId int) error
+	//流程结束或终止时删除全部定时任务
+	DeleteTimerJobsByProcessInstance(tx *sql.Tx, processInstanceId int) error
+
+	//创建异步任务 返回自增id
+	CreateAsyncJob(tx *sql.Tx, job *AsyncJob) (int, error)
+	//查询到期的异步任务 按到期时间排序 挂起的流程实例不返回
+	GetDueAsyncJobs(now time.Time, limit int) ([]AsyncJob, error)
+	//在事务里读取并锁住异步任务 不存在时返回 nil
+	LockAsyncJob(tx *sql.Tx, id int) (*AsyncJob, error)
+	//记录失败次数和错误 推迟到下一次重试的时间
+	UpdateAsyncJobFailure(tx *sql.Tx, id int, attempts int, dueTime time.Time, lastError string) error
+	DeleteAsyncJob(tx *sql.Tx, id int) error
+	//查询流程实例还没有执行的异步任务 打回时清理下游的异步任务
+	GetAsyncJobsByProcessInstance(tx *sql.Tx, processInstanceId int) ([]AsyncJob, error)
+	DeleteAsyncJobsByProcessInstance(tx *sql.Tx, processInstanceId int) error
+	//把异步任务移到死信表 死信任务的id和原来的异步任务一致
+	MoveAsyncJobToDeadLetter(tx *sql.Tx, id int) error
+	//查询全部死信任务 按id排序
+	GetDeadLetterJobs() ([]DeadLetterJob, error)
+	//在事务里读取并锁住死信任务 不存在时返回 nil
+	LockDeadLetterJob(tx *sql.Tx, id int) (*DeadLetterJob, error)
+	DeleteDeadLetterJob(tx *sql.Tx, id int) error
+	//把定时任务移到死信表 死信定时任务的id和原来的定时任务一致
+	MoveTimerJobToDeadLetter(tx *sql.Tx, id int) error
+	//查询全部死信定时任务 按id排序
+	GetDeadLetterTimerJobs() ([]DeadLetterTimerJob, error)
+	//在事务里读取并锁住死信定时任务 不存在时返回 nil
+	LockDeadLetterTimerJob(tx *sql.Tx, id int) (*DeadLetterTimerJob, error)
+	DeleteDeadLetterTimerJob(tx *sql.Tx, id int) error
+}
+
+// 流程结束或终止时 清理还没有触发的定时任务和还没有执行的异步任务，死信任务保留用于排查
+func clearProcessJobs(tx *sql.Tx, processInstanceId int) error {
+	jobService := GetServiceFactory().GetJobService()
+	if err := jobService.DeleteTimerJobsByProcessInstance(tx, processInstanceId); err != nil {
+		return err
+	}
+	return jobService.DeleteAsyncJobsByProcessInstance(tx, processInstanceId)
+}
@@ -0,0 +1,119 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+var (
+	listenerTestCalls atomic.Int32
+	listenerTestFail  atomic.Bool
+)
+
+func init() {
+	RegisterListener("listenerTestCheck", func(ctx *WorkflowContext) error {
+		listenerTestCalls.Add(1)
+		if listenerTestFail.Load() {
+			return fmt.Errorf("check of %s failed", ctx.CurrentExecutionId)
+		}
+		return nil
+	})
+}
+
+const listenerXML = `<Process name="listenerCheck">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="lsn-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing><Listener>listenerTestCheck</Listener></Task>
+  <Task executionId="t1" name="T1" assigneeType="ByAssigneeName" assigneeKey="lsn-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="t1"/>
+  <SequenceFlow executionId="f2" sourceRef="t1" targetRef="e"/>
+</Process>`
+
+// 注册时出错直接panic 返回panic的内容
+func registerListenerPanic(name string, listener ListenerFunc) (recovered any) {
+	defer func() { recovered = recover() }()
+	RegisterListener(name, listener)
+	return nil
+}
+
+func TestRegisterListenerPanicsOnInvalidName(t *testing.T) {
+	noop := func(ctx *WorkflowContext) error { return nil }
+	for _, name := range []string{"", "  "} {
+		if recovered := registerListenerPanic(name, noop); recovered == nil {
+			t.Errorf("register listener %q did not panic", name)
+		}
+	}
+	if recovered := registerListenerPanic("listenerTestNil", nil); recovered == nil {
+		t.Error("register nil listener did not panic")
+	}
+	// 名称前后的空白不算区别
+	if recovered := registerListenerPanic(" listenerTestCheck ", noop); recovered == nil {
+		t.Error("register duplicate listener did not panic")
+	}
+	if _, ok := GetListener("listenerTestNil"); ok {
+		t.Error("nil listener was registered")
+	}
+}
+
+// 监听返回错误时审批节点的事务回滚 节点保持待办，后面的节点不创建
+func TestFailingListenerRollsBackTask(t *testing.T) {
+	deployXML(t, "listenerCheck", []byte(listenerXML))
+	id := startProcess(t, "listenerCheck", "lsn-ann", "")
+	task := activeTask(t, id, "t0")
+
+	listenerTestFail.Store(true)
+	t.Cleanup(func() { listenerTestFail.Store(false) })
+	calls := listenerTestCalls.Load()
+	_, err := GetServiceFactory().GetRuntimeService().CompleteTask(task.Id, "lsn-user", map[string]any{"checked": "no"})
+	if !errors.Is(err, ErrListenerFailed) {
+		t.Fatalf("complete error = %v", err)
+	}
+	if listenerTestCalls.Load() != calls+1 {
+		t.Fatal("listener was not called")
+	}
+	if activeTask(t, id, "t0").Id != task.Id {
+		t.Fatal("task was completed although the listener failed")
+	}
+	if findActiveNode(t, id, "t1") != nil {
+		t.Fatal("next task was created although the listener failed")
+	}
+	if status := processStatus(t, id); status != PROCESS_STATUS_RUNNING {
+		t.Fatalf("status = %s", status)
+	}
+	variables, err := GetServiceFactory().GetRuntimeService().GetVariables(id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := variables["t0"]; ok {
+		t.Fatalf("task output was saved: %v", variables)
+	}
+
+	listenerTestFail.Store(false)
+	completeTaskAs(t, task.Id, "lsn-user", map[string]any{"checked": "yes"})
+	activeTask(t, id, "t1")
+}
+
+func TestDeployRejectsUnregisteredListener(t *testing.T) {
+	xmlContent := []byte(`<Process name="listenerMissing">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="lsn-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing><Listener>listenerTestCheck, listenerTestMissing</Listener></Task>
+  <EndEvent executionId="e"><Incoming>f1</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="e"/>
+</Process>`)
+	err := tryDeployXML("listenerMissing", xmlContent)
+	var validationErr *ModelValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("deploy error = %v", err)
+	}
+	problems := validationErr.Problems
+	if len(problems) != 1 || problems[0].Code != PROBLEM_LISTENER || problems[0].ExecutionId != "t0" {
+		t.Fatalf("problems = %+v", problems)
+	}
+	if _, err := tryStartProcess("listenerMissing", "lsn-ann", ""); err == nil {
+		t.Fatal("started a process whose deploy was rejected")
+	}
+}
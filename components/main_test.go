@@ -0,0 +1,127 @@
+package components
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// 服务都是进程内的单例 整个测试包共用一个内存库，每个测试用自己的流程名称和用户名互不影响
+func TestMain(m *testing.M) {
+	db, err := OpenMemoryDB("components_test")
+	if err != nil {
+		panic(err)
+	}
+	Init(db, MEMORY_DBNAME)
+	os.Exit(m.Run())
+}
+
+// 部署流程定义 失败时直接结束测试
+func deployXML(t *testing.T, name string, xmlContent []byte) {
+	t.Helper()
+	if err := tryDeployXML(name, xmlContent); err != nil {
+		t.Fatalf("deploy %s: %v", name, err)
+	}
+}
+
+func tryDeployXML(name string, xmlContent []byte) error {
+	repository := GetServiceFactory().GetRepositoryService()
+	tx, err := repository.GetTransaction()
+	if err != nil {
+		return err
+	}
+	_, err = repository.SaveProcessDefinition(tx, &ProcessDefinition{
+		ProcessDefinitionName: name,
+		XMLContent:            xmlContent,
+		CreatedAt:             time.Now(),
+		CreatedBy:             "test",
+		Status:                "active",
+	})
+	return FinishTransaction(tx, err)
+}
+
+// 启动流程实例 失败时直接结束测试
+func startProcess(t *testing.T, name string, createdBy string, formParams string) int {
+	t.Helper()
+	id, err := tryStartProcess(name, createdBy, formParams)
+	if err != nil {
+		t.Fatalf("start %s: %v", name, err)
+	}
+	return id
+}
+
+func tryStartProcess(name string, createdBy string, formParams string) (int, error) {
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return 0, err
+	}
+	return runtimeService.StartProcessInstance(tx, name, "test", createdBy, formParams)
+}
+
+// 流程实例里某个节点还没有完成的节点实例 没有时返回 nil
+func findActiveNode(t *testing.T, processInstanceId int, executionId string) *NodeInstance {
+	t.Helper()
+	nodes, err := GetMemoryNodeService().GetNodeInstancesByProcessInstanceId(processInstanceId)
+	if err != nil {
+		t.Fatalf("get nodes of %d: %v", processInstanceId, err)
+	}
+	for _, node := range nodes {
+		if node.ExecutionId == executionId && node.OutputData == "" {
+			return node
+		}
+	}
+	return nil
+}
+
+// 流程实例里某个审批节点还没有完成的节点实例 没有时直接结束测试
+func activeTask(t *testing.T, processInstanceId int, executionId string) *NodeInstance {
+	t.Helper()
+	node := findActiveNode(t, processInstanceId, executionId)
+	if node == nil {
+		t.Fatalf("process instance %d has no active task %s", processInstanceId, executionId)
+	}
+	return node
+}
+
+// 完成审批节点 失败时直接结束测试
+func completeTaskAs(t *testing.T, taskId int, userId string, output map[string]any) []NodeInstance {
+	t.Helper()
+	tasks, err := GetServiceFactory().GetRuntimeService().CompleteTask(taskId, userId, output)
+	if err != nil {
+		t.Fatalf("complete task %d as %s: %v", taskId, userId, err)
+	}
+	return tasks
+}
+
+// 流程实例当前的状态
+func processStatus(t *testing.T, processInstanceId int) string {
+	t.Helper()
+	instance, err := GetServiceFactory().GetRuntimeService().GetProcessInstanceById(processInstanceId)
+	if err != nil {
+		t.Fatalf("get process instance %d: %v", processInstanceId, err)
+	}
+	if instance == nil {
+		t.Fatalf("process instance %d not found", processInstanceId)
+	}
+	return instance.Status
+}
+
+// 内存库里某个流程的实例数量
+func countProcessInstances(t *testing.T, name string) int {
+	t.Helper()
+	count := 0
+	runtimeService := GetServiceFactory().GetRuntimeService().(*MemoryRuntimeService)
+	err := memoryQuery(runtimeService.DB, func(data *memoryData) error {
+		for _, instance := range data.processInstances {
+			if instance.ProcessDefinitionName == name {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
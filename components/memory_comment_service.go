@@ -0,0 +1,97 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryCommentService 是 CommentService 接口的内存实现
+type MemoryCommentService struct {
+	DB *sql.DB
+}
+
+var memoryCommentServiceInstance *MemoryCommentService
+var memoryCommentServiceOnce sync.Once
+
+// InitializeMemoryCommentService 初始化单例实例
+func InitializeMemoryCommentService(db *sql.DB) {
+	memoryCommentServiceOnce.Do(func() {
+		memoryCommentServiceInstance = &MemoryCommentService{DB: db}
+	})
+}
+
+// GetMemoryCommentService 获取单例实例
+func GetMemoryCommentService() *MemoryCommentService {
+	if memoryCommentServiceInstance == nil {
+		panic("MemoryCommentService is not initialized. Call InitializeMemoryCommentService first.")
+	}
+	return memoryCommentServiceInstance
+}
+
+func (service *MemoryCommentService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// AddComment 新增评论
+func (service *MemoryCommentService) AddComment(tx *sql.Tx, comment *Comment) (int, error) {
+	err := memoryExec(tx, func(data *memoryData) error {
+		comment.Id = data.nextId("task_comment")
+		data.comments[comment.Id] = *comment
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to add comment: %v", err)
+	}
+	return comment.Id, nil
+}
+
+// GetComments 查询评论
+func (service *MemoryCommentService) GetComments(processInstanceId int, nodeInstanceId int) ([]Comment, error) {
+	var comments []Comment
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, comment := range data.comments {
+			if comment.ProcessInstanceId == processInstanceId && (nodeInstanceId == 0 || comment.NodeInstanceId == nodeInstanceId) {
+				comments = append(comments, comment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %v", err)
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].Id < comments[j].Id })
+	return comments, nil
+}
+
+// AddAttachment 新增附件
+func (service *MemoryCommentService) AddAttachment(tx *sql.Tx, attachment *Attachment) (int, error) {
+	err := memoryExec(tx, func(data *memoryData) error {
+		attachment.Id = data.nextId("task_attachment")
+		data.attachments[attachment.Id] = *attachment
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to add attachment: %v", err)
+	}
+	return attachment.Id, nil
+}
+
+// GetAttachments 查询附件
+func (service *MemoryCommentService) GetAttachments(processInstanceId int, nodeInstanceId int) ([]Attachment, error) {
+	var attachments []Attachment
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, attachment := range data.attachments {
+			if attachment.ProcessInstanceId == processInstanceId && (nodeInstanceId == 0 || attachment.NodeInstanceId == nodeInstanceId) {
+				attachments = append(attachments, attachment)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %v", err)
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Id < attachments[j].Id })
+	return attachments, nil
+}
@@ -0,0 +1,103 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryDelegationRuleService 是 DelegationRuleService 接口的内存实现
+type MemoryDelegationRuleService struct {
+	DB *sql.DB
+}
+
+var memoryDelegationRuleServiceInstance *MemoryDelegationRuleService
+var memoryDelegationRuleServiceOnce sync.Once
+
+// InitializeMemoryDelegationRuleService 初始化单例实例
+func InitializeMemoryDelegationRuleService(db *sql.DB) {
+	memoryDelegationRuleServiceOnce.Do(func() {
+		memoryDelegationRuleServiceInstance = &MemoryDelegationRuleService{DB: db}
+	})
+}
+
+// GetMemoryDelegationRuleService 获取单例实例
+func GetMemoryDelegationRuleService() *MemoryDelegationRuleService {
+	if memoryDelegationRuleServiceInstance == nil {
+		panic("MemoryDelegationRuleService is not initialized. Call InitializeMemoryDelegationRuleService first.")
+	}
+	return memoryDelegationRuleServiceInstance
+}
+
+func (service *MemoryDelegationRuleService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// AddDelegationRule 新增代理规则
+func (service *MemoryDelegationRuleService) AddDelegationRule(rule *DelegationRule) (int, error) {
+	if err := validateDelegationRule(rule); err != nil {
+		return 0, err
+	}
+	err := memoryExec(service.DB, func(data *memoryData) error {
+		rule.Id = data.nextId("delegation_rule")
+		rule.CreatedAt = time.Now()
+		data.delegationRules[rule.Id] = *rule
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to add delegation rule: %v", ErrPersistenceFailed, err)
+	}
+	return rule.Id, nil
+}
+
+// DeleteDelegationRule 删除代理规则
+func (service *MemoryDelegationRuleService) DeleteDelegationRule(id int) error {
+	err := memoryExec(service.DB, func(data *memoryData) error {
+		delete(data.delegationRules, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete delegation rule: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// GetDelegationRules 查询用户的全部代理规则
+func (service *MemoryDelegationRuleService) GetDelegationRules(userId string) ([]DelegationRule, error) {
+	rules, err := service.queryDelegationRules(service.DB, func(rule DelegationRule) bool { return rule.UserId == userId })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].StartTime.Before(rules[j].StartTime) })
+	return rules, nil
+}
+
+// GetActiveDelegationRules 查询某个时间点对某个流程生效的代理规则
+func (service *MemoryDelegationRuleService) GetActiveDelegationRules(tx *sql.Tx, userId string, processDefinitionName string, at time.Time) ([]DelegationRule, error) {
+	return service.queryDelegationRules(tx, func(rule DelegationRule) bool {
+		return rule.UserId == userId && !rule.StartTime.After(at) && rule.EndTime.After(at) &&
+			(rule.ProcessDefinitionName == "" || rule.ProcessDefinitionName == processDefinitionName)
+	})
+}
+
+// 按id排序筛选代理规则
+func (service *MemoryDelegationRuleService) queryDelegationRules(execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, filter func(rule DelegationRule) bool) ([]DelegationRule, error) {
+	var rules []DelegationRule
+	err := memoryQuery(execer, func(data *memoryData) error {
+		for _, rule := range data.delegationRules {
+			if filter(rule) {
+				rules = append(rules, rule)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delegation rules: %v", err)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Id < rules[j].Id })
+	return rules, nil
+}
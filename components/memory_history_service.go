@@ -0,0 +1,205 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryHistoryService 是 HistoryService 接口的内存实现
+type MemoryHistoryService struct {
+	DB *sql.DB
+}
+
+var memoryHistoryServiceInstance *MemoryHistoryService
+var memoryHistoryServiceOnce sync.Once
+
+// InitializeMemoryHistoryService 初始化单例实例
+func InitializeMemoryHistoryService(db *sql.DB) {
+	memoryHistoryServiceOnce.Do(func() {
+		memoryHistoryServiceInstance = &MemoryHistoryService{DB: db}
+	})
+}
+
+// GetMemoryHistoryService 获取单例实例
+func GetMemoryHistoryService() *MemoryHistoryService {
+	if memoryHistoryServiceInstance == nil {
+		panic("MemoryHistoryService is not initialized. Call InitializeMemoryHistoryService first.")
+	}
+	return memoryHistoryServiceInstance
+}
+
+func (service *MemoryHistoryService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+func (service *MemoryHistoryService) CopyNodeInstanceById(tx *sql.Tx, nodeId int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[nodeId]
+		if !ok {
+			return nil
+		}
+		if _, exists := data.historicNodeInstances[nodeId]; exists {
+			return fmt.Errorf("duplicate historic node instance id: %d", nodeId)
+		}
+		row.Status = NODE_STATUS_COMPLETED
+		data.historicNodeInstances[nodeId] = row
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy node instance to historic_node_instance: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryHistoryService) CopyNodeInstance(tx *sql.Tx, nodeId int, processInstanceId int, processDefinitionName string, nodeName string, executionId string,
+	previousExecutionId string, assignee string) (int, error) {
+	err := memoryExec(tx, func(data *memoryData) error {
+		if _, exists := data.historicNodeInstances[nodeId]; exists {
+			return fmt.Errorf("duplicate historic node instance id: %d", nodeId)
+		}
+		data.historicNodeInstances[nodeId] = memoryNodeRow{
+			Id:                    nodeId,
+			ProcessInstanceId:     processInstanceId,
+			ProcessDefinitionName: processDefinitionName,
+			NodeName:              nodeName,
+			ExecutionId:           executionId,
+			PreviousExecutionId:   sql.NullString{String: previousExecutionId, Valid: true},
+			Assignee:              assignee,
+			StartTime:             time.Now(),
+			Status:                NODE_STATUS_COMPLETED,
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy node instance to historic: %v", err)
+	}
+	return nodeId, nil
+}
+
+func (service *MemoryHistoryService) ArchiveNodeInstance(tx *sql.Tx, nodeId int, status string, comment string) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[nodeId]
+		if !ok {
+			return nil
+		}
+		if _, exists := data.historicNodeInstances[nodeId]; exists {
+			return fmt.Errorf("duplicate historic node instance id: %d", nodeId)
+		}
+		row.EndTime = sql.NullTime{Time: time.Now(), Valid: true}
+		row.Status = status
+		row.Comment = comment
+		data.historicNodeInstances[nodeId] = row
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive node instance: %v", err)
+	}
+	return nil
+}
+
+// ArchiveProcessInstance 把流程实例复制到历史流程实例表 同时记录持续时间和结束事件
+func (service *MemoryHistoryService) ArchiveProcessInstance(tx *sql.Tx, processInstanceId int, endExecutionId string) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		instance, ok := data.processInstances[processInstanceId]
+		if !ok {
+			return fmt.Errorf("process instance %d not found", processInstanceId)
+		}
+		if _, exists := data.historicProcessInstances[processInstanceId]; exists {
+			return fmt.Errorf("duplicate historic process instance id: %d", processInstanceId)
+		}
+		endTime := time.Now()
+		if instance.EndTime != nil {
+			endTime = *instance.EndTime
+		}
+		instance.EndTime = &endTime
+		data.historicProcessInstances[processInstanceId] = HistoricProcessInstance{
+			ProcessInstance: instance,
+			Duration:        endTime.Sub(instance.StartTime).Milliseconds(),
+			EndExecutionId:  endExecutionId,
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive process instance: %v", err)
+	}
+	return nil
+}
+
+// GetHistoricProcessInstanceById 根据id查询历史流程实例
+func (service *MemoryHistoryService) GetHistoricProcessInstanceById(id int) (*HistoricProcessInstance, error) {
+	var instance *HistoricProcessInstance
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		if existing, ok := data.historicProcessInstances[id]; ok {
+			instance = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historic process instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+func (service *MemoryHistoryService) GetHistoricNodeInstanceById(id int) (*NodeInstance, error) {
+	var instance *NodeInstance
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		if row, ok := data.historicNodeInstances[id]; ok {
+			instance = row.toNodeInstance()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historic node instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+func (service *MemoryHistoryService) GetProcessCompleteTask(ProcessInstanceId int) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, row := range sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+			return row.ProcessInstanceId == ProcessInstanceId
+		}) {
+			results = append(results, row.toMap())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// AddTaskOperation 记录一条审批节点的操作
+func (service *MemoryHistoryService) AddTaskOperation(tx *sql.Tx, operation TaskOperation) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		operation.Id = data.nextId("task_operation")
+		data.taskOperations[operation.Id] = operation
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add task operation: %v", err)
+	}
+	return nil
+}
+
+// GetTaskOperations 查询流程实例的审批节点操作记录
+func (service *MemoryHistoryService) GetTaskOperations(processInstanceId int) ([]TaskOperation, error) {
+	var operations []TaskOperation
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, operation := range data.taskOperations {
+			if operation.ProcessInstanceId == processInstanceId {
+				operations = append(operations, operation)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task operations: %v", err)
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Id < operations[j].Id })
+	return operations, nil
+}
@@ -0,0 +1,391 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryJobService 是 JobService 接口的内存实现
+type MemoryJobService struct {
+	DB *sql.DB
+}
+
+var memoryJobServiceInstance *MemoryJobService
+var memoryJobServiceOnce sync.Once
+
+// InitializeMemoryJobService 初始化单例实例
+func InitializeMemoryJobService(db *sql.DB) {
+	memoryJobServiceOnce.Do(func() {
+		memoryJobServiceInstance = &MemoryJobService{DB: db}
+	})
+}
+
+// GetMemoryJobService 获取单例实例
+func GetMemoryJobService() *MemoryJobService {
+	if memoryJobServiceInstance == nil {
+		panic("MemoryJobService is not initialized. Call InitializeMemoryJobService first.")
+	}
+	return memoryJobServiceInstance
+}
+
+func (service *MemoryJobService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+func (service *MemoryJobService) CreateTimerJob(tx *sql.Tx, job *TimerJob) (int, error) {
+	var id int
+	err := memoryExec(tx, func(data *memoryData) error {
+		id = data.nextId("timer_job")
+		row := *job
+		row.Id = id
+		row.CreatedAt = time.Now()
+		data.timerJobs[id] = row
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create timer job: %v", err)
+	}
+	return id, nil
+}
+
+func (service *MemoryJobService) GetDueTimerJobs(now time.Time, limit int) ([]TimerJob, error) {
+	var jobs []TimerJob
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, job := range data.timerJobs {
+			if instance, ok := data.processInstances[job.ProcessInstanceId]; ok && processInstancePaused(instance.Status) {
+				continue
+			}
+			if !job.DueTime.After(now) {
+				jobs = append(jobs, job)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due timer jobs: %v", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].DueTime.Equal(jobs[j].DueTime) {
+			return jobs[i].DueTime.Before(jobs[j].DueTime)
+		}
+		return jobs[i].Id < jobs[j].Id
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+// LockTimerJob 内存数据库的事务是串行的，读取就等于加锁
+func (service *MemoryJobService) LockTimerJob(tx *sql.Tx, id int) (*TimerJob, error) {
+	var job *TimerJob
+	err := memoryExec(tx, func(data *memoryData) error {
+		if existing, ok := data.timerJobs[id]; ok {
+			job = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock timer job: %v", err)
+	}
+	return job, nil
+}
+
+func (service *MemoryJobService) UpdateTimerJobFailure(tx *sql.Tx, id int, attempts int, dueTime time.Time, lastError string) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		job, ok := data.timerJobs[id]
+		if !ok {
+			return nil
+		}
+		job.Attempts = attempts
+		job.DueTime = dueTime
+		job.LastError = lastError
+		data.timerJobs[id] = job
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update timer job: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) DeleteTimerJob(tx *sql.Tx, id int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		delete(data.timerJobs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete timer job: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) DeleteTimerJobsByNodeInstance(tx *sql.Tx, nodeInstanceId int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		for id, job := range data.timerJobs {
+			if job.NodeInstanceId == nodeInstanceId {
+				delete(data.timerJobs, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete timer jobs of node instance: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) DeleteTimerJobsByProcessInstance(tx *sql.Tx, processInstanceId int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		for id, job := range data.timerJobs {
+			if job.ProcessInstanceId == processInstanceId {
+				delete(data.timerJobs, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete timer jobs of process instance: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) CreateAsyncJob(tx *sql.Tx, job *AsyncJob) (int, error) {
+	var id int
+	err := memoryExec(tx, func(data *memoryData) error {
+		id = data.nextId("async_job")
+		row := *job
+		row.Id = id
+		row.CreatedAt = time.Now()
+		data.asyncJobs[id] = row
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create async job: %v", err)
+	}
+	return id, nil
+}
+
+func (service *MemoryJobService) GetDueAsyncJobs(now time.Time, limit int) ([]AsyncJob, error) {
+	var jobs []AsyncJob
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, job := range data.asyncJobs {
+			if instance, ok := data.processInstances[job.ProcessInstanceId]; ok && processInstancePaused(instance.Status) {
+				continue
+			}
+			if !job.DueTime.After(now) {
+				jobs = append(jobs, job)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get async jobs: %v", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].DueTime.Equal(jobs[j].DueTime) {
+			return jobs[i].DueTime.Before(jobs[j].DueTime)
+		}
+		return jobs[i].Id < jobs[j].Id
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (service *MemoryJobService) LockAsyncJob(tx *sql.Tx, id int) (*AsyncJob, error) {
+	var job *AsyncJob
+	err := memoryExec(tx, func(data *memoryData) error {
+		if existing, ok := data.asyncJobs[id]; ok {
+			job = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock async job: %v", err)
+	}
+	return job, nil
+}
+
+func (service *MemoryJobService) UpdateAsyncJobFailure(tx *sql.Tx, id int, attempts int, dueTime time.Time, lastError string) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		job, ok := data.asyncJobs[id]
+		if !ok {
+			return nil
+		}
+		job.Attempts = attempts
+		job.DueTime = dueTime
+		job.LastError = lastError
+		data.asyncJobs[id] = job
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update async job: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) DeleteAsyncJob(tx *sql.Tx, id int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		delete(data.asyncJobs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete async job: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) GetAsyncJobsByProcessInstance(tx *sql.Tx, processInstanceId int) ([]AsyncJob, error) {
+	var jobs []AsyncJob
+	err := memoryExec(tx, func(data *memoryData) error {
+		for _, job := range data.asyncJobs {
+			if job.ProcessInstanceId == processInstanceId {
+				jobs = append(jobs, job)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get async jobs: %v", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Id < jobs[j].Id })
+	return jobs, nil
+}
+
+func (service *MemoryJobService) DeleteAsyncJobsByProcessInstance(tx *sql.Tx, processInstanceId int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		for id, job := range data.asyncJobs {
+			if job.ProcessInstanceId == processInstanceId {
+				delete(data.asyncJobs, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete async jobs of process instance: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) MoveAsyncJobToDeadLetter(tx *sql.Tx, id int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		job, ok := data.asyncJobs[id]
+		if !ok {
+			return nil
+		}
+		if _, exists := data.deadLetterJobs[id]; exists {
+			return fmt.Errorf("duplicate dead letter job id: %d", id)
+		}
+		data.deadLetterJobs[id] = DeadLetterJob{AsyncJob: job, FailedAt: time.Now()}
+		delete(data.asyncJobs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move async job to dead letter: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) GetDeadLetterJobs() ([]DeadLetterJob, error) {
+	var jobs []DeadLetterJob
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, job := range data.deadLetterJobs {
+			jobs = append(jobs, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter jobs: %v", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Id < jobs[j].Id })
+	return jobs, nil
+}
+
+func (service *MemoryJobService) LockDeadLetterJob(tx *sql.Tx, id int) (*DeadLetterJob, error) {
+	var job *DeadLetterJob
+	err := memoryExec(tx, func(data *memoryData) error {
+		if existing, ok := data.deadLetterJobs[id]; ok {
+			job = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock dead letter job: %v", err)
+	}
+	return job, nil
+}
+
+func (service *MemoryJobService) DeleteDeadLetterJob(tx *sql.Tx, id int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		delete(data.deadLetterJobs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter job: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) MoveTimerJobToDeadLetter(tx *sql.Tx, id int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		job, ok := data.timerJobs[id]
+		if !ok {
+			return nil
+		}
+		if _, exists := data.deadLetterTimerJobs[id]; exists {
+			return fmt.Errorf("duplicate dead letter timer job id: %d", id)
+		}
+		data.deadLetterTimerJobs[id] = DeadLetterTimerJob{TimerJob: job, FailedAt: time.Now()}
+		delete(data.timerJobs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move timer job to dead letter: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryJobService) GetDeadLetterTimerJobs() ([]DeadLetterTimerJob, error) {
+	var jobs []DeadLetterTimerJob
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, job := range data.deadLetterTimerJobs {
+			jobs = append(jobs, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter timer jobs: %v", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Id < jobs[j].Id })
+	return jobs, nil
+}
+
+func (service *MemoryJobService) LockDeadLetterTimerJob(tx *sql.Tx, id int) (*DeadLetterTimerJob, error) {
+	var job *DeadLetterTimerJob
+	err := memoryExec(tx, func(data *memoryData) error {
+		if existing, ok := data.deadLetterTimerJobs[id]; ok {
+			job = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock dead letter timer job: %v", err)
+	}
+	return job, nil
+}
+
+func (service *MemoryJobService) DeleteDeadLetterTimerJob(tx *sql.Tx, id int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		delete(data.deadLetterTimerJobs, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter timer job: %v", err)
+	}
+	return nil
+}
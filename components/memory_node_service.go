@@ -0,0 +1,512 @@
+package components
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryNodeService 是 NodeService 接口的内存实现
+type MemoryNodeService struct {
+	DB *sql.DB
+}
+
+var memoryNodeServiceInstance *MemoryNodeService
+var memoryNodeServiceOnce sync.Once
+
+// InitializeMemoryNodeService 初始化单例实例
+func InitializeMemoryNodeService(db *sql.DB) {
+	memoryNodeServiceOnce.Do(func() {
+		memoryNodeServiceInstance = &MemoryNodeService{DB: db}
+	})
+}
+
+// GetMemoryNodeService 获取单例实例
+func GetMemoryNodeService() *MemoryNodeService {
+	if memoryNodeServiceInstance == nil {
+		panic("MemoryNodeService is not initialized. Call InitializeMemoryNodeService first.")
+	}
+	return memoryNodeServiceInstance
+}
+
+func (service *MemoryNodeService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// InitNodeInstance 创建一个新的节点实例
+func (service *MemoryNodeService) InitNodeInstance(tx *sql.Tx, processInstanceId int, processDefinitionName string, nodeName string, executionId string, previousExecutionId string, assignee string) (int, error) {
+	var id int
+	err := memoryExec(tx, func(data *memoryData) error {
+		id = data.nextId("node_instance")
+		data.nodeInstances[id] = memoryNodeRow{
+			Id:                    id,
+			ProcessInstanceId:     processInstanceId,
+			ProcessDefinitionName: processDefinitionName,
+			NodeName:              nodeName,
+			ExecutionId:           executionId,
+			PreviousExecutionId:   sql.NullString{String: previousExecutionId, Valid: true},
+			Assignee:              assignee,
+			StartTime:             time.Now(),
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start node instance: %v", err)
+	}
+	return id, nil
+}
+
+// GetAttributeByExpression 根据表达式获取属性值 取节点表里该结构id最新的一条输出
+func (service *MemoryNodeService) GetAttributeByExpression(tx *sql.Tx, expression string, processInstanceId int) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for _, attr := range ExtractAttributes(expression) {
+		parts := strings.Split(attr, ".")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid attribute format: %s", attr)
+		}
+		executionId := parts[0]
+
+		var outputData sql.NullString
+		found := false
+		err := memoryExec(tx, func(data *memoryData) error {
+			rows := sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+				return row.ExecutionId == executionId && row.ProcessInstanceId == processInstanceId
+			})
+			if len(rows) > 0 {
+				found = true
+				outputData = rows[len(rows)-1].OutputData
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query database: %v", err)
+		}
+		if !found {
+			return nil, fmt.Errorf("no data found for execution_id: %s", executionId)
+		}
+
+		var data interface{}
+		if err := json.Unmarshal([]byte(outputData.String), &data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+		value, exists := lookupPath(data, parts[1:])
+		if !exists {
+			return nil, fmt.Errorf("attribute %s not found in output data", attr)
+		}
+		result[attr] = value
+	}
+	return result, nil
+}
+
+// CountParallelGatewayIncoming 事务是串行的 直接计数即可
+func (service *MemoryNodeService) CountParallelGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string) (int, error) {
+	var count int
+	err := memoryExec(tx, func(data *memoryData) error {
+		for _, row := range data.nodeInstances {
+			if row.ProcessInstanceId == processInstanceId && row.ExecutionId == executionId {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count parallel gateway incoming: %v", err)
+	}
+	return count, nil
+}
+
+func (service *MemoryNodeService) CountInclusiveGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string, afterId int) (int, error) {
+	var count int
+	err := memoryExec(tx, func(data *memoryData) error {
+		for _, row := range data.nodeInstances {
+			if row.ProcessInstanceId == processInstanceId && row.ExecutionId == executionId && row.Id > afterId {
+				count++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count inclusive gateway incoming: %v", err)
+	}
+	return count, nil
+}
+
+func (service *MemoryNodeService) GetLatestCompletedNodeInstance(tx *sql.Tx, processInstanceId int, executionId string) (*NodeInstance, error) {
+	var instance *NodeInstance
+	err := memoryExec(tx, func(data *memoryData) error {
+		rows := sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+			return row.ProcessInstanceId == processInstanceId && row.ExecutionId == executionId && row.OutputData.Valid
+		})
+		if len(rows) > 0 {
+			instance = rows[len(rows)-1].toNodeInstance()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest completed node instance: %v", err)
+	}
+	return instance, nil
+}
+
+// GetNodeInstanceById 根据Id获取节点实例
+func (service *MemoryNodeService) GetNodeInstanceById(id int) (*NodeInstance, error) {
+	var instance *NodeInstance
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		if row, ok := data.nodeInstances[id]; ok {
+			instance = row.toNodeInstance()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// LockNodeInstance 内存事务本身是串行的 直接读取即可
+func (service *MemoryNodeService) LockNodeInstance(tx *sql.Tx, id int) (*NodeInstance, error) {
+	var instance *NodeInstance
+	err := memoryExec(tx, func(data *memoryData) error {
+		if row, ok := data.nodeInstances[id]; ok {
+			instance = row.toNodeInstance()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock node instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// GetNodeInstancesByProcessInstanceId 根据流程实例Id获取节点实例列表
+func (service *MemoryNodeService) GetNodeInstancesByProcessInstanceId(processInstanceId int) ([]*NodeInstance, error) {
+	var instances []*NodeInstance
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, row := range sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+			return row.ProcessInstanceId == processInstanceId
+		}) {
+			instances = append(instances, row.toNodeInstance())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node instances by process instance Id: %v", err)
+	}
+	return instances, nil
+}
+
+// LockNodeInstancesByProcessInstanceId 内存事务本身是串行的 直接按id顺序读取
+func (service *MemoryNodeService) LockNodeInstancesByProcessInstanceId(tx *sql.Tx, processInstanceId int) ([]NodeInstance, error) {
+	var instances []NodeInstance
+	err := memoryExec(tx, func(data *memoryData) error {
+		for _, row := range data.nodeInstances {
+			if row.ProcessInstanceId == processInstanceId {
+				instances = append(instances, *row.toNodeInstance())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock node instances by process instance Id: %v", err)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Id < instances[j].Id })
+	return instances, nil
+}
+
+// DeleteNodeInstance 根据Id删除节点实例
+func (service *MemoryNodeService) DeleteNodeInstance(tx *sql.Tx, id int) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		delete(data.nodeInstances, id)
+		for candidateId, row := range data.nodeCandidates {
+			if row.NodeInstanceId == id {
+				delete(data.nodeCandidates, candidateId)
+			}
+		}
+		return nil
+	})
+}
+
+// AddNodeCandidates 记录审批节点的候选人和候选组
+func (service *MemoryNodeService) AddNodeCandidates(tx *sql.Tx, processInstanceId int, nodeInstanceId int, users []string, groups []string) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		add := func(candidateType string, candidates []string) {
+			for _, candidate := range candidates {
+				id := data.nextId("node_candidate")
+				data.nodeCandidates[id] = memoryCandidateRow{Id: id, NodeInstanceId: nodeInstanceId, ProcessInstanceId: processInstanceId, CandidateType: candidateType, CandidateId: candidate}
+			}
+		}
+		add(CANDIDATE_TYPE_USER, users)
+		add(CANDIDATE_TYPE_GROUP, groups)
+		return nil
+	})
+}
+
+// GetNodeCandidates 查询审批节点的候选人和候选组 按记录的顺序
+func (service *MemoryNodeService) GetNodeCandidates(tx *sql.Tx, nodeInstanceId int) ([]string, []string, error) {
+	var users, groups []string
+	err := memoryExec(tx, func(data *memoryData) error {
+		for _, row := range sortedCandidateRows(data.nodeCandidates, func(row memoryCandidateRow) bool { return row.NodeInstanceId == nodeInstanceId }) {
+			if row.CandidateType == CANDIDATE_TYPE_GROUP {
+				groups = append(groups, row.CandidateId)
+			} else {
+				users = append(users, row.CandidateId)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query node candidates: %v", err)
+	}
+	return users, groups, nil
+}
+
+// GetCandidateUndoneTask 用户的待办 包括直接分配的和可以认领的审批节点
+func (service *MemoryNodeService) GetCandidateUndoneTask(userId string, groups []string) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		candidate := make(map[int]bool)
+		for _, row := range data.nodeCandidates {
+			if (row.CandidateType == CANDIDATE_TYPE_USER && row.CandidateId == userId) ||
+				(row.CandidateType == CANDIDATE_TYPE_GROUP && slices.Contains(groups, row.CandidateId)) {
+				candidate[row.NodeInstanceId] = true
+			}
+		}
+		rows := sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+			return !row.OutputData.Valid && (row.Assignee == userId || (row.Assignee == "" && candidate[row.Id]))
+		})
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+		for _, row := range rows {
+			results = append(results, row.toMap())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// 按id排序筛选候选人行
+func sortedCandidateRows(table map[int]memoryCandidateRow, filter func(row memoryCandidateRow) bool) []memoryCandidateRow {
+	var rows []memoryCandidateRow
+	for _, row := range table {
+		if filter(row) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+	return rows
+}
+
+// UpdateNodeInstanceOutput 更新节点实例的输出数据
+func (service *MemoryNodeService) UpdateNodeInstanceOutput(tx *sql.Tx, id int, outputData string) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[id]
+		if !ok {
+			return nil
+		}
+		row.OutputData = sql.NullString{String: outputData, Valid: true}
+		row.EndTime = sql.NullTime{Time: time.Now(), Valid: true}
+		data.nodeInstances[id] = row
+		return nil
+	})
+}
+
+func (service *MemoryNodeService) UpdateNodeInstanceAssignee(tx *sql.Tx, id int, assignee string) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[id]
+		if !ok {
+			return nil
+		}
+		row.Assignee = assignee
+		data.nodeInstances[id] = row
+		return nil
+	})
+}
+
+// UpdateNodeInstanceDelegation 修改审批节点的负责人、所有人和委派状态
+func (service *MemoryNodeService) UpdateNodeInstanceDelegation(tx *sql.Tx, id int, assignee string, owner string, delegationState string) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[id]
+		if !ok {
+			return nil
+		}
+		row.Assignee = assignee
+		row.Owner = owner
+		row.DelegationState = delegationState
+		data.nodeInstances[id] = row
+		return nil
+	})
+}
+
+func (service *MemoryNodeService) GetProcessVariables(tx *sql.Tx, processInstanceId int) (map[string]any, error) {
+	variables := make(map[string]any)
+	err := memoryExec(tx, func(data *memoryData) error {
+		for _, row := range sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+			return row.ProcessInstanceId == processInstanceId && row.OutputData.Valid
+		}) {
+			var value any
+			if err := json.Unmarshal([]byte(row.OutputData.String), &value); err != nil {
+				return fmt.Errorf("failed to unmarshal output data of %s: %v", row.ExecutionId, err)
+			}
+			variables[row.ExecutionId] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query process variables: %v", err)
+	}
+	return variables, nil
+}
+
+func (service *MemoryNodeService) GetAssigneeUndoneTask(assignee string) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, row := range sortedNodeRows(data.nodeInstances, func(row memoryNodeRow) bool {
+			return row.Assignee == assignee && !row.OutputData.Valid
+		}) {
+			results = append(results, row.toMap())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (service *MemoryNodeService) GetTaskDetailByTaskId(taskId int) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		if row, ok := data.nodeInstances[taskId]; ok {
+			result = row.toMap()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task detail: %v", err)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("task with id %d not found", taskId)
+	}
+	return result, nil
+}
+
+func (service *MemoryNodeService) GetTaskForm(processDefinitionName string, executionId string) (string, error) {
+	model, err := getLatestModel(processDefinitionName)
+	if err != nil {
+		return "", err
+	}
+	formdata := model.Tasks[executionId].FormData
+	return formdata, nil
+}
+
+func (service *MemoryNodeService) GetTaskFormByVersion(processDefinitionName string, version int, executionId string) (string, error) {
+	model, err := getModelByVersion(processDefinitionName, version)
+	if err != nil {
+		return "", err
+	}
+	formdata := model.Tasks[executionId].FormData
+	return formdata, nil
+}
+
+func (service *MemoryNodeService) ClearProcessData(tx *sql.Tx, processInstanceId int) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		for id, row := range data.nodeInstances {
+			if row.ProcessInstanceId == processInstanceId {
+				delete(data.nodeInstances, id)
+			}
+		}
+		for id, row := range data.nodeCandidates {
+			if row.ProcessInstanceId == processInstanceId {
+				delete(data.nodeCandidates, id)
+			}
+		}
+		return nil
+	})
+}
+
+// 按开始时间和自增id排序筛选节点行，和数据库里 ORDER BY start_time 的结果保持一致
+func sortedNodeRows(table map[int]memoryNodeRow, filter func(row memoryNodeRow) bool) []memoryNodeRow {
+	var rows []memoryNodeRow
+	for _, row := range table {
+		if filter(row) {
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].StartTime.Equal(rows[j].StartTime) {
+			return rows[i].StartTime.Before(rows[j].StartTime)
+		}
+		return rows[i].Id < rows[j].Id
+	})
+	return rows
+}
+
+// UpdateNodeInstanceSubstitute 按代理规则把审批节点交给代理人 记录原来的负责人
+func (service *MemoryNodeService) UpdateNodeInstanceSubstitute(tx *sql.Tx, id int, substitute string, originalAssignee string) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[id]
+		if !ok {
+			return nil
+		}
+		row.Assignee = substitute
+		row.OriginalAssignee = originalAssignee
+		data.nodeInstances[id] = row
+		return nil
+	})
+}
+
+// UpdateNodeInstanceDueDate 记录审批节点的到期时间
+func (service *MemoryNodeService) UpdateNodeInstanceDueDate(tx *sql.Tx, id int, dueDate time.Time) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		row, ok := data.nodeInstances[id]
+		if !ok {
+			return nil
+		}
+		row.DueDate = sql.NullTime{Time: dueDate, Valid: true}
+		data.nodeInstances[id] = row
+		return nil
+	})
+}
+
+func (row memoryNodeRow) toNodeInstance() *NodeInstance {
+	return &NodeInstance{
+		Id:                    row.Id,
+		ProcessInstanceId:     row.ProcessInstanceId,
+		ProcessDefinitionName: row.ProcessDefinitionName,
+		NodeName:              row.NodeName,
+		ExecutionId:           row.ExecutionId,
+		OutputData:            row.OutputData.String,
+		PreviousExecutionId:   row.PreviousExecutionId.String,
+		Assignee:              row.Assignee,
+		StartTime:             row.StartTime,
+		EndTime:               row.EndTime.Time,
+		Owner:                 row.Owner,
+		DelegationState:       row.DelegationState,
+		OriginalAssignee:      row.OriginalAssignee,
+		DueDate:               row.DueDate.Time,
+	}
+}
+
+// 和数据库实现返回的 map 保持相同的键
+func (row memoryNodeRow) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                      row.Id,
+		"process_instance_id":     row.ProcessInstanceId,
+		"process_definition_name": row.ProcessDefinitionName,
+		"node_name":               row.NodeName,
+		"execution_id":            row.ExecutionId,
+		"output_data":             nilIfEmpty(row.OutputData),
+		"previous_execution_id":   nilIfEmpty(row.PreviousExecutionId),
+		"assignee":                row.Assignee,
+		"start_time":              row.StartTime,
+		"end_time":                nilIfEmptyTime(row.EndTime),
+		"due_date":                nilIfEmptyTime(row.DueDate),
+	}
+}
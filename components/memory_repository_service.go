@@ -0,0 +1,159 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// MemoryRepositoryService 是 RepositoryService 接口的内存实现
+type MemoryRepositoryService struct {
+	DB *sql.DB
+}
+
+var memoryRepositoryServiceInstance *MemoryRepositoryService
+var memoryRepositoryServiceOnce sync.Once
+
+// InitializeMemoryRepositoryService 初始化单例实例
+func InitializeMemoryRepositoryService(db *sql.DB) {
+	memoryRepositoryServiceOnce.Do(func() {
+		memoryRepositoryServiceInstance = &MemoryRepositoryService{DB: db}
+	})
+}
+
+// GetMemoryRepositoryService 获取单例实例
+func GetMemoryRepositoryService() *MemoryRepositoryService {
+	if memoryRepositoryServiceInstance == nil {
+		panic("MemoryRepositoryService is not initialized. Call InitializeMemoryRepositoryService first.")
+	}
+	return memoryRepositoryServiceInstance
+}
+
+func (service *MemoryRepositoryService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// SaveProcessDefinition 插入新的流程定义 版本号在同名定义的最大版本上 +1
+func (service *MemoryRepositoryService) SaveProcessDefinition(tx *sql.Tx, pd *ProcessDefinition) (int, error) {
+	if err := validateProcessDefinitionXML(pd.XMLContent); err != nil {
+		return 0, fmt.Errorf("failed to save process definition: %w", err)
+	}
+
+	var id int
+	err := memoryExec(tx, func(data *memoryData) error {
+		version := 0
+		for _, existing := range data.processDefinitions {
+			if existing.ProcessDefinitionName == pd.ProcessDefinitionName && existing.Version > version {
+				version = existing.Version
+			}
+		}
+		id = data.nextId("process_definition")
+		saved := *pd
+		saved.Id = id
+		saved.Version = version + 1
+		data.processDefinitions[id] = saved
+		InvalidateModel(saved.ProcessDefinitionName, saved.Version)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save process definition: %v", err)
+	}
+	return id, nil
+}
+
+// GetProcessDefinitionById 根据Id获取流程定义
+func (service *MemoryRepositoryService) GetProcessDefinitionById(id int) (*ProcessDefinition, error) {
+	var pd *ProcessDefinition
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		if existing, ok := data.processDefinitions[id]; ok {
+			pd = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get process definition by Id: %v", err)
+	}
+	return pd, nil
+}
+
+// GetProcessDefinitionByNameAndVersion 根据流程名称和版本号获取流程定义
+func (service *MemoryRepositoryService) GetProcessDefinitionByNameAndVersion(name string, version int) (*ProcessDefinition, error) {
+	var pd *ProcessDefinition
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, existing := range data.processDefinitions {
+			if existing.ProcessDefinitionName == name && existing.Version == version {
+				pd = &existing
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get process definition by process_definition_name and version: %v", err)
+	}
+	return pd, nil
+}
+
+// GetLatestProcessDefinitionByName 根据流程名称获取最新流程定义
+func (service *MemoryRepositoryService) GetLatestProcessDefinitionByName(name string) (*ProcessDefinition, error) {
+	var pd *ProcessDefinition
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, existing := range data.processDefinitions {
+			if existing.ProcessDefinitionName == name && (pd == nil || existing.Version > pd.Version) {
+				latest := existing
+				pd = &latest
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get process definition by name and version: %v", err)
+	}
+	return pd, nil
+}
+
+// GetLatestVersionByName 根据流程名称获取最新的版本号，没有部署过返回 0
+func (service *MemoryRepositoryService) GetLatestVersionByName(name string) (int, error) {
+	version := 0
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, existing := range data.processDefinitions {
+			if existing.ProcessDefinitionName == name && existing.Version > version {
+				version = existing.Version
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest version of process definition: %v", err)
+	}
+	return version, nil
+}
+
+// UpdateProcessDefinition 更新流程定义 只允许改数据 不允许改结构 ，名称和版本都不变
+func (service *MemoryRepositoryService) UpdateProcessDefinition(tx *sql.Tx, pd *ProcessDefinition) error {
+	if err := validateProcessDefinitionXML(pd.XMLContent); err != nil {
+		return fmt.Errorf("failed to update process definition: %w", err)
+	}
+	return memoryExec(tx, func(data *memoryData) error {
+		existing, ok := data.processDefinitions[pd.Id]
+		if !ok {
+			return nil
+		}
+		existing.XMLContent = pd.XMLContent
+		existing.CreatedBy = pd.CreatedBy
+		data.processDefinitions[pd.Id] = existing
+		InvalidateModel(existing.ProcessDefinitionName, existing.Version)
+		return nil
+	})
+}
+
+// DeleteProcessDefinition 根据Id删除流程定义
+func (service *MemoryRepositoryService) DeleteProcessDefinition(tx *sql.Tx, id int) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		if existing, ok := data.processDefinitions[id]; ok {
+			InvalidateModel(existing.ProcessDefinitionName, existing.Version)
+		}
+		delete(data.processDefinitions, id)
+		return nil
+	})
+}
@@ -0,0 +1,226 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryRuntimeService 是 RuntimeService 接口的内存实现
+type MemoryRuntimeService struct {
+	DB *sql.DB
+}
+
+var memoryRuntimeServiceInstance *MemoryRuntimeService
+var memoryRuntimeServiceOnce sync.Once
+
+// InitializeMemoryRuntimeService 初始化单例实例
+func InitializeMemoryRuntimeService(db *sql.DB) {
+	memoryRuntimeServiceOnce.Do(func() {
+		memoryRuntimeServiceInstance = &MemoryRuntimeService{DB: db}
+	})
+}
+
+// GetMemoryRuntimeService 获取单例实例
+func GetMemoryRuntimeService() *MemoryRuntimeService {
+	if memoryRuntimeServiceInstance == nil {
+		panic("MemoryRuntimeService is not initialized. Call InitializeMemoryRuntimeService first.")
+	}
+	return memoryRuntimeServiceInstance
+}
+
+func (service *MemoryRuntimeService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// StartProcessInstance 创建一个新的流程实例
+func (service *MemoryRuntimeService) StartProcessInstance(tx *sql.Tx, processDefinitionName string, business_key string, createdBy string, formParams string) (int, error) {
+	model, err := getLatestModel(processDefinitionName)
+	if err != nil {
+		return 0, finishTransaction(tx, err)
+	}
+
+	var id int
+	err = memoryExec(tx, func(data *memoryData) error {
+		id = data.nextId("process_instance")
+		data.processInstances[id] = ProcessInstance{
+			Id:                    id,
+			ProcessDefinitionName: processDefinitionName,
+			Version:               model.Version,
+			Business_key:          business_key,
+			Status:                PROCESS_STATUS_RUNNING,
+			CreatedBy:             createdBy,
+			StartTime:             time.Now(),
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, finishTransaction(tx, fmt.Errorf("%w: failed to start process instance: %v", ErrPersistenceFailed, err))
+	}
+
+	if err := finishTransaction(tx, executeStartEvent(tx, model, id, processDefinitionName, createdBy, formParams)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (service *MemoryRuntimeService) CompleteProcessInstance(tx *sql.Tx, ProcessInstanceId int) error {
+	return service.UpdateProcessInstanceStatus(tx, ProcessInstanceId, PROCESS_STATUS_COMPLETE)
+}
+
+// DeleteProcessInstance 删除流程实例
+func (service *MemoryRuntimeService) DeleteProcessInstance(tx *sql.Tx, id int) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		delete(data.processInstances, id)
+		return nil
+	})
+}
+
+// UpdateProcessInstanceStatus 修改流程实例状态 完成和终止时同时记录结束时间
+func (service *MemoryRuntimeService) UpdateProcessInstanceStatus(tx *sql.Tx, id int, status string) error {
+	return memoryExec(tx, func(data *memoryData) error {
+		instance, ok := data.processInstances[id]
+		if !ok {
+			return nil
+		}
+		instance.Status = status
+		instance.EndTime = nil
+		if status == PROCESS_STATUS_COMPLETE || status == PROCESS_STATUS_TERMINATED {
+			endTime := time.Now()
+			instance.EndTime = &endTime
+		}
+		data.processInstances[id] = instance
+		return nil
+	})
+}
+
+// GetProcessInstanceById 根据id查询流程实例
+func (service *MemoryRuntimeService) GetProcessInstanceById(id int) (*ProcessInstance, error) {
+	var instance *ProcessInstance
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		if existing, ok := data.processInstances[id]; ok {
+			instance = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get process instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// LockProcessInstance 内存事务本身是串行的 直接读取即可
+func (service *MemoryRuntimeService) LockProcessInstance(tx *sql.Tx, id int) (*ProcessInstance, error) {
+	var instance *ProcessInstance
+	err := memoryExec(tx, func(data *memoryData) error {
+		if existing, ok := data.processInstances[id]; ok {
+			instance = &existing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock process instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// TerminateProcessInstance 终止流程实例
+func (service *MemoryRuntimeService) TerminateProcessInstance(processInstanceId int, reason string) error {
+	return terminateProcessInstance(service, processInstanceId, reason)
+}
+
+// SuspendProcessInstance 挂起流程实例
+func (service *MemoryRuntimeService) SuspendProcessInstance(processInstanceId int) error {
+	return changeProcessInstanceStatus(service, processInstanceId, PROCESS_STATUS_RUNNING, PROCESS_STATUS_SUSPENDED)
+}
+
+// ResumeProcessInstance 恢复挂起的流程实例
+func (service *MemoryRuntimeService) ResumeProcessInstance(processInstanceId int) error {
+	return changeProcessInstanceStatus(service, processInstanceId, PROCESS_STATUS_SUSPENDED, PROCESS_STATUS_RUNNING)
+}
+
+// ResolveIncident 恢复处于故障状态的流程实例
+func (service *MemoryRuntimeService) ResolveIncident(processInstanceId int) error {
+	return changeProcessInstanceStatus(service, processInstanceId, PROCESS_STATUS_INCIDENT, PROCESS_STATUS_RUNNING)
+}
+
+// ClaimTask 候选人认领审批节点
+func (service *MemoryRuntimeService) ClaimTask(taskId int, userId string) error {
+	return claimTask(service, taskId, userId)
+}
+
+// UnclaimTask 认领人把审批节点退回给候选人
+func (service *MemoryRuntimeService) UnclaimTask(taskId int, userId string) error {
+	return unclaimTask(service, taskId, userId)
+}
+
+// GetUserTasks 查询用户的待办
+func (service *MemoryRuntimeService) GetUserTasks(userId string) ([]map[string]interface{}, error) {
+	return getUserTasks(userId)
+}
+
+// GetUserTasksByDueDate 查询用户的待办 按到期时间排序
+func (service *MemoryRuntimeService) GetUserTasksByDueDate(userId string) ([]map[string]interface{}, error) {
+	return getUserTasksByDueDate(userId)
+}
+
+// SetVariable 设置流程变量
+func (service *MemoryRuntimeService) SetVariable(processInstanceId int, scope string, name string, value any, userId string) error {
+	return setProcessVariable(service, processInstanceId, scope, name, value, userId)
+}
+
+// GetVariables 查询某个作用域的全部流程变量
+func (service *MemoryRuntimeService) GetVariables(processInstanceId int, scope string) (map[string]any, error) {
+	return getProcessVariables(processInstanceId, scope)
+}
+
+// DelegateTask 负责人把审批节点委派给另一个人
+func (service *MemoryRuntimeService) DelegateTask(taskId int, userId string, delegate string, reason string) error {
+	return delegateTask(service, taskId, userId, delegate, reason)
+}
+
+// ResolveTask 委派的人处理完 审批节点回到委派人
+func (service *MemoryRuntimeService) ResolveTask(taskId int, userId string, reason string) error {
+	return resolveTask(service, taskId, userId, reason)
+}
+
+// TransferTask 负责人把审批节点转办给另一个人
+func (service *MemoryRuntimeService) TransferTask(taskId int, userId string, assignee string, reason string) error {
+	return transferTask(service, taskId, userId, assignee, reason)
+}
+
+// AddSigner 负责人在自己之前或者之后加签
+func (service *MemoryRuntimeService) AddSigner(taskId int, userId string, signer string, position string, reason string) error {
+	return addSigner(service, taskId, userId, signer, position, reason)
+}
+
+// AddComment 在流程实例或者审批节点上评论
+func (service *MemoryRuntimeService) AddComment(processInstanceId int, taskId int, userId string, message string) (int, error) {
+	return addComment(service, processInstanceId, taskId, userId, message)
+}
+
+// ListComments 查询评论
+func (service *MemoryRuntimeService) ListComments(processInstanceId int, taskId int) ([]Comment, error) {
+	return listComments(processInstanceId, taskId)
+}
+
+// AddAttachment 记录附件的元数据
+func (service *MemoryRuntimeService) AddAttachment(attachment Attachment) (int, error) {
+	return addAttachment(service, attachment)
+}
+
+// ListAttachments 查询附件
+func (service *MemoryRuntimeService) ListAttachments(processInstanceId int, taskId int) ([]Attachment, error) {
+	return listAttachments(processInstanceId, taskId)
+}
+
+// CompleteTask 负责人完成审批节点
+func (service *MemoryRuntimeService) CompleteTask(taskId int, userId string, output map[string]any) ([]NodeInstance, error) {
+	return completeTask(service, taskId, userId, output)
+}
+
+// SendBack 把审批节点打回到上游的审批节点
+func (service *MemoryRuntimeService) SendBack(taskId int, targetExecutionId string, comment string) ([]NodeInstance, error) {
+	return sendBack(service, taskId, targetExecutionId, comment)
+}
@@ -0,0 +1,55 @@
+package components
+
+import (
+	"database/sql"
+)
+
+// MemoryServiceFactory 不依赖外部数据库，数据都存在进程内存里，用于单元测试和本地调试
+type MemoryServiceFactory struct{}
+
+// InitServiceInstance db 为空时使用默认的内存数据库
+func (f *MemoryServiceFactory) InitServiceInstance(db *sql.DB) {
+	if db == nil {
+		db, _ = OpenMemoryDB("default")
+	}
+	InitializeMemoryRuntimeService(db)
+	InitializeMemoryRepositoryService(db)
+	InitializeMemoryNodeService(db)
+	InitializeMemoryHistoryService(db)
+	InitializeMemoryJobService(db)
+	InitializeMemoryVariableService(db)
+	InitializeMemoryDelegationRuleService(db)
+	InitializeMemoryCommentService(db)
+}
+
+func (f *MemoryServiceFactory) GetRuntimeService() RuntimeService {
+	return GetMemoryRuntimeService()
+}
+
+func (f *MemoryServiceFactory) GetRepositoryService() RepositoryService {
+	return GetMemoryRepositoryService()
+}
+
+func (f *MemoryServiceFactory) GetNodeService() NodeService {
+	return GetMemoryNodeService()
+}
+
+func (f *MemoryServiceFactory) GetHistoryService() HistoryService {
+	return GetMemoryHistoryService()
+}
+
+func (f *MemoryServiceFactory) GetJobService() JobService {
+	return GetMemoryJobService()
+}
+
+func (f *MemoryServiceFactory) GetVariableService() VariableService {
+	return GetMemoryVariableService()
+}
+
+func (f *MemoryServiceFactory) GetDelegationRuleService() DelegationRuleService {
+	return GetMemoryDelegationRuleService()
+}
+
+func (f *MemoryServiceFactory) GetCommentService() CommentService {
+	return GetMemoryCommentService()
+}
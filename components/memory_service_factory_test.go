@@ -0,0 +1,35 @@
+package components
+
+import "testing"
+
+func TestLeaveProcessEndToEnd(t *testing.T) {
+	xmlContent, err := ReadXMLFile("../xml/leave.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const name = "Leave Request Process"
+	deployXML(t, name, xmlContent)
+	id := startProcess(t, name, "ann", `{"employeeName":"ann","leaveType":"Annual Leave","startDate":"2024-05-01","endDate":"2024-05-03","reason":"trip"}`)
+
+	task1 := activeTask(t, id, "approveTask1")
+	task2 := activeTask(t, id, "approveTask2")
+	if task1.Assignee != "SC" || task2.Assignee != "somedata" {
+		t.Fatalf("assignees = %s, %s", task1.Assignee, task2.Assignee)
+	}
+	completeTaskAs(t, task1.Id, "SC", map[string]any{"approvalStatus": "Approve", "comments": "ok"})
+	if status := processStatus(t, id); status != PROCESS_STATUS_RUNNING {
+		t.Fatalf("status after first approval = %s", status)
+	}
+	completeTaskAs(t, task2.Id, "somedata", map[string]any{"approvalStatus": "Approve"})
+
+	historic, err := GetServiceFactory().GetHistoryService().GetHistoricProcessInstanceById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if historic == nil {
+		t.Fatalf("process instance %d was not archived", id)
+	}
+	if historic.Status != PROCESS_STATUS_COMPLETE || historic.EndExecutionId != "approvedEndEvent" {
+		t.Fatalf("historic instance = %s %s", historic.Status, historic.EndExecutionId)
+	}
+}
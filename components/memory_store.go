@@ -0,0 +1,273 @@
+package components
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// 内存数据库的驱动名称，内存版的服务也走 *sql.DB / *sql.Tx，这样节点代码和服务接口不需要区分数据库类型
+const MEMORY_DRIVER_NAME = "zjf_workflow_memory"
+
+// 内存驱动认识的语句，参数是一个操作内存数据的函数
+// 只读操作单独一种语句：不在事务里的读不需要等待其他事务结束，否则事务里的代码调用不带事务的查询会死锁
+const (
+	memoryOperation      = "memory operation"
+	memoryQueryOperation = "memory query"
+)
+
+// 内存里的一行节点实例，字段和 node_instance / historic_node_instance 表一一对应
+type memoryNodeRow struct {
+	Id                    int
+	ProcessInstanceId     int
+	ProcessDefinitionName string
+	NodeName              string
+	ExecutionId           string
+	OutputData            sql.NullString
+	PreviousExecutionId   sql.NullString
+	Assignee              string
+	StartTime             time.Time
+	EndTime               sql.NullTime
+	Owner                 string
+	DelegationState       string // 只有运行表有
+	OriginalAssignee      string
+	DueDate               sql.NullTime
+	// 下面两个只有历史表有
+	Status  string
+	Comment string
+}
+
+// 内存里的一行流程变量，值按 JSON 存储，字段和 process_variable / historic_variable 表一一对应
+type memoryVariableRow struct {
+	Id                int
+	ProcessInstanceId int
+	Scope             string
+	Name              string
+	Type              string
+	Value             sql.NullString
+	ChangedBy         string
+	ChangedAt         time.Time
+	// 只有历史表有
+	Action string
+}
+
+// 内存里的一行审批节点候选人，字段和 node_candidate 表一一对应
+type memoryCandidateRow struct {
+	Id                int
+	NodeInstanceId    int
+	ProcessInstanceId int
+	CandidateType     string
+	CandidateId       string
+}
+
+// memoryData 内存里的全部表，行都按值存储，开启事务时整体复制一份就是快照
+type memoryData struct {
+	processDefinitions       map[int]ProcessDefinition
+	processInstances         map[int]ProcessInstance
+	historicProcessInstances map[int]HistoricProcessInstance
+	nodeInstances            map[int]memoryNodeRow
+	historicNodeInstances    map[int]memoryNodeRow
+	timerJobs                map[int]TimerJob
+	asyncJobs                map[int]AsyncJob
+	deadLetterJobs           map[int]DeadLetterJob
+	deadLetterTimerJobs      map[int]DeadLetterTimerJob
+	variables                map[int]memoryVariableRow
+	historicVariables        map[int]memoryVariableRow
+	nodeCandidates           map[int]memoryCandidateRow
+	taskOperations           map[int]TaskOperation
+	delegationRules          map[int]DelegationRule
+	comments                 map[int]Comment
+	attachments              map[int]Attachment
+	// 各个表的自增主键
+	sequences map[string]int
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		processDefinitions:       make(map[int]ProcessDefinition),
+		processInstances:         make(map[int]ProcessInstance),
+		historicProcessInstances: make(map[int]HistoricProcessInstance),
+		nodeInstances:            make(map[int]memoryNodeRow),
+		historicNodeInstances:    make(map[int]memoryNodeRow),
+		timerJobs:                make(map[int]TimerJob),
+		asyncJobs:                make(map[int]AsyncJob),
+		deadLetterJobs:           make(map[int]DeadLetterJob),
+		deadLetterTimerJobs:      make(map[int]DeadLetterTimerJob),
+		variables:                make(map[int]memoryVariableRow),
+		historicVariables:        make(map[int]memoryVariableRow),
+		nodeCandidates:           make(map[int]memoryCandidateRow),
+		taskOperations:           make(map[int]TaskOperation),
+		delegationRules:          make(map[int]DelegationRule),
+		comments:                 make(map[int]Comment),
+		attachments:              make(map[int]Attachment),
+		sequences:                make(map[string]int),
+	}
+}
+
+func (data *memoryData) clone() *memoryData {
+	return &memoryData{
+		processDefinitions:       maps.Clone(data.processDefinitions),
+		processInstances:         maps.Clone(data.processInstances),
+		historicProcessInstances: maps.Clone(data.historicProcessInstances),
+		nodeInstances:            maps.Clone(data.nodeInstances),
+		historicNodeInstances:    maps.Clone(data.historicNodeInstances),
+		timerJobs:                maps.Clone(data.timerJobs),
+		asyncJobs:                maps.Clone(data.asyncJobs),
+		deadLetterJobs:           maps.Clone(data.deadLetterJobs),
+		deadLetterTimerJobs:      maps.Clone(data.deadLetterTimerJobs),
+		variables:                maps.Clone(data.variables),
+		historicVariables:        maps.Clone(data.historicVariables),
+		nodeCandidates:           maps.Clone(data.nodeCandidates),
+		taskOperations:           maps.Clone(data.taskOperations),
+		delegationRules:          maps.Clone(data.delegationRules),
+		comments:                 maps.Clone(data.comments),
+		attachments:              maps.Clone(data.attachments),
+		sequences:                maps.Clone(data.sequences),
+	}
+}
+
+// 模拟自增主键
+func (data *memoryData) nextId(table string) int {
+	data.sequences[table]++
+	return data.sequences[table]
+}
+
+// memoryStore 一个具名的内存数据库
+// 事务之间是串行的：开启事务就独占写锁，直到提交或者回滚，并行网关汇聚计数因此不会出现并发问题
+// 不在事务里的写要等其他事务结束，否则事务回滚恢复快照时会把这次写入一起丢掉
+// 不在事务里的读只加数据锁，可以读到其他事务未提交的数据
+type memoryStore struct {
+	txLock sync.Mutex
+	mu     sync.Mutex
+	data   *memoryData
+}
+
+var (
+	memoryStores      = make(map[string]*memoryStore)
+	memoryStoresMutex sync.Mutex
+)
+
+func init() {
+	sql.Register(MEMORY_DRIVER_NAME, memoryDriver{})
+}
+
+// OpenMemoryDB 打开一个内存数据库，名称相同的连接共享同一份数据，测试里用不同的名称互相隔离
+func OpenMemoryDB(name string) (*sql.DB, error) {
+	return sql.Open(MEMORY_DRIVER_NAME, name)
+}
+
+func getMemoryStore(name string) *memoryStore {
+	memoryStoresMutex.Lock()
+	defer memoryStoresMutex.Unlock()
+	store, ok := memoryStores[name]
+	if !ok {
+		store = &memoryStore{data: newMemoryData()}
+		memoryStores[name] = store
+	}
+	return store
+}
+
+// 在事务或者连接上执行一个内存操作
+func memoryExec(execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, operation func(data *memoryData) error) error {
+	_, err := execer.Exec(memoryOperation, operation)
+	return err
+}
+
+// 在事务或者连接上执行一个只读的内存操作 operation 不能修改数据
+func memoryQuery(execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, operation func(data *memoryData) error) error {
+	_, err := execer.Exec(memoryQueryOperation, operation)
+	return err
+}
+
+type memoryDriver struct{}
+
+func (memoryDriver) Open(name string) (driver.Conn, error) {
+	return &memoryConn{store: getMemoryStore(name)}, nil
+}
+
+type memoryConn struct {
+	store *memoryStore
+	tx    *memoryTx
+}
+
+// CheckNamedValue 放行所有参数，内存操作函数本身就是作为参数传进来的
+func (conn *memoryConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (conn *memoryConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("memory database does not support prepared statements: %s", query)
+}
+
+func (conn *memoryConn) Close() error {
+	if conn.tx != nil {
+		return conn.tx.Rollback()
+	}
+	return nil
+}
+
+func (conn *memoryConn) Begin() (driver.Tx, error) {
+	return conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (conn *memoryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	conn.store.txLock.Lock()
+	conn.store.mu.Lock()
+	snapshot := conn.store.data.clone()
+	conn.store.mu.Unlock()
+	conn.tx = &memoryTx{conn: conn, snapshot: snapshot}
+	return conn.tx, nil
+}
+
+func (conn *memoryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if (query != memoryOperation && query != memoryQueryOperation) || len(args) != 1 {
+		return nil, fmt.Errorf("memory database does not support query: %s", query)
+	}
+	operation, ok := args[0].Value.(func(data *memoryData) error)
+	if !ok {
+		return nil, errors.New("memory database expects an operation function")
+	}
+	if query == memoryOperation && conn.tx == nil {
+		conn.store.txLock.Lock()
+		defer conn.store.txLock.Unlock()
+	}
+	conn.store.mu.Lock()
+	defer conn.store.mu.Unlock()
+	if err := operation(conn.store.data); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type memoryTx struct {
+	conn     *memoryConn
+	snapshot *memoryData
+}
+
+func (tx *memoryTx) Commit() error {
+	tx.finish()
+	return nil
+}
+
+// Rollback 恢复到事务开启时的快照
+func (tx *memoryTx) Rollback() error {
+	tx.conn.store.mu.Lock()
+	tx.conn.store.data = tx.snapshot
+	tx.conn.store.mu.Unlock()
+	tx.finish()
+	return nil
+}
+
+func (tx *memoryTx) finish() {
+	tx.conn.tx = nil
+	tx.conn.store.txLock.Unlock()
+}
@@ -0,0 +1,40 @@
+package components
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryWriteOutsideTransactionSurvivesRollback(t *testing.T) {
+	rules := GetServiceFactory().GetDelegationRuleService()
+	tx, err := rules.GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := make(chan error)
+	go func() {
+		_, err := rules.AddDelegationRule(&DelegationRule{UserId: "store-user", Substitute: "store-substitute", StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)})
+		added <- err
+	}()
+	// 事务没结束时 不在事务里的读不需要等待
+	if _, err := rules.GetDelegationRules("store-user"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-added; err != nil {
+		t.Fatal(err)
+	}
+	saved, err := rules.GetDelegationRules("store-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 {
+		t.Fatalf("rules after rollback = %d", len(saved))
+	}
+	if err := rules.DeleteDelegationRule(saved[0].Id); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,242 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryVariableService 是 VariableService 接口的内存实现
+type MemoryVariableService struct {
+	DB *sql.DB
+}
+
+var memoryVariableServiceInstance *MemoryVariableService
+var memoryVariableServiceOnce sync.Once
+
+// InitializeMemoryVariableService 初始化单例实例
+func InitializeMemoryVariableService(db *sql.DB) {
+	memoryVariableServiceOnce.Do(func() {
+		memoryVariableServiceInstance = &MemoryVariableService{DB: db}
+	})
+}
+
+// GetMemoryVariableService 获取单例实例
+func GetMemoryVariableService() *MemoryVariableService {
+	if memoryVariableServiceInstance == nil {
+		panic("MemoryVariableService is not initialized. Call InitializeMemoryVariableService first.")
+	}
+	return memoryVariableServiceInstance
+}
+
+func (service *MemoryVariableService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+func (row memoryVariableRow) toProcessVariable() (ProcessVariable, error) {
+	value, err := decodeVariable(row.Value.String)
+	if err != nil {
+		return ProcessVariable{}, err
+	}
+	return ProcessVariable{
+		Id:                row.Id,
+		ProcessInstanceId: row.ProcessInstanceId,
+		Scope:             row.Scope,
+		Name:              row.Name,
+		Type:              row.Type,
+		Value:             value,
+		UpdatedBy:         row.ChangedBy,
+		UpdatedAt:         row.ChangedAt,
+	}, nil
+}
+
+// 记录一条变量历史
+func (data *memoryData) insertHistoricVariable(row memoryVariableRow, action string) {
+	row.Id = data.nextId("historic_variable")
+	row.Action = action
+	data.historicVariables[row.Id] = row
+}
+
+func (service *MemoryVariableService) SetVariable(tx *sql.Tx, processInstanceId int, scope string, name string, value any, changedBy string) error {
+	varType, varValue, err := encodeVariable(name, value)
+	if err != nil {
+		return err
+	}
+	err = memoryExec(tx, func(data *memoryData) error {
+		row := memoryVariableRow{
+			ProcessInstanceId: processInstanceId,
+			Scope:             scope,
+			Name:              name,
+			Type:              varType,
+			Value:             sql.NullString{String: varValue, Valid: true},
+			ChangedBy:         changedBy,
+			ChangedAt:         time.Now(),
+		}
+		action := VARIABLE_ACTION_CREATE
+		for id, existing := range data.variables {
+			if existing.ProcessInstanceId == processInstanceId && existing.Scope == scope && existing.Name == name {
+				row.Id = id
+				action = VARIABLE_ACTION_UPDATE
+				break
+			}
+		}
+		if row.Id == 0 {
+			row.Id = data.nextId("process_variable")
+		}
+		data.variables[row.Id] = row
+		data.insertHistoricVariable(row, action)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set process variable %s: %v", name, err)
+	}
+	return nil
+}
+
+func (service *MemoryVariableService) GetVariable(tx *sql.Tx, processInstanceId int, scope string, name string) (*ProcessVariable, error) {
+	variables, err := service.queryVariables(tx, func(row memoryVariableRow) bool {
+		return row.ProcessInstanceId == processInstanceId && row.Scope == scope && row.Name == name
+	})
+	if err != nil || len(variables) == 0 {
+		return nil, err
+	}
+	return &variables[0], nil
+}
+
+func (service *MemoryVariableService) GetVariables(tx *sql.Tx, processInstanceId int, scope string) (map[string]any, error) {
+	variables, err := service.queryVariables(tx, func(row memoryVariableRow) bool {
+		return row.ProcessInstanceId == processInstanceId && row.Scope == scope
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(variables))
+	for _, variable := range variables {
+		result[variable.Name] = variable.Value
+	}
+	return result, nil
+}
+
+func (service *MemoryVariableService) GetProcessInstanceVariables(processInstanceId int) ([]ProcessVariable, error) {
+	return service.queryVariables(service.DB, func(row memoryVariableRow) bool {
+		return row.ProcessInstanceId == processInstanceId
+	})
+}
+
+func (service *MemoryVariableService) GetAllVariables(tx *sql.Tx, processInstanceId int) ([]ProcessVariable, error) {
+	return service.queryVariables(tx, func(row memoryVariableRow) bool {
+		return row.ProcessInstanceId == processInstanceId
+	})
+}
+
+// 按作用域和名称排序 和 SQL 实现一致
+func (service *MemoryVariableService) queryVariables(execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, match func(row memoryVariableRow) bool) ([]ProcessVariable, error) {
+	var rows []memoryVariableRow
+	err := memoryQuery(execer, func(data *memoryData) error {
+		for _, row := range data.variables {
+			if match(row) {
+				rows = append(rows, row)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query process variables: %v", err)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Scope != rows[j].Scope {
+			return rows[i].Scope < rows[j].Scope
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	variables := make([]ProcessVariable, 0, len(rows))
+	for _, row := range rows {
+		variable, err := row.toProcessVariable()
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, variable)
+	}
+	return variables, nil
+}
+
+func (service *MemoryVariableService) DeleteVariablesByScope(tx *sql.Tx, processInstanceId int, scope string, changedBy string) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		var ids []int
+		for id, row := range data.variables {
+			if row.ProcessInstanceId == processInstanceId && row.Scope == scope {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return data.variables[ids[i]].Name < data.variables[ids[j]].Name })
+		changedAt := time.Now()
+		for _, id := range ids {
+			row := data.variables[id]
+			row.Value = sql.NullString{}
+			row.ChangedBy = changedBy
+			row.ChangedAt = changedAt
+			data.insertHistoricVariable(row, VARIABLE_ACTION_DELETE)
+			delete(data.variables, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete process variables of scope %s: %v", scope, err)
+	}
+	return nil
+}
+
+func (service *MemoryVariableService) DeleteVariablesByProcessInstance(tx *sql.Tx, processInstanceId int) error {
+	err := memoryExec(tx, func(data *memoryData) error {
+		for id, row := range data.variables {
+			if row.ProcessInstanceId == processInstanceId {
+				delete(data.variables, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete process variables: %v", err)
+	}
+	return nil
+}
+
+func (service *MemoryVariableService) GetVariableHistory(processInstanceId int) ([]HistoricVariable, error) {
+	var rows []memoryVariableRow
+	err := memoryQuery(service.DB, func(data *memoryData) error {
+		for _, row := range data.historicVariables {
+			if row.ProcessInstanceId == processInstanceId {
+				rows = append(rows, row)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query historic variables: %v", err)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Id < rows[j].Id })
+	history := make([]HistoricVariable, 0, len(rows))
+	for _, row := range rows {
+		variable := HistoricVariable{
+			Id:                row.Id,
+			ProcessInstanceId: row.ProcessInstanceId,
+			Scope:             row.Scope,
+			Name:              row.Name,
+			Type:              row.Type,
+			Action:            row.Action,
+			ChangedBy:         row.ChangedBy,
+			ChangedAt:         row.ChangedAt,
+		}
+		if row.Value.Valid {
+			if variable.Value, err = decodeVariable(row.Value.String); err != nil {
+				return nil, err
+			}
+		}
+		history = append(history, variable)
+	}
+	return history, nil
+}
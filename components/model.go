@@ -1,31 +1,45 @@
 package components
 
-import "sync"
+import (
+	"fmt"
+)
 
 // Model 代表整个流程模型，包含所有元素和序列流
 type Model struct {
-	ProcessDefinitionName string // 模型的名称，用于标识不同的模型
-	Version               int
-	StartEvents           map[string]StartEvent       // 存储所有的开始事件，使用唯一Id作为键
-	Tasks                 map[string]Task             // 存储所有的任务，使用唯一Id作为键
-	ParallelGateways      map[string]ParallelGateway  // 存储所有的并行网关，使用唯一Id作为键
-	ExclusiveGateways     map[string]ExclusiveGateway // 存储所有的互斥网关，使用唯一Id作为键
-	EndEvents             map[string]EndEvent         // 存储所有的结束事件，使用唯一Id作为键
-	SequenceFlows         map[string]SequenceFlow     // 存储所有的序列流，使用唯一Id作为键
-	AllData               map[string]Executor         // 冗余数据
+	ProcessDefinitionName   string // 模型的名称，用于标识不同的模型
+	Version                 int
+	StartEvents             map[string]StartEvent             // 存储所有的开始事件，使用唯一Id作为键
+	Tasks                   map[string]Task                   // 存储所有的任务，使用唯一Id作为键
+	ParallelGateways        map[string]ParallelGateway        // 存储所有的并行网关，使用唯一Id作为键
+	ExclusiveGateways       map[string]ExclusiveGateway       // 存储所有的互斥网关，使用唯一Id作为键
+	EndEvents               map[string]EndEvent               // 存储所有的结束事件，使用唯一Id作为键
+	IntermediateTimerEvents map[string]IntermediateTimerEvent // 存储所有的中间定时事件，使用唯一Id作为键
+	ServiceTasks            map[string]ServiceTask            // 存储所有的服务节点，使用唯一Id作为键
+	InclusiveGateways       map[string]InclusiveGateway       // 存储所有的包容网关，使用唯一Id作为键
+	SequenceFlows           map[string]SequenceFlow           // 存储所有的序列流，使用唯一Id作为键
+	AllData                 map[string]Executor               // 冗余数据
+	BranchScopes            map[string]string                 // 节点结构id -> 所在分支的变量作用域 不在任何分支里的节点没有记录
+	BranchParents           map[string]string                 // 分支的变量作用域 -> 外层分支的作用域，最外层的分支是流程实例作用域
+	JoinForks               map[string]string                 // 汇聚网关结构id -> 对应的分支网关结构id
 }
 
 // NewModel 创建并初始化一个新的模型，并为其设置名称
 func NewModel(processDefinitionName string) *Model {
 	return &Model{
-		ProcessDefinitionName: processDefinitionName,
-		StartEvents:           make(map[string]StartEvent),
-		Tasks:                 make(map[string]Task),
-		ParallelGateways:      make(map[string]ParallelGateway),
-		ExclusiveGateways:     make(map[string]ExclusiveGateway),
-		EndEvents:             make(map[string]EndEvent),
-		SequenceFlows:         make(map[string]SequenceFlow),
-		AllData:               make(map[string]Executor),
+		ProcessDefinitionName:   processDefinitionName,
+		StartEvents:             make(map[string]StartEvent),
+		Tasks:                   make(map[string]Task),
+		ParallelGateways:        make(map[string]ParallelGateway),
+		ExclusiveGateways:       make(map[string]ExclusiveGateway),
+		EndEvents:               make(map[string]EndEvent),
+		IntermediateTimerEvents: make(map[string]IntermediateTimerEvent),
+		ServiceTasks:            make(map[string]ServiceTask),
+		InclusiveGateways:       make(map[string]InclusiveGateway),
+		SequenceFlows:           make(map[string]SequenceFlow),
+		AllData:                 make(map[string]Executor),
+		BranchScopes:            make(map[string]string),
+		BranchParents:           make(map[string]string),
+		JoinForks:               make(map[string]string),
 	}
 }
 
@@ -53,12 +67,30 @@ func (model *Model) AddExclusiveGateway(ExecutionId string, gateway ExclusiveGat
 	model.AllData[ExecutionId] = gateway
 }
 
+// AddInclusiveGateway 向模型中添加包容网关
+func (model *Model) AddInclusiveGateway(ExecutionId string, gateway InclusiveGateway) {
+	model.InclusiveGateways[ExecutionId] = gateway
+	model.AllData[ExecutionId] = gateway
+}
+
 // AddEndEvent 向模型中添加结束事件
 func (model *Model) AddEndEvent(ExecutionId string, endEvent EndEvent) {
 	model.EndEvents[ExecutionId] = endEvent
 	model.AllData[ExecutionId] = endEvent
 }
 
+// AddIntermediateTimerEvent 向模型中添加中间定时事件
+func (model *Model) AddIntermediateTimerEvent(ExecutionId string, timerEvent IntermediateTimerEvent) {
+	model.IntermediateTimerEvents[ExecutionId] = timerEvent
+	model.AllData[ExecutionId] = timerEvent
+}
+
+// AddServiceTask 向模型中添加服务节点
+func (model *Model) AddServiceTask(ExecutionId string, serviceTask ServiceTask) {
+	model.ServiceTasks[ExecutionId] = serviceTask
+	model.AllData[ExecutionId] = serviceTask
+}
+
 // AddSequenceFlow 向模型中添加序列流
 func (model *Model) AddSequenceFlow(ExecutionId string, flow SequenceFlow) {
 	model.SequenceFlows[ExecutionId] = flow
@@ -81,29 +113,129 @@ type Process struct {
 	// 存储所有的互斥网关
 	ExclusiveGateways []ExclusiveGateway `xml:"ExclusiveGateway"`
 
+	// 存储所有的包容网关
+	InclusiveGateways []InclusiveGateway `xml:"InclusiveGateway"`
+
 	// 存储所有的结束事件
 	EndEvents []EndEvent `xml:"EndEvent"`
 
+	// 存储所有的中间定时事件
+	IntermediateTimerEvents []IntermediateTimerEvent `xml:"IntermediateTimerEvent"`
+
+	// 存储所有的服务节点
+	ServiceTasks []ServiceTask `xml:"ServiceTask"`
+
 	// 存储所有的序列流
 	SequenceFlows []SequenceFlow `xml:"SequenceFlow"`
 }
 
-var (
-	modelInstance *map[string]*Model
-	modelOnce     sync.Once
-)
+// 模型缓存的键 同一个流程的不同版本分开缓存
+func modelCacheKey(processDefinitionName string, version int) string {
+	return fmt.Sprintf("%s@%d", processDefinitionName, version)
+}
+
+// 取流程最新版本的模型，启动新的流程实例时使用
+func getLatestModel(processDefinitionName string) (*Model, error) {
+	repositoryService := GetServiceFactory().GetRepositoryService()
+	pd, err := repositoryService.GetLatestProcessDefinitionByName(processDefinitionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve process definition: %v", err)
+	}
+	if pd == nil {
+		return nil, fmt.Errorf("no process definition found with name: %s", processDefinitionName)
+	}
+	return getModelByDefinition(pd)
+}
+
+// 按流程名称和版本取模型，继续执行已有的流程实例时用 process_instance.version，保证实例一直按启动时的版本流转
+func getModelByVersion(processDefinitionName string, version int) (*Model, error) {
+	repositoryService := GetServiceFactory().GetRepositoryService()
+	pd, err := repositoryService.GetProcessDefinitionByNameAndVersion(processDefinitionName, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve process definition: %v", err)
+	}
+	if pd == nil {
+		return nil, fmt.Errorf("no process definition found with name: %s version: %d", processDefinitionName, version)
+	}
+	return getModelByDefinition(pd)
+}
 
-func initModelMap() *map[string]*Model {
-	modelOnce.Do(func() {
-		temp := make(map[string]*Model)
-		modelInstance = &temp
+// 缓存里有这份定义解析好的模型就直接用，没有就解析后放进缓存，同一份定义并发请求时只解析一次
+func getModelByDefinition(pd *ProcessDefinition) (*Model, error) {
+	return modelCacheInstance.get(modelCacheKey(pd.ProcessDefinitionName, pd.Version), pd, func() (*Model, error) {
+		//解析xml
+		model, parseErr := ParseXMLByte(pd.XMLContent)
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse process definition %s: %v", pd.ProcessDefinitionName, parseErr)
+		}
+		//更新版本 流程定义不用更新
+		model.Version = pd.Version
+		return model, nil
 	})
-	return modelInstance
 }
 
-func GetModelMap() *map[string]*Model {
-	if modelInstance == nil {
-		panic("modelInstance is not initialized. Call initModelMap first.")
+// InvalidateModel 移除缓存里某个版本的模型 仓库服务修改流程定义时调用，尽早释放旧的模型
+// 不调用也不会用到旧的模型，取模型时会核对流程定义的内容
+func InvalidateModel(processDefinitionName string, version int) {
+	modelCacheInstance.remove(modelCacheKey(processDefinitionName, version))
+}
+
+// 按模型结构划分并行分支 并行网关和包容网关的每一条出线是一个分支，出线的结构id就是分支的变量作用域
+// 从出线到对应的汇聚网关之间的节点属于这个分支，嵌套的分支记录外层分支，同时记录汇聚网关对应的分支网关，模型加载时计算一次
+func (model *Model) analyzeBranches() {
+	validator := &modelValidator{model: model, joins: make(map[string]string)}
+	visited := make(map[string]bool)
+	var walk func(flowId string, scope string, stopAt string)
+	walk = func(flowId string, scope string, stopAt string) {
+		flow, ok := model.SequenceFlows[flowId]
+		if !ok || flow.TargetRef == stopAt || visited[flow.TargetRef] {
+			return
+		}
+		target := flow.TargetRef
+		node, ok := model.AllData[target]
+		if !ok {
+			return
+		}
+		visited[target] = true
+		if scope != VARIABLE_SCOPE_PROCESS {
+			model.BranchScopes[target] = scope
+		}
+		_, outgoing := nodeFlows(node)
+		if isPairedGateway(node) && len(outgoing) > 1 {
+			if join := validator.matchJoin(target); join != "" {
+				model.JoinForks[join] = target
+				for _, branch := range outgoing {
+					model.BranchParents[branch] = scope
+					walk(branch, branch, join)
+				}
+				// 汇聚之后回到外层的分支
+				if scope != VARIABLE_SCOPE_PROCESS {
+					model.BranchScopes[join] = scope
+				}
+				visited[join] = true
+				_, outgoing = nodeFlows(model.AllData[join])
+			}
+		}
+		for _, next := range outgoing {
+			walk(next, scope, stopAt)
+		}
+	}
+	for _, startEvent := range model.StartEvents {
+		_, outgoing := nodeFlows(startEvent)
+		for _, flowId := range outgoing {
+			walk(flowId, VARIABLE_SCOPE_PROCESS, "")
+		}
+	}
+}
+
+// 节点所在的分支作用域 从里到外，不包括流程实例作用域
+func (model *Model) branchChain(executionId string) []string {
+	if model == nil {
+		return nil
+	}
+	var chain []string
+	for scope := model.BranchScopes[executionId]; scope != VARIABLE_SCOPE_PROCESS; scope = model.BranchParents[scope] {
+		chain = append(chain, scope)
 	}
-	return modelInstance
+	return chain
 }
@@ -0,0 +1,176 @@
+package components
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+)
+
+// 模型缓存默认最多保存的流程版本数
+const DEFAULT_MODEL_CACHE_CAPACITY = 256
+
+// ModelCacheStats 模型缓存的统计数据
+type ModelCacheStats struct {
+	Hits       uint64 // 直接从缓存取到模型的次数
+	Misses     uint64 // 缓存里没有或者内容对不上、需要解析（或者等待别人解析）的次数
+	Loads      uint64 // 真正解析流程定义的次数
+	LoadErrors uint64 // 解析失败的次数
+	Evictions  uint64 // 超出容量被淘汰的次数
+	Size       int    // 当前缓存的模型数
+	Capacity   int    // 容量
+}
+
+// modelCache 并发安全的 LRU 模型缓存
+// 每次取模型时调用方先读出流程定义，缓存按定义的id和xml内容核对，内容变了就重新解析
+// 这样修改流程定义的事务不管怎么结束，缓存里都不会留下旧的或者回滚掉的模型
+// 同一个键同一份内容同时只有一个协程去解析，其他协程等待它的结果
+type modelCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	// 最近使用的在前面
+	order   *list.List
+	loading map[string]*modelLoadCall
+	stats   ModelCacheStats
+}
+
+type modelCacheEntry struct {
+	key    string
+	source modelSource
+	model  *Model
+}
+
+// 模型是从哪一份流程定义解析出来的
+type modelSource struct {
+	definitionId int
+	xmlContent   []byte
+}
+
+func (source modelSource) matches(pd *ProcessDefinition) bool {
+	return source.definitionId == pd.Id && bytes.Equal(source.xmlContent, pd.XMLContent)
+}
+
+// 一次正在进行的解析
+type modelLoadCall struct {
+	source modelSource
+	done   chan struct{}
+	model  *Model
+	err    error
+}
+
+var modelCacheInstance = newModelCache(DEFAULT_MODEL_CACHE_CAPACITY)
+
+func newModelCache(capacity int) *modelCache {
+	if capacity <= 0 {
+		capacity = DEFAULT_MODEL_CACHE_CAPACITY
+	}
+	return &modelCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		loading:  make(map[string]*modelLoadCall),
+	}
+}
+
+// get 取流程定义 pd 对应的模型，缓存里没有或者内容对不上时调用 load 解析，解析失败的结果不缓存
+func (cache *modelCache) get(key string, pd *ProcessDefinition, load func() (*Model, error)) (*Model, error) {
+	cache.mu.Lock()
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*modelCacheEntry)
+		if entry.source.matches(pd) {
+			cache.order.MoveToFront(element)
+			cache.stats.Hits++
+			cache.mu.Unlock()
+			return entry.model, nil
+		}
+		// 流程定义在缓存之后被修改、删除或者回滚过
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+	cache.stats.Misses++
+	if call, ok := cache.loading[key]; ok && call.source.matches(pd) {
+		cache.mu.Unlock()
+		<-call.done
+		return call.model, call.err
+	}
+	call := &modelLoadCall{source: modelSource{definitionId: pd.Id, xmlContent: pd.XMLContent}, done: make(chan struct{})}
+	cache.loading[key] = call
+	cache.stats.Loads++
+	cache.mu.Unlock()
+
+	call.model, call.err = load()
+
+	cache.mu.Lock()
+	// 解析期间被 remove 过或者有更新的内容在解析的话，结果只返回给这一批调用方，不放进缓存
+	if cache.loading[key] == call {
+		delete(cache.loading, key)
+		if call.err == nil {
+			cache.add(key, call.source, call.model)
+		}
+	}
+	if call.err != nil {
+		cache.stats.LoadErrors++
+	}
+	cache.mu.Unlock()
+	close(call.done)
+	return call.model, call.err
+}
+
+// add 放进缓存 调用方持有锁
+func (cache *modelCache) add(key string, source modelSource, model *Model) {
+	if element, ok := cache.entries[key]; ok {
+		cache.order.Remove(element)
+	}
+	cache.entries[key] = cache.order.PushFront(&modelCacheEntry{key: key, source: source, model: model})
+	cache.evict()
+}
+
+// evict 超出容量时从最久没用的开始淘汰 调用方持有锁
+func (cache *modelCache) evict() {
+	for cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*modelCacheEntry).key)
+		cache.stats.Evictions++
+	}
+}
+
+// remove 移除缓存的模型，正在解析的结果也作废
+func (cache *modelCache) remove(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if element, ok := cache.entries[key]; ok {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+	delete(cache.loading, key)
+}
+
+func (cache *modelCache) setCapacity(capacity int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if capacity <= 0 {
+		capacity = DEFAULT_MODEL_CACHE_CAPACITY
+	}
+	cache.capacity = capacity
+	cache.evict()
+}
+
+func (cache *modelCache) snapshot() ModelCacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	stats := cache.stats
+	stats.Size = cache.order.Len()
+	stats.Capacity = cache.capacity
+	return stats
+}
+
+// SetModelCacheCapacity 设置模型缓存最多保存的流程版本数，小于等于 0 时使用默认值
+func SetModelCacheCapacity(capacity int) {
+	modelCacheInstance.setCapacity(capacity)
+}
+
+// GetModelCacheStats 获取模型缓存的命中统计，给监控用
+func GetModelCacheStats() ModelCacheStats {
+	return modelCacheInstance.snapshot()
+}
@@ -0,0 +1,247 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const cacheTestXML = `<Process name="cacheTest">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="t" name="NAME" assigneeType="ByAssigneeName" assigneeKey="cache-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>
+  <SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>
+</Process>`
+
+func TestModelCacheIgnoresUncommittedDefinition(t *testing.T) {
+	const name = "cacheTest"
+	deployXML(t, name, []byte(strings.Replace(cacheTestXML, "NAME", "Original", 1)))
+	model, err := getModelByVersion(name, 1)
+	if err != nil || model.Tasks["t"].Name != "Original" {
+		t.Fatalf("model = %v %v", model, err)
+	}
+
+	repository := GetServiceFactory().GetRepositoryService()
+	pd, err := repository.GetProcessDefinitionByNameAndVersion(name, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := repository.GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd.XMLContent = []byte(strings.Replace(cacheTestXML, "NAME", "Changed", 1))
+	if err := repository.UpdateProcessDefinition(tx, pd); err != nil {
+		t.Fatal(err)
+	}
+	// 内存库不在事务里的读能看到未提交的数据 这时加载的模型不能进缓存
+	if _, err := getModelByVersion(name, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := FinishTransaction(tx, errors.New("abort")); err == nil {
+		t.Fatal("expected the rollback error")
+	}
+
+	model, err = getModelByVersion(name, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Tasks["t"].Name != "Original" {
+		t.Fatalf("cached model after rollback = %s", model.Tasks["t"].Name)
+	}
+}
+
+// 调用方直接用 tx.Commit 结束事务 保存的版本照样进入缓存，之后的修改也不会读到旧模型
+func TestModelCacheWithRawCommit(t *testing.T) {
+	const name = "cacheRawCommit"
+	xmlContent := strings.Replace(strings.Replace(cacheTestXML, "cacheTest", name, 1), "NAME", "Original", 1)
+	repository := GetServiceFactory().GetRepositoryService()
+	tx, err := repository.GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := repository.SaveProcessDefinition(tx, &ProcessDefinition{ProcessDefinitionName: name, XMLContent: []byte(xmlContent), CreatedBy: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	pd, err := repository.GetProcessDefinitionById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := GetModelCacheStats()
+	for range 2 {
+		if _, err := getModelByVersion(name, pd.Version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := GetModelCacheStats()
+	if after.Loads-before.Loads != 1 || after.Hits-before.Hits != 1 {
+		t.Fatalf("loads %d, hits %d", after.Loads-before.Loads, after.Hits-before.Hits)
+	}
+
+	tx, err = repository.GetTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pd.XMLContent = []byte(strings.Replace(xmlContent, "Original", "Changed", 1))
+	if err := repository.UpdateProcessDefinition(tx, pd); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	model, err := getModelByVersion(name, pd.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Tasks["t"].Name != "Changed" {
+		t.Fatalf("model after update = %s", model.Tasks["t"].Name)
+	}
+}
+
+// 缓存测试用的流程定义 每个键一份
+func cacheTestDefinition(id int) *ProcessDefinition {
+	return &ProcessDefinition{Id: id, ProcessDefinitionName: fmt.Sprintf("lru%d", id), Version: 1, XMLContent: []byte(fmt.Sprintf("<Process name=\"lru%d\"/>", id))}
+}
+
+// 从缓存取模型 返回有没有调用解析
+func getCached(t *testing.T, cache *modelCache, pd *ProcessDefinition) bool {
+	t.Helper()
+	loaded := false
+	_, err := cache.get(modelCacheKey(pd.ProcessDefinitionName, pd.Version), pd, func() (*Model, error) {
+		loaded = true
+		return &Model{ProcessDefinitionName: pd.ProcessDefinitionName}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestModelCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newModelCache(2)
+	a, b, c := cacheTestDefinition(1), cacheTestDefinition(2), cacheTestDefinition(3)
+	getCached(t, cache, a)
+	getCached(t, cache, b)
+	// 访问 a 之后 b 是最久没用的，放进 c 时淘汰 b
+	if getCached(t, cache, a) {
+		t.Fatal("a was loaded again")
+	}
+	getCached(t, cache, c)
+	if getCached(t, cache, a) || getCached(t, cache, c) {
+		t.Fatal("recently used models were evicted")
+	}
+	if !getCached(t, cache, b) {
+		t.Fatal("b was not evicted")
+	}
+
+	stats := cache.snapshot()
+	want := ModelCacheStats{Hits: 3, Misses: 4, Loads: 4, Evictions: 2, Size: 2, Capacity: 2}
+	if stats != want {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestModelCacheCapacityChange(t *testing.T) {
+	cache := newModelCache(3)
+	for id := 1; id <= 3; id++ {
+		getCached(t, cache, cacheTestDefinition(id))
+	}
+	cache.setCapacity(1)
+	if stats := cache.snapshot(); stats.Size != 1 || stats.Evictions != 2 || stats.Capacity != 1 {
+		t.Fatalf("stats after shrinking = %+v", stats)
+	}
+	// 只留下最近放进去的
+	if getCached(t, cache, cacheTestDefinition(3)) {
+		t.Fatal("most recent model was evicted")
+	}
+	cache.setCapacity(0)
+	if stats := cache.snapshot(); stats.Capacity != DEFAULT_MODEL_CACHE_CAPACITY {
+		t.Fatalf("capacity = %d", stats.Capacity)
+	}
+
+	SetModelCacheCapacity(5)
+	t.Cleanup(func() { SetModelCacheCapacity(DEFAULT_MODEL_CACHE_CAPACITY) })
+	if stats := GetModelCacheStats(); stats.Capacity != 5 {
+		t.Fatalf("global capacity = %d", stats.Capacity)
+	}
+}
+
+// 同一份定义同时未命中 只解析一次，其他协程等待同一个结果
+func TestModelCacheLoadsOnceForConcurrentMisses(t *testing.T) {
+	const callers = 8
+	cache := newModelCache(2)
+	pd := cacheTestDefinition(1)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() (*Model, error) {
+		loads.Add(1)
+		<-release
+		return &Model{ProcessDefinitionName: pd.ProcessDefinitionName}, nil
+	}
+
+	models := make([]*Model, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			model, err := cache.get("lru1@1", pd, load)
+			if err != nil {
+				t.Error(err)
+			}
+			models[i] = model
+		}()
+	}
+	// 等所有协程都未命中之后再让解析结束
+	for deadline := time.Now().Add(5 * time.Second); cache.snapshot().Misses < callers; {
+		if time.Now().After(deadline) {
+			t.Fatalf("misses = %d", cache.snapshot().Misses)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Fatalf("loads = %d", loads.Load())
+	}
+	for _, model := range models {
+		if model != models[0] {
+			t.Fatal("callers got different models")
+		}
+	}
+	if stats := cache.snapshot(); stats.Loads != 1 || stats.Misses != callers {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+// 流程定义的内容变了 同一个键重新解析
+func TestModelCacheReloadsChangedDefinition(t *testing.T) {
+	cache := newModelCache(2)
+	pd := cacheTestDefinition(1)
+	getCached(t, cache, pd)
+	changed := *pd
+	changed.XMLContent = []byte("<Process name=\"lru1\"><Task/></Process>")
+	if !getCached(t, cache, &changed) {
+		t.Fatal("changed definition was not loaded")
+	}
+	// 同一个版本被删除后重新保存 id 不一样
+	recreated := changed
+	recreated.Id = 9
+	if !getCached(t, cache, &recreated) {
+		t.Fatal("recreated definition was not loaded")
+	}
+	if stats := cache.snapshot(); stats.Size != 1 || stats.Loads != 3 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,542 @@
+package components
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 模型校验问题的类别
+const (
+	PROBLEM_START_EVENT        = "startEvent"        // 开始事件不是正好一个
+	PROBLEM_DUPLICATE_ID       = "duplicateId"       // 结构id为空或者重复
+	PROBLEM_DANGLING_REFERENCE = "danglingReference" // Incoming/Outgoing 引用了不存在的序列流，或者和序列流的两端对不上
+	PROBLEM_MISSING_NODE       = "missingNode"       // 序列流的 sourceRef/targetRef 不存在
+	PROBLEM_MISSING_FLOW       = "missingFlow"       // 节点缺少必须的进线或者出线
+	PROBLEM_UNREACHABLE        = "unreachable"       // 从开始事件走不到的节点
+	PROBLEM_UNBALANCED_GATEWAY = "unbalancedGateway" // 并行网关或包容网关的分支和汇聚对不上
+	PROBLEM_LISTENER           = "listener"          // 引用了未注册的监听
+	PROBLEM_TIMER              = "timer"             // 定时器的时长或者动作配置不正确
+	PROBLEM_HANDLER            = "handler"           // 服务节点引用了未注册的处理函数
+	PROBLEM_DEFAULT_FLOW       = "defaultFlow"       // 互斥网关或者包容网关的默认出线不是它自己的出线
+	PROBLEM_EXPRESSION         = "expression"        // 条件表达式解析失败或者调用了未注册的函数
+	PROBLEM_MULTI_INSTANCE     = "multiInstance"     // 多实例审批节点的负责人集合或者完成条件配置不正确
+	PROBLEM_ASSIGNEE           = "assignee"          // 负责人指定方式没有注册解析器或者 assigneeKey 配置不正确
+	PROBLEM_FORM               = "form"              // 表单定义不是合法的 JSON，或者字段id、选项配置不正确
+	PROBLEM_ASYNC              = "async"             // 服务节点以外的元素配置了 async 属性
+)
+
+// ModelProblem 模型校验发现的一个问题
+type ModelProblem struct {
+	ExecutionId string // 出问题的元素结构id 整个流程层面的问题为空
+	Code        string // 问题类别 PROBLEM_* 之一
+	Message     string
+}
+
+func (problem ModelProblem) String() string {
+	if problem.ExecutionId == "" {
+		return fmt.Sprintf("[%s] %s", problem.Code, problem.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", problem.Code, problem.ExecutionId, problem.Message)
+}
+
+// ModelValidationError 流程定义没有通过校验时返回的错误，用 errors.As 取出全部问题
+type ModelValidationError struct {
+	ProcessDefinitionName string
+	Problems              []ModelProblem
+}
+
+func (e *ModelValidationError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.String())
+	}
+	return fmt.Sprintf("invalid process definition %s: %s", e.ProcessDefinitionName, strings.Join(messages, "; "))
+}
+
+func (e *ModelValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// ValidateModel 校验流程模型的结构，没有问题返回空
+// 只校验结构 不校验监听是否注册，部署时两者都会检查
+func ValidateModel(model *Model) []ModelProblem {
+	validator := &modelValidator{model: model, joins: make(map[string]string)}
+	validator.checkIds()
+	validator.checkStartEvents()
+	validator.checkNodeReferences()
+	validator.checkSequenceFlows()
+	validator.checkReachable()
+	validator.checkPairedGateways()
+	validator.checkTimers()
+	validator.checkDefaultFlows()
+	validator.checkMultiInstances()
+	sort.SliceStable(validator.problems, func(i, j int) bool {
+		if validator.problems[i].Code != validator.problems[j].Code {
+			return validator.problems[i].Code < validator.problems[j].Code
+		}
+		return validator.problems[i].ExecutionId < validator.problems[j].ExecutionId
+	})
+	return validator.problems
+}
+
+// 监听检查的结果也转成问题列表
+func listenerProblems(model *Model) []ModelProblem {
+	var problems []ModelProblem
+	for executionId, node := range model.AllData {
+		for _, name := range parseListenerNames(nodeListener(node)) {
+			if _, ok := GetListener(name); !ok {
+				problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_LISTENER, Message: fmt.Sprintf("listener %s is not registered", name)})
+			}
+		}
+	}
+	// 边界定时器不在 AllData 里 单独检查
+	for _, task := range model.Tasks {
+		for _, timer := range task.BoundaryTimers {
+			for _, name := range parseListenerNames(timer.Listener) {
+				if _, ok := GetListener(name); !ok {
+					problems = append(problems, ModelProblem{ExecutionId: timer.ExecutionId, Code: PROBLEM_LISTENER, Message: fmt.Sprintf("listener %s is not registered", name)})
+				}
+			}
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].ExecutionId < problems[j].ExecutionId })
+	return problems
+}
+
+// 服务节点的处理函数也要在部署前注册
+func handlerProblems(model *Model) []ModelProblem {
+	var problems []ModelProblem
+	for executionId, serviceTask := range model.ServiceTasks {
+		if strings.TrimSpace(serviceTask.Handler) == "" {
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_HANDLER, Message: "service task has no handler"})
+		} else if _, ok := GetServiceHandler(serviceTask.Handler); !ok {
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_HANDLER, Message: fmt.Sprintf("service handler %s is not registered", serviceTask.Handler)})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].ExecutionId < problems[j].ExecutionId })
+	return problems
+}
+
+// 只有服务节点支持 async 其他元素解析时会丢掉这个属性，同步执行和配置的不一致，部署时直接拒绝
+// 模型里已经没有这个属性了 从 xml 内容里检查
+func asyncProblems(xmlContent []byte) []ModelProblem {
+	var problems []ModelProblem
+	decoder := xml.NewDecoder(bytes.NewReader(xmlContent))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// 读完或者 xml 本身有问题 后者在解析时已经报错
+			break
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local == "ServiceTask" {
+			continue
+		}
+		executionId, async := "", false
+		for _, attr := range element.Attr {
+			switch attr.Name.Local {
+			case "executionId":
+				executionId = attr.Value
+			case "async":
+				async = true
+			}
+		}
+		if async {
+			problems = append(problems, ModelProblem{ExecutionId: executionId, Code: PROBLEM_ASYNC, Message: fmt.Sprintf("async is only supported on ServiceTask, not on %s", element.Name.Local)})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].ExecutionId < problems[j].ExecutionId })
+	return problems
+}
+
+type modelValidator struct {
+	model    *Model
+	problems []ModelProblem
+	// 并行网关分支 -> 对应的汇聚网关，走不通时为空
+	joins map[string]string
+}
+
+func (validator *modelValidator) report(executionId string, code string, format string, args ...any) {
+	validator.problems = append(validator.problems, ModelProblem{ExecutionId: executionId, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// 节点的进线和出线 序列流返回空
+// 审批节点边界定时器的出线也算作审批节点的出线
+func nodeFlows(node Executor) (incoming []string, outgoing []string) {
+	switch element := node.(type) {
+	case StartEvent:
+		if element.Outgoing != "" {
+			outgoing = []string{element.Outgoing}
+		}
+	case Task:
+		incoming, outgoing = element.Incoming, element.Outgoing
+		for _, timer := range element.BoundaryTimers {
+			outgoing = append(slices.Clip(outgoing), timer.Outgoing...)
+		}
+	case IntermediateTimerEvent:
+		incoming, outgoing = element.Incoming, element.Outgoing
+	case ServiceTask:
+		incoming, outgoing = element.Incoming, slices.Concat(element.Outgoing, element.ErrorOutgoing)
+	case ParallelGateway:
+		incoming, outgoing = element.Incoming, element.Outgoing
+	case ExclusiveGateway:
+		incoming, outgoing = element.Incoming, element.Outgoing
+	case InclusiveGateway:
+		incoming, outgoing = element.Incoming, element.Outgoing
+	case EndEvent:
+		if element.Incoming != "" {
+			incoming = []string{element.Incoming}
+		}
+	}
+	return incoming, outgoing
+}
+
+func isSequenceFlow(node Executor) bool {
+	_, ok := node.(SequenceFlow)
+	return ok
+}
+
+// 结构id不能为空，不同类型的元素也不能重名 重名的元素解析时会互相覆盖
+func (validator *modelValidator) checkIds() {
+	model := validator.model
+	counts := make(map[string]int)
+	for id := range model.StartEvents {
+		counts[id]++
+	}
+	for id := range model.Tasks {
+		counts[id]++
+	}
+	for id := range model.ParallelGateways {
+		counts[id]++
+	}
+	for id := range model.ExclusiveGateways {
+		counts[id]++
+	}
+	for id := range model.InclusiveGateways {
+		counts[id]++
+	}
+	for id := range model.EndEvents {
+		counts[id]++
+	}
+	for id := range model.IntermediateTimerEvents {
+		counts[id]++
+	}
+	for id := range model.ServiceTasks {
+		counts[id]++
+	}
+	for _, task := range model.Tasks {
+		for _, timer := range task.BoundaryTimers {
+			counts[timer.ExecutionId]++
+		}
+	}
+	for id := range model.SequenceFlows {
+		counts[id]++
+	}
+	for id, count := range counts {
+		if strings.TrimSpace(id) == "" {
+			validator.report(id, PROBLEM_DUPLICATE_ID, "element without executionId")
+		} else if count > 1 {
+			validator.report(id, PROBLEM_DUPLICATE_ID, "executionId is used by %d elements", count)
+		}
+	}
+}
+
+func (validator *modelValidator) checkStartEvents() {
+	if count := len(validator.model.StartEvents); count != 1 {
+		validator.report("", PROBLEM_START_EVENT, "process must have exactly one start event, found %d", count)
+	}
+}
+
+// 节点上的 Incoming/Outgoing 必须是存在的序列流，并且序列流的两端就是这个节点
+func (validator *modelValidator) checkNodeReferences() {
+	for executionId, node := range validator.model.AllData {
+		if isSequenceFlow(node) {
+			continue
+		}
+		incoming, outgoing := nodeFlows(node)
+		for _, flowId := range incoming {
+			flow, ok := validator.model.SequenceFlows[flowId]
+			if !ok {
+				validator.report(executionId, PROBLEM_DANGLING_REFERENCE, "incoming sequence flow %s is not defined", flowId)
+			} else if flow.TargetRef != executionId {
+				validator.report(executionId, PROBLEM_DANGLING_REFERENCE, "incoming sequence flow %s targets %s", flowId, flow.TargetRef)
+			}
+		}
+		for _, flowId := range outgoing {
+			flow, ok := validator.model.SequenceFlows[flowId]
+			if !ok {
+				validator.report(executionId, PROBLEM_DANGLING_REFERENCE, "outgoing sequence flow %s is not defined", flowId)
+			} else if flow.SourceRef != executionId {
+				validator.report(executionId, PROBLEM_DANGLING_REFERENCE, "outgoing sequence flow %s starts from %s", flowId, flow.SourceRef)
+			}
+		}
+
+		switch node.(type) {
+		case StartEvent:
+			if len(outgoing) == 0 {
+				validator.report(executionId, PROBLEM_MISSING_FLOW, "start event has no outgoing sequence flow")
+			}
+		case EndEvent:
+			if len(incoming) == 0 {
+				validator.report(executionId, PROBLEM_MISSING_FLOW, "end event has no incoming sequence flow")
+			}
+		default:
+			if len(incoming) == 0 {
+				validator.report(executionId, PROBLEM_MISSING_FLOW, "node has no incoming sequence flow")
+			}
+			if len(outgoing) == 0 {
+				validator.report(executionId, PROBLEM_MISSING_FLOW, "node has no outgoing sequence flow")
+			}
+		}
+	}
+}
+
+// 序列流的两端必须是模型里的节点，并且两端的节点都要引用它
+func (validator *modelValidator) checkSequenceFlows() {
+	for flowId, flow := range validator.model.SequenceFlows {
+		source, ok := validator.model.AllData[flow.SourceRef]
+		if !ok || isSequenceFlow(source) {
+			validator.report(flowId, PROBLEM_MISSING_NODE, "sourceRef %s is not a node of the process", flow.SourceRef)
+		} else if _, outgoing := nodeFlows(source); !slices.Contains(outgoing, flowId) {
+			validator.report(flowId, PROBLEM_DANGLING_REFERENCE, "source node %s does not list it as outgoing", flow.SourceRef)
+		}
+		target, ok := validator.model.AllData[flow.TargetRef]
+		if !ok || isSequenceFlow(target) {
+			validator.report(flowId, PROBLEM_MISSING_NODE, "targetRef %s is not a node of the process", flow.TargetRef)
+		} else if incoming, _ := nodeFlows(target); !slices.Contains(incoming, flowId) {
+			validator.report(flowId, PROBLEM_DANGLING_REFERENCE, "target node %s does not list it as incoming", flow.TargetRef)
+		}
+	}
+}
+
+// 从开始事件沿着出线能走到的节点
+func (validator *modelValidator) checkReachable() {
+	if len(validator.model.StartEvents) == 0 {
+		return
+	}
+	visited := make(map[string]bool)
+	var queue []string
+	for executionId := range validator.model.StartEvents {
+		visited[executionId] = true
+		queue = append(queue, executionId)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		_, outgoing := nodeFlows(validator.model.AllData[current])
+		for _, flowId := range outgoing {
+			flow, ok := validator.model.SequenceFlows[flowId]
+			if !ok {
+				continue
+			}
+			if _, exists := validator.model.AllData[flow.TargetRef]; exists && !visited[flow.TargetRef] {
+				visited[flow.TargetRef] = true
+				queue = append(queue, flow.TargetRef)
+			}
+		}
+	}
+	for executionId, node := range validator.model.AllData {
+		if !isSequenceFlow(node) && !visited[executionId] {
+			validator.report(executionId, PROBLEM_UNREACHABLE, "node is not reachable from the start event")
+		}
+	}
+}
+
+// 并行网关和包容网关需要分支和汇聚配对，互斥网关的分支不需要汇聚
+func isPairedGateway(node Executor) bool {
+	switch node.(type) {
+	case ParallelGateway, InclusiveGateway:
+		return true
+	}
+	return false
+}
+
+// 并行网关和包容网关分成多路之后 每一路都必须到达同一个同类型的汇聚网关，汇聚网关的进线数量和分支数量一致
+// 否则汇聚网关会一直等待，或者某个分支先走到结束事件把整个流程结束掉
+func (validator *modelValidator) checkPairedGateways() {
+	matched := make(map[string]bool)
+	for executionId, node := range validator.model.AllData {
+		_, outgoing := nodeFlows(node)
+		if !isPairedGateway(node) || len(outgoing) < 2 {
+			continue
+		}
+		join := validator.matchJoin(executionId)
+		if join == "" {
+			continue
+		}
+		matched[join] = true
+		joinNode := validator.model.AllData[join]
+		if fmt.Sprintf("%T", joinNode) != fmt.Sprintf("%T", node) {
+			validator.report(executionId, PROBLEM_UNBALANCED_GATEWAY, "splits but joins at %s of a different type", join)
+			continue
+		}
+		if incoming, _ := nodeFlows(joinNode); len(incoming) != len(outgoing) {
+			validator.report(executionId, PROBLEM_UNBALANCED_GATEWAY, "splits into %d branches but joining gateway %s waits for %d", len(outgoing), join, len(incoming))
+		}
+	}
+	for executionId, node := range validator.model.AllData {
+		if incoming, _ := nodeFlows(node); isPairedGateway(node) && len(incoming) > 1 && !matched[executionId] {
+			validator.report(executionId, PROBLEM_UNBALANCED_GATEWAY, "joins %d branches that are not split by a matching gateway", len(incoming))
+		}
+	}
+}
+
+// 找到分支网关对应的汇聚网关 找不到时记录问题并返回空
+func (validator *modelValidator) matchJoin(splitId string) string {
+	if join, ok := validator.joins[splitId]; ok {
+		return join
+	}
+	// 先占位 防止分支嵌套成环时无限递归
+	validator.joins[splitId] = ""
+	join := ""
+	_, outgoing := nodeFlows(validator.model.AllData[splitId])
+	for _, flowId := range outgoing {
+		branchJoin, ok := validator.walkToJoin(flowId, make(map[string]bool))
+		if !ok {
+			validator.report(splitId, PROBLEM_UNBALANCED_GATEWAY, "branch %s does not reach a joining gateway", flowId)
+			return ""
+		}
+		if join != "" && branchJoin != join {
+			validator.report(splitId, PROBLEM_UNBALANCED_GATEWAY, "branches join at different gateways %s and %s", join, branchJoin)
+			return ""
+		}
+		join = branchJoin
+	}
+	validator.joins[splitId] = join
+	return join
+}
+
+// 沿着序列流往下走 直到遇到汇聚网关，所有路径都必须汇聚到同一个网关
+// 回到已经走过的节点说明是打回之类的循环，这条路径不参与判断
+func (validator *modelValidator) walkToJoin(flowId string, seen map[string]bool) (string, bool) {
+	flow, ok := validator.model.SequenceFlows[flowId]
+	if !ok {
+		return "", false
+	}
+	target := flow.TargetRef
+	if seen[target] {
+		return "", true
+	}
+	seen[target] = true
+	defer delete(seen, target)
+
+	node, ok := validator.model.AllData[target]
+	if !ok {
+		return "", false
+	}
+	if _, isEnd := node.(EndEvent); isEnd {
+		return "", false
+	}
+	incoming, outgoing := nodeFlows(node)
+	if isPairedGateway(node) {
+		if len(incoming) > 1 {
+			return target, true
+		}
+		if len(outgoing) > 1 {
+			// 嵌套的分支 从它的汇聚网关继续往下走
+			nested := validator.matchJoin(target)
+			if nested == "" {
+				return "", false
+			}
+			_, outgoing = nodeFlows(validator.model.AllData[nested])
+		}
+	}
+
+	join := ""
+	for _, next := range outgoing {
+		branchJoin, ok := validator.walkToJoin(next, seen)
+		if !ok {
+			return "", false
+		}
+		if branchJoin == "" {
+			continue
+		}
+		if join != "" && branchJoin != join {
+			return "", false
+		}
+		join = branchJoin
+	}
+	return join, join != ""
+}
+
+// 定时器的时长和审批期限必须能解析，边界定时器的动作必须是支持的几种，只有 flow 动作需要出线
+func (validator *modelValidator) checkTimers() {
+	for executionId, task := range validator.model.Tasks {
+		if strings.TrimSpace(task.DueDate) != "" {
+			if _, err := parseTimerDuration(task.DueDate); err != nil {
+				validator.report(executionId, PROBLEM_TIMER, "invalid due date: %v", err)
+			}
+		}
+		for _, timer := range task.BoundaryTimers {
+			if _, err := parseTimerDuration(timer.Duration); err != nil {
+				validator.report(timer.ExecutionId, PROBLEM_TIMER, "%v", err)
+			}
+			switch timer.Action {
+			case TIMER_ACTION_COMPLETE, TIMER_ACTION_ESCALATE:
+			case TIMER_ACTION_REASSIGN:
+				if strings.TrimSpace(timer.AssigneeKey) == "" {
+					validator.report(timer.ExecutionId, PROBLEM_TIMER, "reassign timer has no assigneeKey")
+				}
+			case TIMER_ACTION_FLOW:
+				if len(timer.Outgoing) == 0 {
+					validator.report(timer.ExecutionId, PROBLEM_TIMER, "flow timer has no outgoing sequence flow")
+				}
+			default:
+				validator.report(timer.ExecutionId, PROBLEM_TIMER, "unknown timer action %q", timer.Action)
+			}
+			if timer.Action != TIMER_ACTION_FLOW && len(timer.Outgoing) > 0 {
+				validator.report(timer.ExecutionId, PROBLEM_TIMER, "only flow timers can have outgoing sequence flows")
+			}
+		}
+	}
+	for executionId, timerEvent := range validator.model.IntermediateTimerEvents {
+		if _, err := parseTimerDuration(timerEvent.Duration); err != nil {
+			validator.report(executionId, PROBLEM_TIMER, "%v", err)
+		}
+	}
+}
+
+// 默认出线必须是网关自己的出线 否则条件都不成立时找不到可以走的序列流
+func (validator *modelValidator) checkDefaultFlows() {
+	for executionId, gateway := range validator.model.ExclusiveGateways {
+		validator.checkDefaultFlow(executionId, gateway.Default, gateway.Outgoing)
+	}
+	for executionId, gateway := range validator.model.InclusiveGateways {
+		validator.checkDefaultFlow(executionId, gateway.Default, gateway.Outgoing)
+	}
+}
+
+func (validator *modelValidator) checkDefaultFlow(executionId string, defaultFlow string, outgoing []string) {
+	if defaultFlow != "" && !slices.Contains(outgoing, defaultFlow) {
+		validator.report(executionId, PROBLEM_DEFAULT_FLOW, "default flow %s is not an outgoing flow of the gateway", defaultFlow)
+	}
+}
+
+// 多实例审批节点必须配置负责人集合 完成条件必须能解析，定时器走出线会把其他负责人的节点实例留下，不支持
+func (validator *modelValidator) checkMultiInstances() {
+	for executionId, task := range validator.model.Tasks {
+		if task.MultiInstance == nil {
+			continue
+		}
+		if strings.TrimSpace(task.MultiInstance.Collection) == "" {
+			validator.report(executionId, PROBLEM_MULTI_INSTANCE, "multi-instance task has no collection")
+		} else if _, err := parseExpression(task.MultiInstance.Collection); err != nil {
+			validator.report(executionId, PROBLEM_MULTI_INSTANCE, "collection: %v", err)
+		}
+		if _, err := task.MultiInstance.requiredApprovals(1); err != nil {
+			validator.report(executionId, PROBLEM_MULTI_INSTANCE, "%v", err)
+		}
+		if task.hasCandidates() {
+			validator.report(executionId, PROBLEM_MULTI_INSTANCE, "candidate users and groups are not supported on multi-instance tasks")
+		}
+		for _, timer := range task.BoundaryTimers {
+			if timer.Action == TIMER_ACTION_FLOW {
+				validator.report(timer.ExecutionId, PROBLEM_MULTI_INSTANCE, "flow timers are not supported on multi-instance task %s", executionId)
+			}
+		}
+	}
+}
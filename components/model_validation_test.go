@@ -0,0 +1,219 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 校验用例的基础元素 s -> t -> e
+const (
+	vStart = `<StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>`
+	vTask  = `<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>`
+	vEnd   = `<EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>`
+	vF1    = `<SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>`
+	vF2    = `<SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>`
+)
+
+func validationXML(elements ...string) string {
+	return `<Process name="validation">` + strings.Join(elements, "\n") + `</Process>`
+}
+
+// s 之后的分支网关 p1 分成 a、b 两路，在 p2 汇聚后结束
+func validationSplitXML(split string, join string, extra ...string) string {
+	return validationXML(slices.Concat([]string{vStart, vF1, vEnd,
+		`<` + split + ` executionId="t"><Incoming>f1</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing></` + split + `>`,
+		`<Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing></Task>`,
+		`<` + join + ` executionId="p2"><Incoming>fa2</Incoming><Incoming>fb2</Incoming><Outgoing>f2</Outgoing></` + join + `>`,
+		`<SequenceFlow executionId="fa" sourceRef="t" targetRef="a"/>`,
+		`<SequenceFlow executionId="fb" sourceRef="t" targetRef="b"/>`,
+		`<SequenceFlow executionId="fa2" sourceRef="a" targetRef="p2"/>`,
+		`<SequenceFlow executionId="f2" sourceRef="p2" targetRef="e"/>`,
+	}, extra)...)
+}
+
+const vTaskB = `<Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>`
+
+var validationCases = []struct {
+	name string
+	xml  string
+	// 只在部署时检查的问题 ValidateModel 不报
+	deployOnly bool
+	want       []ModelProblem
+}{
+	{
+		name: "no start event",
+		xml:  validationXML(vTask, vEnd, vF1, vF2),
+		want: []ModelProblem{{Code: PROBLEM_MISSING_NODE, ExecutionId: "f1"}, {Code: PROBLEM_START_EVENT}},
+	},
+	{
+		name: "two start events",
+		xml: validationXML(vStart, vEnd, vF1, vF2,
+			`<StartEvent executionId="s2"><Outgoing>f0</Outgoing></StartEvent>`,
+			`<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming><Incoming>f0</Incoming><Outgoing>f2</Outgoing></Task>`,
+			`<SequenceFlow executionId="f0" sourceRef="s2" targetRef="t"/>`),
+		want: []ModelProblem{{Code: PROBLEM_START_EVENT}},
+	},
+	{
+		name: "duplicate id",
+		xml:  validationXML(vStart, vTask, vEnd, vF1, vF2, `<EndEvent executionId="f2"><Incoming>f2</Incoming></EndEvent>`),
+		want: []ModelProblem{{Code: PROBLEM_DUPLICATE_ID, ExecutionId: "f2"}},
+	},
+	{
+		name: "outgoing flow not defined",
+		xml:  validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing><Outgoing>f9</Outgoing></Task>`),
+		want: []ModelProblem{{Code: PROBLEM_DANGLING_REFERENCE, ExecutionId: "t"}},
+	},
+	{
+		name: "flow target does not exist",
+		xml:  validationXML(vStart, vTask, vEnd, vF1, `<SequenceFlow executionId="f2" sourceRef="t" targetRef="nowhere"/>`),
+		want: []ModelProblem{{Code: PROBLEM_DANGLING_REFERENCE, ExecutionId: "e"}, {Code: PROBLEM_MISSING_NODE, ExecutionId: "f2"}, {Code: PROBLEM_UNREACHABLE, ExecutionId: "e"}},
+	},
+	{
+		name: "task without outgoing flow",
+		xml:  validationXML(vStart, vEnd, vF1, `<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming></Task>`, `<SequenceFlow executionId="f2" sourceRef="s" targetRef="e"/>`),
+		want: []ModelProblem{{Code: PROBLEM_DANGLING_REFERENCE, ExecutionId: "f2"}, {Code: PROBLEM_MISSING_FLOW, ExecutionId: "t"}, {Code: PROBLEM_UNREACHABLE, ExecutionId: "e"}},
+	},
+	{
+		name: "unreachable loop",
+		xml: validationXML(vStart, vTask, vEnd, vF1, vF2,
+			`<Task executionId="x" name="X" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>fy</Incoming><Outgoing>fx</Outgoing></Task>`,
+			`<Task executionId="y" name="Y" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>fx</Incoming><Outgoing>fy</Outgoing></Task>`,
+			`<SequenceFlow executionId="fx" sourceRef="x" targetRef="y"/>`,
+			`<SequenceFlow executionId="fy" sourceRef="y" targetRef="x"/>`),
+		want: []ModelProblem{{Code: PROBLEM_UNREACHABLE, ExecutionId: "x"}, {Code: PROBLEM_UNREACHABLE, ExecutionId: "y"}},
+	},
+	{
+		name: "branch ends without joining",
+		xml: validationXML(vStart, vF1, vEnd, vTaskB,
+			`<ParallelGateway executionId="t"><Incoming>f1</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing></ParallelGateway>`,
+			`<Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>fa</Incoming><Outgoing>f2</Outgoing></Task>`,
+			`<EndEvent executionId="eb"><Incoming>fb2</Incoming></EndEvent>`,
+			`<SequenceFlow executionId="fa" sourceRef="t" targetRef="a"/>`,
+			`<SequenceFlow executionId="fb" sourceRef="t" targetRef="b"/>`,
+			`<SequenceFlow executionId="f2" sourceRef="a" targetRef="e"/>`,
+			`<SequenceFlow executionId="fb2" sourceRef="b" targetRef="eb"/>`),
+		want: []ModelProblem{{Code: PROBLEM_UNBALANCED_GATEWAY, ExecutionId: "t"}},
+	},
+	{
+		name: "join of a different type",
+		xml:  validationSplitXML("ParallelGateway", "InclusiveGateway", vTaskB, `<SequenceFlow executionId="fb2" sourceRef="b" targetRef="p2"/>`),
+		want: []ModelProblem{{Code: PROBLEM_UNBALANCED_GATEWAY, ExecutionId: "t"}},
+	},
+	{
+		name: "bad timer duration",
+		xml:  validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing><BoundaryTimer executionId="bt" duration="soon" action="complete"/></Task>`),
+		want: []ModelProblem{{Code: PROBLEM_TIMER, ExecutionId: "bt"}},
+	},
+	{
+		name: "bad task due date",
+		xml:  validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user" dueDate="P"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>`),
+		want: []ModelProblem{{Code: PROBLEM_TIMER, ExecutionId: "t"}},
+	},
+	{
+		name: "default flow of another element",
+		xml: validationXML(vStart, vEnd, vF1, `<ExclusiveGateway executionId="t" default="f1"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></ExclusiveGateway>`,
+			`<SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>`),
+		want: []ModelProblem{{Code: PROBLEM_DEFAULT_FLOW, ExecutionId: "t"}},
+	},
+	{
+		name: "multi-instance without collection",
+		xml:  validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T"><Incoming>f1</Incoming><Outgoing>f2</Outgoing><MultiInstance collection=""/></Task>`),
+		want: []ModelProblem{{Code: PROBLEM_MULTI_INSTANCE, ExecutionId: "t"}},
+	},
+	{
+		name:       "unregistered listener",
+		xml:        validationXML(vStart, vTask, vF1, vF2, `<EndEvent executionId="e"><Incoming>f2</Incoming><Listener>validationMissingListener</Listener></EndEvent>`),
+		deployOnly: true,
+		want:       []ModelProblem{{Code: PROBLEM_LISTENER, ExecutionId: "e"}},
+	},
+	{
+		name:       "unregistered service handler",
+		xml:        validationXML(vStart, vEnd, vF1, vF2, `<ServiceTask executionId="t" handler="validationMissingHandler"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></ServiceTask>`),
+		deployOnly: true,
+		want:       []ModelProblem{{Code: PROBLEM_HANDLER, ExecutionId: "t"}},
+	},
+	{
+		name: "condition that does not parse",
+		xml: validationXML(vStart, vEnd, vF1, `<ExclusiveGateway executionId="t"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></ExclusiveGateway>`,
+			`<SequenceFlow executionId="f2" sourceRef="t" targetRef="e"><ConditionExpression>amount &gt;</ConditionExpression></SequenceFlow>`),
+		deployOnly: true,
+		want:       []ModelProblem{{Code: PROBLEM_EXPRESSION, ExecutionId: "f2"}},
+	},
+	{
+		name:       "unknown assignee resolver",
+		xml:        validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T" assigneeType="ByNothing" assigneeKey="v-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>`),
+		deployOnly: true,
+		want:       []ModelProblem{{Code: PROBLEM_ASSIGNEE, ExecutionId: "t"}},
+	},
+	{
+		name:       "form that is not JSON",
+		xml:        validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing><FormData>{"elements":</FormData></Task>`),
+		deployOnly: true,
+		want:       []ModelProblem{{Code: PROBLEM_FORM, ExecutionId: "t"}},
+	},
+	{
+		name:       "async on a task",
+		xml:        validationXML(vStart, vEnd, vF1, vF2, `<Task executionId="t" name="T" async="true" assigneeType="ByAssigneeName" assigneeKey="v-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>`),
+		deployOnly: true,
+		want:       []ModelProblem{{Code: PROBLEM_ASYNC, ExecutionId: "t"}},
+	},
+}
+
+// 只比较问题类别和位置
+func problemKeys(problems []ModelProblem) []string {
+	keys := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		keys = append(keys, fmt.Sprintf("%s@%s", problem.Code, problem.ExecutionId))
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestValidationProblems(t *testing.T) {
+	for _, tc := range validationCases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := problemKeys(tc.want)
+			model, err := ParseXMLByte([]byte(tc.xml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			modelWant := want
+			if tc.deployOnly {
+				modelWant = []string{}
+			}
+			if got := problemKeys(ValidateModel(model)); !slices.Equal(got, modelWant) {
+				t.Errorf("ValidateModel problems = %v, want %v", got, modelWant)
+			}
+
+			var validationErr *ModelValidationError
+			err = validateProcessDefinitionXML([]byte(tc.xml))
+			if !errors.As(err, &validationErr) || !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("deploy validation error = %v", err)
+			}
+			if got := problemKeys(validationErr.Problems); !slices.Equal(got, want) {
+				t.Errorf("deploy problems = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// 仓库里自带的流程定义不应该有任何问题
+func TestBundledProcessesAreValid(t *testing.T) {
+	for _, path := range []string{"../xml/leave.xml", "../xml/test.xml"} {
+		xmlContent, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		model, err := ParseXMLByte(xmlContent)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if problems := ValidateModel(model); len(problems) != 0 {
+			t.Errorf("%s: problems = %v", path, problems)
+		}
+	}
+}
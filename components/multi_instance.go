@@ -0,0 +1,250 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 多实例审批节点的完成条件
+const (
+	MULTI_INSTANCE_ALL = "all" // 全部负责人同意才通过 有人不同意时提前结束（会签）
+	MULTI_INSTANCE_ANY = "any" // 任意一个负责人同意就通过 全部不同意时结束（或签）
+)
+
+// 多实例审批节点保存在节点作用域里的汇总变量 下游的条件表达式可以直接使用，例如 heads.approved == true
+const (
+	MULTI_INSTANCE_VAR_ASSIGNEES = "assignees"              // 负责人列表 按集合的顺序
+	MULTI_INSTANCE_VAR_INSTANCES = "nrOfInstances"          // 节点实例总数
+	MULTI_INSTANCE_VAR_COMPLETED = "nrOfCompletedInstances" // 已经完成的节点实例数
+	MULTI_INSTANCE_VAR_APPROVED  = "nrOfApproved"           // 同意的人数
+	MULTI_INSTANCE_VAR_REJECTED  = "nrOfRejected"           // 不同意的人数
+	MULTI_INSTANCE_VAR_OUTPUTS   = "outputs"                // 每个负责人的输出 [{"assignee":"bob","output":{...}}]
+	MULTI_INSTANCE_VAR_RESULT    = "approved"               // 节点整体完成时是否满足完成条件
+)
+
+// 节点实例的输出里表示是否同意的字段 没有这个字段时按同意处理，只有明确为 false 才算不同意
+const MULTI_INSTANCE_APPROVAL_FIELD = "approved"
+
+// 完成条件的比例或人数写法 例如 >= 2/3、> 1/2、>= 2，后面可以跟 approved
+var completionConditionRegex = regexp.MustCompile(`^(>=|>)\s*(\d+)(?:\s*/\s*(\d+))?(?:\s+approved)?$`)
+
+// MultiInstance 多实例（会签）配置 审批节点按负责人集合生成多个节点实例，每个负责人一条
+// 并行时同时生成全部节点实例，依次审批时前一个完成后再生成下一个
+// 同意人数达到完成条件，或者剩下的人全部同意也达不到时，节点整体完成，没有完成的节点实例被取消
+type MultiInstance struct {
+	Sequential          bool   `xml:"sequential,attr"`          // 为 true 时按集合的顺序依次审批
+	Collection          string `xml:"collection,attr"`          // 负责人集合的表达式 取值是字符串数组，例如启动表单里的 startEvent.heads，第一段是开始事件的结构id
+	CompletionCondition string `xml:"completionCondition,attr"` // all（默认）、any 或者同意的比例/人数，例如 >= 2/3
+}
+
+// 按负责人总数计算需要多少人同意
+func (multiInstance MultiInstance) requiredApprovals(total int) (int, error) {
+	condition := strings.TrimSpace(multiInstance.CompletionCondition)
+	switch condition {
+	case "", MULTI_INSTANCE_ALL:
+		return total, nil
+	case MULTI_INSTANCE_ANY:
+		return 1, nil
+	}
+	match := completionConditionRegex.FindStringSubmatch(condition)
+	if match == nil {
+		return 0, fmt.Errorf("invalid completion condition %q", multiInstance.CompletionCondition)
+	}
+	numerator, _ := strconv.Atoi(match[2])
+	if match[3] == "" {
+		if match[1] == ">" {
+			return numerator + 1, nil
+		}
+		if numerator == 0 {
+			return 0, fmt.Errorf("completion condition %q requires no approval", multiInstance.CompletionCondition)
+		}
+		return numerator, nil
+	}
+	denominator, _ := strconv.Atoi(match[3])
+	if denominator == 0 || numerator > denominator || (numerator == 0 && match[1] == ">=") {
+		return 0, fmt.Errorf("invalid approval ratio in completion condition %q", multiInstance.CompletionCondition)
+	}
+	if match[1] == ">" {
+		return total*numerator/denominator + 1, nil
+	}
+	// 向上取整 3 个人的 2/3 是 2 人
+	return (total*numerator + denominator - 1) / denominator, nil
+}
+
+// 计算负责人集合 集合表达式的结果必须是非空的字符串数组
+func (task Task) multiInstanceAssignees(ctx *WorkflowContext) ([]string, error) {
+	collection := task.MultiInstance.Collection
+	parameters, err := resolveExpressionParameters(ctx, collection)
+	if err != nil {
+		return nil, newExecutionError(task.ExecutionId, ErrExpressionFailed, err)
+	}
+	result, err := EvaluateExpression(collection, parameters)
+	if err != nil {
+		return nil, newExecutionError(task.ExecutionId, ErrExpressionFailed, err)
+	}
+	var items []any
+	switch value := result.(type) {
+	case expressionList:
+		items = value
+	case []any:
+		items = value
+	case string:
+		items = []any{value}
+	default:
+		return nil, newExecutionError(task.ExecutionId, ErrInvalidInput, fmt.Errorf("collection %s is %T, expected an array of assignees", collection, result))
+	}
+	assignees := make([]string, 0, len(items))
+	for _, item := range items {
+		assignee, ok := item.(string)
+		if !ok || strings.TrimSpace(assignee) == "" {
+			return nil, newExecutionError(task.ExecutionId, ErrInvalidInput, fmt.Errorf("collection %s contains invalid assignee %v", collection, item))
+		}
+		assignees = append(assignees, assignee)
+	}
+	if len(assignees) == 0 {
+		return nil, newExecutionError(task.ExecutionId, ErrInvalidInput, fmt.Errorf("collection %s is empty", collection))
+	}
+	return assignees, nil
+}
+
+// 多实例审批节点开始执行 计算负责人集合，重置汇总变量后生成节点实例
+func (task Task) executeMultiInstance(ctx *WorkflowContext) error {
+	assignees, err := task.multiInstanceAssignees(ctx)
+	if err != nil {
+		return err
+	}
+	state := map[string]any{
+		MULTI_INSTANCE_VAR_ASSIGNEES: assignees,
+		MULTI_INSTANCE_VAR_INSTANCES: len(assignees),
+		MULTI_INSTANCE_VAR_COMPLETED: 0,
+		MULTI_INSTANCE_VAR_APPROVED:  0,
+		MULTI_INSTANCE_VAR_REJECTED:  0,
+		MULTI_INSTANCE_VAR_OUTPUTS:   []any{},
+		MULTI_INSTANCE_VAR_RESULT:    nil, // 上一轮的结果 节点整体完成时再写入
+	}
+	if err := task.saveMultiInstanceState(ctx, state); err != nil {
+		return err
+	}
+	if task.MultiInstance.Sequential {
+		assignees = assignees[:1]
+	}
+	for _, assignee := range assignees {
+		if err := task.initTaskInstance(ctx, ctx.CurrentExecutionId, Assignment{Assignee: assignee}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 多实例审批节点的一个节点实例完成 更新汇总变量，满足完成条件时取消剩下的节点实例并往下走
+func (task Task) completeMultiInstance(ctx *WorkflowContext, id int, data string) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	node, err := nodeService.LockNodeInstance(ctx.Tx, id)
+	if err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	if node == nil {
+		return newExecutionError(task.ExecutionId, ErrNodeNotFound, fmt.Errorf("node instance %d not found", id))
+	}
+	state, err := GetServiceFactory().GetVariableService().GetVariables(ctx.Tx, ctx.ProcessInstanceId, task.ExecutionId)
+	if err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	assignees, _ := state[MULTI_INSTANCE_VAR_ASSIGNEES].([]any)
+	total := len(assignees)
+	if total == 0 {
+		return newExecutionError(task.ExecutionId, ErrNodeNotFound, fmt.Errorf("multi-instance state of %s is missing", task.ExecutionId))
+	}
+	completed := variableInt(state[MULTI_INSTANCE_VAR_COMPLETED]) + 1
+	approvedCount := variableInt(state[MULTI_INSTANCE_VAR_APPROVED])
+	rejectedCount := variableInt(state[MULTI_INSTANCE_VAR_REJECTED])
+	var output any
+	if err := json.Unmarshal([]byte(data), &output); err != nil {
+		return newExecutionError(task.ExecutionId, ErrInvalidInput, fmt.Errorf("failed to parse task data %s: %v", data, err))
+	}
+	if fields, ok := output.(map[string]any); ok && fields[MULTI_INSTANCE_APPROVAL_FIELD] == false {
+		rejectedCount++
+	} else {
+		approvedCount++
+	}
+	outputs, _ := state[MULTI_INSTANCE_VAR_OUTPUTS].([]any)
+	outputs = append(outputs, map[string]any{"assignee": node.Assignee, "output": output})
+
+	required, err := task.MultiInstance.requiredApprovals(total)
+	if err != nil {
+		return newExecutionError(task.ExecutionId, ErrInvalidInput, err)
+	}
+	approved := approvedCount >= required
+	finished := approved || approvedCount+total-completed < required || completed == total
+	update := map[string]any{
+		MULTI_INSTANCE_VAR_COMPLETED: completed,
+		MULTI_INSTANCE_VAR_APPROVED:  approvedCount,
+		MULTI_INSTANCE_VAR_REJECTED:  rejectedCount,
+		MULTI_INSTANCE_VAR_OUTPUTS:   outputs,
+	}
+	if finished {
+		update[MULTI_INSTANCE_VAR_RESULT] = approved
+	}
+	if err := task.saveMultiInstanceState(ctx, update); err != nil {
+		return err
+	}
+
+	if !finished {
+		if !task.MultiInstance.Sequential {
+			return nil
+		}
+		next, _ := assignees[completed].(string)
+		return task.initTaskInstance(ctx, node.PreviousExecutionId, Assignment{Assignee: next})
+	}
+	if err := task.cancelMultiInstances(ctx); err != nil {
+		return err
+	}
+	return task.leave(ctx)
+}
+
+// 节点整体完成后 还没有完成的兄弟节点实例带着取消状态迁移到历史表
+func (task Task) cancelMultiInstances(ctx *WorkflowContext) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	historyService := GetServiceFactory().GetHistoryService()
+	jobService := GetServiceFactory().GetJobService()
+	nodes, err := nodeService.LockNodeInstancesByProcessInstanceId(ctx.Tx, ctx.ProcessInstanceId)
+	if err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	for _, current := range nodes {
+		if current.ExecutionId != task.ExecutionId || current.OutputData != "" {
+			continue
+		}
+		if err := historyService.ArchiveNodeInstance(ctx.Tx, current.Id, NODE_STATUS_CANCELLED, "multi-instance completed"); err != nil {
+			return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if err := nodeService.DeleteNodeInstance(ctx.Tx, current.Id); err != nil {
+			return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if err := jobService.DeleteTimerJobsByNodeInstance(ctx.Tx, current.Id); err != nil {
+			return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+		}
+	}
+	return nil
+}
+
+// 汇总变量按名称顺序保存 变量历史的顺序是固定的
+func (task Task) saveMultiInstanceState(ctx *WorkflowContext, state map[string]any) error {
+	dataBytes, err := json.Marshal(state)
+	if err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	if err := saveOutputVariables(ctx, task.ExecutionId, string(dataBytes)); err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// 变量里的数字解码后是 float64
+func variableInt(value any) int {
+	number, _ := toFloat64(value)
+	return int(number)
+}
@@ -0,0 +1,100 @@
+package components
+
+import (
+	"sort"
+	"testing"
+)
+
+// 会签节点 heads 并行 seq 依次审批，负责人集合来自启动表单，之后按 heads 的结果走不同的出线
+const multiInstanceXML = `<Process name="multiInstance">
+  <StartEvent executionId="startEvent"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="heads" name="Heads" assigneeType="ByAssigneeName" assigneeKey=""><Incoming>f1</Incoming><Outgoing>f2</Outgoing>
+    <MultiInstance collection="startEvent.heads" completionCondition="&gt;= 2/3 approved"/>
+  </Task>
+  <ExclusiveGateway executionId="x" default="fno"><Incoming>f2</Incoming><Outgoing>fok</Outgoing><Outgoing>fno</Outgoing></ExclusiveGateway>
+  <Task executionId="seq" name="Seq" assigneeType="ByAssigneeName" assigneeKey=""><Incoming>fok</Incoming><Outgoing>f3</Outgoing>
+    <MultiInstance sequential="true" collection="startEvent.seq"/>
+  </Task>
+  <Task executionId="no" name="NO" assigneeType="ByAssigneeName" assigneeKey="mi-user"><Incoming>fno</Incoming><Outgoing>f4</Outgoing></Task>
+  <Task executionId="done" name="Done" assigneeType="ByAssigneeName" assigneeKey="mi-user"><Incoming>f3</Incoming><Outgoing>f5</Outgoing></Task>
+  <EndEvent executionId="e1"><Incoming>f4</Incoming></EndEvent>
+  <EndEvent executionId="e2"><Incoming>f5</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="startEvent" targetRef="heads"/>
+  <SequenceFlow executionId="f2" sourceRef="heads" targetRef="x"/>
+  <SequenceFlow executionId="fok" sourceRef="x" targetRef="seq"><ConditionExpression>heads.approved == true &amp;&amp; heads.nrOfApproved &gt;= 2</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fno" sourceRef="x" targetRef="no"/>
+  <SequenceFlow executionId="f3" sourceRef="seq" targetRef="done"/>
+  <SequenceFlow executionId="f4" sourceRef="no" targetRef="e1"/>
+  <SequenceFlow executionId="f5" sourceRef="done" targetRef="e2"/>
+</Process>`
+
+const multiInstanceForm = `{"heads":["mi-a","mi-b","mi-c"],"seq":["mi-x","mi-y"]}`
+
+// 会签节点还没有完成的节点实例 负责人 -> 节点实例
+func activeInstances(t *testing.T, processInstanceId int, executionId string) map[string]*NodeInstance {
+	t.Helper()
+	nodes, err := GetMemoryNodeService().GetNodeInstancesByProcessInstanceId(processInstanceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]*NodeInstance)
+	for _, node := range nodes {
+		if node.ExecutionId == executionId && node.OutputData == "" {
+			result[node.Assignee] = node
+		}
+	}
+	return result
+}
+
+func assigneesOf(instances map[string]*NodeInstance) []string {
+	assignees := make([]string, 0, len(instances))
+	for assignee := range instances {
+		assignees = append(assignees, assignee)
+	}
+	sort.Strings(assignees)
+	return assignees
+}
+
+func TestMultiInstanceCollectionFromStartForm(t *testing.T) {
+	deployXML(t, "multiInstance", []byte(multiInstanceXML))
+	id := startProcess(t, "multiInstance", "mi-ann", multiInstanceForm)
+	terminateOnCleanup(t, id)
+
+	heads := activeInstances(t, id, "heads")
+	if assignees := assigneesOf(heads); len(assignees) != 3 || assignees[0] != "mi-a" || assignees[2] != "mi-c" {
+		t.Fatalf("parallel assignees = %v", assignees)
+	}
+	completeTaskAs(t, heads["mi-a"].Id, "mi-a", map[string]any{"approved": true})
+	completeTaskAs(t, heads["mi-b"].Id, "mi-b", map[string]any{"approved": false})
+	if findActiveNode(t, id, "seq") != nil {
+		t.Fatal("multi instance completed before the completion condition was decided")
+	}
+	completeTaskAs(t, heads["mi-c"].Id, "mi-c", nil)
+
+	// 依次审批 前一个完成后才生成下一个
+	if assignees := assigneesOf(activeInstances(t, id, "seq")); len(assignees) != 1 || assignees[0] != "mi-x" {
+		t.Fatalf("sequential assignees after start = %v", assignees)
+	}
+	completeTaskAs(t, activeInstances(t, id, "seq")["mi-x"].Id, "mi-x", nil)
+	if assignees := assigneesOf(activeInstances(t, id, "seq")); len(assignees) != 1 || assignees[0] != "mi-y" {
+		t.Fatalf("sequential assignees after first approval = %v", assignees)
+	}
+	completeTaskAs(t, activeInstances(t, id, "seq")["mi-y"].Id, "mi-y", nil)
+	activeTask(t, id, "done")
+}
+
+func TestMultiInstanceCompletesWhenConditionCannotBeMet(t *testing.T) {
+	deployXML(t, "multiInstance", []byte(multiInstanceXML))
+	id := startProcess(t, "multiInstance", "mi-ann", multiInstanceForm)
+	terminateOnCleanup(t, id)
+
+	heads := activeInstances(t, id, "heads")
+	completeTaskAs(t, heads["mi-a"].Id, "mi-a", map[string]any{"approved": false})
+	completeTaskAs(t, heads["mi-b"].Id, "mi-b", map[string]any{"approved": false})
+
+	// 剩下的人全部同意也到不了 2/3 其他节点实例被取消
+	if remaining := activeInstances(t, id, "heads"); len(remaining) != 0 {
+		t.Fatalf("instances left after rejection = %v", assigneesOf(remaining))
+	}
+	activeTask(t, id, "no")
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// MySQLCommentService 是 CommentService 接口的 MySQL 实现
+type MySQLCommentService struct {
+	*SQLCommentService
+}
+
+var mysqlCommentServiceInstance *MySQLCommentService
+var mysqlCommentServiceOnce sync.Once
+
+// InitializeMySQLCommentService 初始化单例实例
+func InitializeMySQLCommentService(db *sql.DB) {
+	mysqlCommentServiceOnce.Do(func() {
+		mysqlCommentServiceInstance = &MySQLCommentService{&SQLCommentService{DB: db, dialect: mysqlDialect}}
+	})
+}
+
+// GetMySQLCommentService 获取单例实例
+func GetMySQLCommentService() *MySQLCommentService {
+	if mysqlCommentServiceInstance == nil {
+		panic("MySQLCommentService is not initialized. Call InitializeMySQLCommentService first.")
+	}
+	return mysqlCommentServiceInstance
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// MySQLDelegationRuleService 是 DelegationRuleService 接口的 MySQL 实现
+type MySQLDelegationRuleService struct {
+	*SQLDelegationRuleService
+}
+
+var mysqlDelegationRuleServiceInstance *MySQLDelegationRuleService
+var mysqlDelegationRuleServiceOnce sync.Once
+
+// InitializeMySQLDelegationRuleService 初始化单例实例
+func InitializeMySQLDelegationRuleService(db *sql.DB) {
+	mysqlDelegationRuleServiceOnce.Do(func() {
+		mysqlDelegationRuleServiceInstance = &MySQLDelegationRuleService{&SQLDelegationRuleService{DB: db, dialect: mysqlDialect}}
+	})
+}
+
+// GetMySQLDelegationRuleService 获取单例实例
+func GetMySQLDelegationRuleService() *MySQLDelegationRuleService {
+	if mysqlDelegationRuleServiceInstance == nil {
+		panic("MySQLDelegationRuleService is not initialized. Call InitializeMySQLDelegationRuleService first.")
+	}
+	return mysqlDelegationRuleServiceInstance
+}
@@ -0,0 +1,28 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+type MySQLJobService struct {
+	*SQLJobService
+}
+
+var mysqlJobServiceInstance *MySQLJobService
+var mysqlJobServiceOnce sync.Once
+
+// InitializeMySQLJobService 初始化单例实例
+func InitializeMySQLJobService(db *sql.DB) {
+	mysqlJobServiceOnce.Do(func() {
+		mysqlJobServiceInstance = &MySQLJobService{&SQLJobService{DB: db, dialect: mysqlDialect}}
+	})
+}
+
+// GetMySQLJobService 获取单例实例
+func GetMySQLJobService() *MySQLJobService {
+	if mysqlJobServiceInstance == nil {
+		panic("MySQLJobService is not initialized. Call InitializeMySQLJobService first.")
+	}
+	return mysqlJobServiceInstance
+}
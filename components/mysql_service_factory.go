@@ -11,6 +11,10 @@ func (f *MySQLServiceFactory) InitServiceInstance(db *sql.DB) {
 	InitializeMySQLRepositoryService(db)
 	InitializeMySQLNodeService(db)
 	InitializeMySQLHistoryService(db)
+	InitializeMySQLJobService(db)
+	InitializeMySQLVariableService(db)
+	InitializeMySQLDelegationRuleService(db)
+	InitializeMySQLCommentService(db)
 }
 
 func (f *MySQLServiceFactory) GetRuntimeService() RuntimeService {
@@ -28,3 +32,19 @@ func (f *MySQLServiceFactory) GetNodeService() NodeService {
 func (f *MySQLServiceFactory) GetHistoryService() HistoryService {
 	return GetMySQLHistoryService()
 }
+
+func (f *MySQLServiceFactory) GetJobService() JobService {
+	return GetMySQLJobService()
+}
+
+func (f *MySQLServiceFactory) GetVariableService() VariableService {
+	return GetMySQLVariableService()
+}
+
+func (f *MySQLServiceFactory) GetDelegationRuleService() DelegationRuleService {
+	return GetMySQLDelegationRuleService()
+}
+
+func (f *MySQLServiceFactory) GetCommentService() CommentService {
+	return GetMySQLCommentService()
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// MySQLVariableService 是 VariableService 接口的 MySQL 实现
+type MySQLVariableService struct {
+	*SQLVariableService
+}
+
+var mysqlVariableServiceInstance *MySQLVariableService
+var mysqlVariableServiceOnce sync.Once
+
+// InitializeMySQLVariableService 初始化单例实例
+func InitializeMySQLVariableService(db *sql.DB) {
+	mysqlVariableServiceOnce.Do(func() {
+		mysqlVariableServiceInstance = &MySQLVariableService{&SQLVariableService{DB: db, dialect: mysqlDialect}}
+	})
+}
+
+// GetMySQLVariableService 获取单例实例
+func GetMySQLVariableService() *MySQLVariableService {
+	if mysqlVariableServiceInstance == nil {
+		panic("MySQLVariableService is not initialized. Call InitializeMySQLVariableService first.")
+	}
+	return mysqlVariableServiceInstance
+}
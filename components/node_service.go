@@ -17,6 +17,10 @@ type NodeInstance struct {
 	Assignee              string // 节点的负责人 (网关 和 序列流 负责人为空)
 	StartTime             time.Time
 	EndTime               time.Time
+	Owner                 string    // 委派或者加签时审批节点的所有人 委派的人处理完或者加签的人审批完回到这个人
+	DelegationState       string    // TASK_DELEGATION_* 之一 没有委派过时为空
+	DueDate               time.Time // 审批期限 按审批节点的 dueDate 或者最早到期的边界定时器计算，都没有时为零值
+	OriginalAssignee      string    // 负责人不在时按代理规则交给代理人，这里是第一次被代理的负责人 之后认领、委派、加签或者转办给不在的人也交给代理人，这里都不变
 }
 
 // TaskRuntimeService 提供了操作节点实例的接口
@@ -28,9 +32,40 @@ type NodeService interface {
 	GetAttributeByExpression(tx *sql.Tx, expression string, processInstanceId int) (map[string]interface{}, error)
 	//需要加锁防止并发情况下的
 	CountParallelGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string) (int, error)
+	//包容网关汇聚时 只统计对应的分支网关最近一次激活之后到达的记录 加锁方式和并行网关一致
+	CountInclusiveGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string, afterId int) (int, error)
+	//查询某个结构id最近一次有输出数据的节点实例 不存在时返回 nil
+	GetLatestCompletedNodeInstance(tx *sql.Tx, processInstanceId int, executionId string) (*NodeInstance, error)
 	UpdateNodeInstanceOutput(tx *sql.Tx, id int, outputData string) error
+	//修改审批节点的负责人 定时器升级和转派时使用
+	UpdateNodeInstanceAssignee(tx *sql.Tx, id int, assignee string) error
+	//修改审批节点的负责人、所有人和委派状态 委派和加签时使用
+	UpdateNodeInstanceDelegation(tx *sql.Tx, id int, assignee string, owner string, delegationState string) error
+	//按代理规则把审批节点交给代理人 记录原来的负责人
+	UpdateNodeInstanceSubstitute(tx *sql.Tx, id int, substitute string, originalAssignee string) error
+	//记录审批节点的到期时间
+	UpdateNodeInstanceDueDate(tx *sql.Tx, id int, dueDate time.Time) error
 	GetAssigneeUndoneTask(assignee string) ([]map[string]interface{}, error)
+	//记录审批节点的候选人和候选组 认领之前节点的负责人为空
+	AddNodeCandidates(tx *sql.Tx, processInstanceId int, nodeInstanceId int, users []string, groups []string) error
+	//查询审批节点的候选人和候选组
+	GetNodeCandidates(tx *sql.Tx, nodeInstanceId int) (users []string, groups []string, err error)
+	//用户的待办 分配给用户的，以及还没有被认领、用户或者用户所在的组是候选的审批节点，按id排序
+	GetCandidateUndoneTask(userId string, groups []string) ([]map[string]interface{}, error)
 	GetTaskDetailByTaskId(taskId int) (map[string]interface{}, error)
+	//根据id查询节点实例 不存在时返回 nil
+	GetNodeInstanceById(id int) (*NodeInstance, error)
+	//在事务里读取并锁住节点实例 不存在时返回 nil
+	LockNodeInstance(tx *sql.Tx, id int) (*NodeInstance, error)
+	//在事务里读取并锁住流程实例的全部节点实例 按id排序
+	LockNodeInstancesByProcessInstanceId(tx *sql.Tx, processInstanceId int) ([]NodeInstance, error)
+	//删除节点实例和它的候选人 打回时清理下游的节点
+	DeleteNodeInstance(tx *sql.Tx, id int) error
+	//按最新版本的流程定义取表单
 	GetTaskForm(processDefinitionName string, executionId string) (string, error)
+	//按流程实例启动时的版本取表单
+	GetTaskFormByVersion(processDefinitionName string, version int, executionId string) (string, error)
 	ClearProcessData(tx *sql.Tx, processInstanceId int) error
+	//流程实例各节点最近一次的输出数据 结构id -> 输出数据
+	GetProcessVariables(tx *sql.Tx, processInstanceId int) (map[string]any, error)
 }
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresCommentService 是 CommentService 接口的 PostgreSQL 实现
+type PostgresCommentService struct {
+	*SQLCommentService
+}
+
+var postgresCommentServiceInstance *PostgresCommentService
+var postgresCommentServiceOnce sync.Once
+
+// InitializePostgresCommentService 初始化单例实例
+func InitializePostgresCommentService(db *sql.DB) {
+	postgresCommentServiceOnce.Do(func() {
+		postgresCommentServiceInstance = &PostgresCommentService{&SQLCommentService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresCommentService 获取单例实例
+func GetPostgresCommentService() *PostgresCommentService {
+	if postgresCommentServiceInstance == nil {
+		panic("PostgresCommentService is not initialized. Call InitializePostgresCommentService first.")
+	}
+	return postgresCommentServiceInstance
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresDelegationRuleService 是 DelegationRuleService 接口的 PostgreSQL 实现
+type PostgresDelegationRuleService struct {
+	*SQLDelegationRuleService
+}
+
+var postgresDelegationRuleServiceInstance *PostgresDelegationRuleService
+var postgresDelegationRuleServiceOnce sync.Once
+
+// InitializePostgresDelegationRuleService 初始化单例实例
+func InitializePostgresDelegationRuleService(db *sql.DB) {
+	postgresDelegationRuleServiceOnce.Do(func() {
+		postgresDelegationRuleServiceInstance = &PostgresDelegationRuleService{&SQLDelegationRuleService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresDelegationRuleService 获取单例实例
+func GetPostgresDelegationRuleService() *PostgresDelegationRuleService {
+	if postgresDelegationRuleServiceInstance == nil {
+		panic("PostgresDelegationRuleService is not initialized. Call InitializePostgresDelegationRuleService first.")
+	}
+	return postgresDelegationRuleServiceInstance
+}
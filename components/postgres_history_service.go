@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresHistoryService 是 HistoryService 接口的 PostgreSQL 实现
+type PostgresHistoryService struct {
+	*SQLHistoryService
+}
+
+var postgresHistoryServiceInstance *PostgresHistoryService
+var postgresHistoryServiceOnce sync.Once
+
+// InitializePostgresHistoryService 初始化单例实例
+func InitializePostgresHistoryService(db *sql.DB) {
+	postgresHistoryServiceOnce.Do(func() {
+		postgresHistoryServiceInstance = &PostgresHistoryService{&SQLHistoryService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresHistoryService 获取单例实例
+func GetPostgresHistoryService() *PostgresHistoryService {
+	if postgresHistoryServiceInstance == nil {
+		panic("PostgresHistoryService is not initialized. Call InitializePostgresHistoryService first.")
+	}
+	return postgresHistoryServiceInstance
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresJobService 是 JobService 接口的 PostgreSQL 实现
+type PostgresJobService struct {
+	*SQLJobService
+}
+
+var postgresJobServiceInstance *PostgresJobService
+var postgresJobServiceOnce sync.Once
+
+// InitializePostgresJobService 初始化单例实例
+func InitializePostgresJobService(db *sql.DB) {
+	postgresJobServiceOnce.Do(func() {
+		postgresJobServiceInstance = &PostgresJobService{&SQLJobService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresJobService 获取单例实例
+func GetPostgresJobService() *PostgresJobService {
+	if postgresJobServiceInstance == nil {
+		panic("PostgresJobService is not initialized. Call InitializePostgresJobService first.")
+	}
+	return postgresJobServiceInstance
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresNodeService 是 NodeService 接口的 PostgreSQL 实现
+type PostgresNodeService struct {
+	*SQLNodeService
+}
+
+var postgresNodeServiceInstance *PostgresNodeService
+var postgresNodeServiceOnce sync.Once
+
+// InitializePostgresNodeService 初始化单例实例
+func InitializePostgresNodeService(db *sql.DB) {
+	postgresNodeServiceOnce.Do(func() {
+		postgresNodeServiceInstance = &PostgresNodeService{&SQLNodeService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresNodeService 获取单例实例
+func GetPostgresNodeService() *PostgresNodeService {
+	if postgresNodeServiceInstance == nil {
+		panic("PostgresNodeService is not initialized. Call InitializePostgresNodeService first.")
+	}
+	return postgresNodeServiceInstance
+}
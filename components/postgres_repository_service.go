@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresRepositoryService 是 RepositoryService 接口的 PostgreSQL 实现
+type PostgresRepositoryService struct {
+	*SQLRepositoryService
+}
+
+var postgresRepositoryServiceInstance *PostgresRepositoryService
+var postgresRepositoryServiceOnce sync.Once
+
+// InitializePostgresRepositoryService 初始化单例实例
+func InitializePostgresRepositoryService(db *sql.DB) {
+	postgresRepositoryServiceOnce.Do(func() {
+		postgresRepositoryServiceInstance = &PostgresRepositoryService{&SQLRepositoryService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresRepositoryService 获取单例实例
+func GetPostgresRepositoryService() *PostgresRepositoryService {
+	if postgresRepositoryServiceInstance == nil {
+		panic("PostgresRepositoryService is not initialized. Call InitializePostgresRepositoryService first.")
+	}
+	return postgresRepositoryServiceInstance
+}
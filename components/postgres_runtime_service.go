@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresRuntimeService 是 RuntimeService 接口的 PostgreSQL 实现
+type PostgresRuntimeService struct {
+	*SQLRuntimeService
+}
+
+var postgresRuntimeServiceInstance *PostgresRuntimeService
+var postgresRuntimeServiceOnce sync.Once
+
+// InitializePostgresRuntimeService 初始化单例实例
+func InitializePostgresRuntimeService(db *sql.DB) {
+	postgresRuntimeServiceOnce.Do(func() {
+		postgresRuntimeServiceInstance = &PostgresRuntimeService{&SQLRuntimeService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresRuntimeService 获取单例实例
+func GetPostgresRuntimeService() *PostgresRuntimeService {
+	if postgresRuntimeServiceInstance == nil {
+		panic("PostgresRuntimeService is not initialized. Call InitializePostgresRuntimeService first.")
+	}
+	return postgresRuntimeServiceInstance
+}
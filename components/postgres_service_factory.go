@@ -0,0 +1,50 @@
+package components
+
+import (
+	"database/sql"
+)
+
+type PostgresServiceFactory struct{}
+
+func (f *PostgresServiceFactory) InitServiceInstance(db *sql.DB) {
+	InitializePostgresRuntimeService(db)
+	InitializePostgresRepositoryService(db)
+	InitializePostgresNodeService(db)
+	InitializePostgresHistoryService(db)
+	InitializePostgresJobService(db)
+	InitializePostgresVariableService(db)
+	InitializePostgresDelegationRuleService(db)
+	InitializePostgresCommentService(db)
+}
+
+func (f *PostgresServiceFactory) GetRuntimeService() RuntimeService {
+	return GetPostgresRuntimeService()
+}
+
+func (f *PostgresServiceFactory) GetRepositoryService() RepositoryService {
+	return GetPostgresRepositoryService()
+}
+
+func (f *PostgresServiceFactory) GetNodeService() NodeService {
+	return GetPostgresNodeService()
+}
+
+func (f *PostgresServiceFactory) GetHistoryService() HistoryService {
+	return GetPostgresHistoryService()
+}
+
+func (f *PostgresServiceFactory) GetJobService() JobService {
+	return GetPostgresJobService()
+}
+
+func (f *PostgresServiceFactory) GetVariableService() VariableService {
+	return GetPostgresVariableService()
+}
+
+func (f *PostgresServiceFactory) GetDelegationRuleService() DelegationRuleService {
+	return GetPostgresDelegationRuleService()
+}
+
+func (f *PostgresServiceFactory) GetCommentService() CommentService {
+	return GetPostgresCommentService()
+}
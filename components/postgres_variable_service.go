@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// PostgresVariableService 是 VariableService 接口的 PostgreSQL 实现
+type PostgresVariableService struct {
+	*SQLVariableService
+}
+
+var postgresVariableServiceInstance *PostgresVariableService
+var postgresVariableServiceOnce sync.Once
+
+// InitializePostgresVariableService 初始化单例实例
+func InitializePostgresVariableService(db *sql.DB) {
+	postgresVariableServiceOnce.Do(func() {
+		postgresVariableServiceInstance = &PostgresVariableService{&SQLVariableService{DB: db, dialect: postgresDialect}}
+	})
+}
+
+// GetPostgresVariableService 获取单例实例
+func GetPostgresVariableService() *PostgresVariableService {
+	if postgresVariableServiceInstance == nil {
+		panic("PostgresVariableService is not initialized. Call InitializePostgresVariableService first.")
+	}
+	return postgresVariableServiceInstance
+}
@@ -0,0 +1,157 @@
+package components
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// 各数据库实现共用的终止流程实例的逻辑
+// 未完成的审批节点带着原因迁移到历史表，清空运行中的节点数据，流程实例记录终止状态后归档到历史流程实例表
+func terminateProcessInstance(runtimeService RuntimeService, processInstanceId int, reason string) error {
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, terminateInTx(runtimeService, tx, processInstanceId, reason))
+}
+
+func terminateInTx(runtimeService RuntimeService, tx *sql.Tx, processInstanceId int, reason string) error {
+	instance, err := lockProcessInstance(runtimeService, tx, processInstanceId)
+	if err != nil {
+		return err
+	}
+	if instance.Status != PROCESS_STATUS_RUNNING && !processInstancePaused(instance.Status) {
+		return fmt.Errorf("%w: id %d status %s", ErrProcessNotRunning, processInstanceId, instance.Status)
+	}
+	model, err := getModelByVersion(instance.ProcessDefinitionName, instance.Version)
+	if err != nil {
+		return err
+	}
+
+	nodeService := GetServiceFactory().GetNodeService()
+	historyService := GetServiceFactory().GetHistoryService()
+	nodes, err := nodeService.LockNodeInstancesByProcessInstanceId(tx, processInstanceId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	// 网关和已经完成的审批节点在执行的时候就已经进了历史表，只需要迁移没有完成的审批节点
+	for _, node := range nodes {
+		if _, isTask := model.Tasks[node.ExecutionId]; !isTask || node.OutputData != "" {
+			continue
+		}
+		if err := historyService.ArchiveNodeInstance(tx, node.Id, NODE_STATUS_TERMINATED, reason); err != nil {
+			return newExecutionError(node.ExecutionId, ErrPersistenceFailed, err)
+		}
+	}
+	if err := nodeService.ClearProcessData(tx, processInstanceId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if err := clearProcessJobs(tx, processInstanceId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if err := GetServiceFactory().GetVariableService().DeleteVariablesByProcessInstance(tx, processInstanceId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if err := runtimeService.UpdateProcessInstanceStatus(tx, processInstanceId, PROCESS_STATUS_TERMINATED); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if err := archiveProcessInstance(runtimeService, tx, processInstanceId, ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// 流程结束或终止后是否从 process_instance 表删除，默认保留，历史表里始终有一份
+var removeFinishedProcessInstances atomic.Bool
+
+// SetRemoveFinishedProcessInstances 设置流程结束或终止后是否从运行表删除流程实例，让 process_instance 表保持精简
+// 删除后 GetProcessInstanceById 查不到，需要用 HistoryService.GetHistoricProcessInstanceById 查询
+func SetRemoveFinishedProcessInstances(remove bool) {
+	removeFinishedProcessInstances.Store(remove)
+}
+
+// 流程实例已经记录了最终状态和结束时间，复制到历史表，按配置从运行表删除
+func archiveProcessInstance(runtimeService RuntimeService, tx *sql.Tx, processInstanceId int, endExecutionId string) error {
+	historyService := GetServiceFactory().GetHistoryService()
+	if err := historyService.ArchiveProcessInstance(tx, processInstanceId, endExecutionId); err != nil {
+		return err
+	}
+	if removeFinishedProcessInstances.Load() {
+		return runtimeService.DeleteProcessInstance(tx, processInstanceId)
+	}
+	return nil
+}
+
+// 挂起和恢复 只有处于 from 状态的流程实例才能改成 to 状态
+func changeProcessInstanceStatus(runtimeService RuntimeService, processInstanceId int, from string, to string) error {
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	instance, err := lockProcessInstance(runtimeService, tx, processInstanceId)
+	if err != nil {
+		return finishTransaction(tx, err)
+	}
+	if instance.Status != from {
+		return finishTransaction(tx, fmt.Errorf("%w: id %d status %s, expected %s", ErrProcessNotRunning, processInstanceId, instance.Status, from))
+	}
+	if err := runtimeService.UpdateProcessInstanceStatus(tx, processInstanceId, to); err != nil {
+		return finishTransaction(tx, fmt.Errorf("%w: %v", ErrPersistenceFailed, err))
+	}
+	return finishTransaction(tx, nil)
+}
+
+// 挂起或者故障的流程实例暂停推进 定时任务和异步任务等恢复之后再处理
+func processInstancePaused(status string) bool {
+	return status == PROCESS_STATUS_SUSPENDED || status == PROCESS_STATUS_INCIDENT
+}
+
+// 推进流程时没有可以走的出线 事务已经回滚，在新的事务里把流程实例标记为故障，返回原来的错误
+// 启动流程时不调用 流程实例本身也在回滚的事务里
+// 其他错误原样返回
+func raiseIncident(runtimeService RuntimeService, processInstanceId int, cause error) error {
+	if !errors.Is(cause, ErrNoMatchingFlow) {
+		return cause
+	}
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err))
+	}
+	marked, err := raiseIncidentInTx(runtimeService, tx, processInstanceId)
+	if err := finishTransaction(tx, err); err != nil {
+		return errors.Join(cause, err)
+	}
+	if marked {
+		log.Printf("Process instance %d marked as incident: %v", processInstanceId, cause)
+	}
+	return cause
+}
+
+// 只有运行中的流程实例才标记 已经被挂起或者终止的保持原状
+func raiseIncidentInTx(runtimeService RuntimeService, tx *sql.Tx, processInstanceId int) (bool, error) {
+	instance, err := lockProcessInstance(runtimeService, tx, processInstanceId)
+	if err != nil {
+		return false, err
+	}
+	if instance.Status != PROCESS_STATUS_RUNNING {
+		return false, nil
+	}
+	if err := runtimeService.UpdateProcessInstanceStatus(tx, processInstanceId, PROCESS_STATUS_INCIDENT); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return true, nil
+}
+
+func lockProcessInstance(runtimeService RuntimeService, tx *sql.Tx, processInstanceId int) (*ProcessInstance, error) {
+	instance, err := runtimeService.LockProcessInstance(tx, processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance == nil {
+		return nil, fmt.Errorf("%w: id %d", ErrProcessInstanceNotFound, processInstanceId)
+	}
+	return instance, nil
+}
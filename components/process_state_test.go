@@ -0,0 +1,103 @@
+package components
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 异步服务任务之后是带转派定时器的审批节点
+const suspendXML = `<Process name="suspendCheck">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <ServiceTask executionId="sv" name="SV" handler="asyncTestHandler" async="true"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></ServiceTask>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="susp-alice"><Incoming>f2</Incoming><Outgoing>f3</Outgoing>
+    <BoundaryTimer executionId="t_reassign" duration="1h" action="reassign" assigneeType="ByAssigneeName" assigneeKey="susp-bob"/>
+  </Task>
+  <EndEvent executionId="e"><Incoming>f3</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="sv"/>
+  <SequenceFlow executionId="f2" sourceRef="sv" targetRef="t"/>
+  <SequenceFlow executionId="f3" sourceRef="t" targetRef="e"/>
+</Process>`
+
+// 挂起期间不能完成审批节点 异步任务和定时任务都不执行，恢复之后照常推进
+func TestSuspendedProcessDoesNotAdvance(t *testing.T) {
+	clock := useManualClock(t)
+	deployXML(t, "suspendCheck", []byte(suspendXML))
+	id := startProcess(t, "suspendCheck", "susp-ann", "")
+	terminateOnCleanup(t, id)
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	executor := NewAsyncExecutor(1, time.Hour)
+	scheduler := NewTimerScheduler(time.Hour)
+
+	if err := runtimeService.SuspendProcessInstance(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtimeService.SuspendProcessInstance(id); !errors.Is(err, ErrProcessNotRunning) {
+		t.Fatalf("suspend twice error = %v", err)
+	}
+	if done, err := executor.RunDueJobs(); done != 0 || err != nil {
+		t.Fatalf("async run while suspended = %d %v", done, err)
+	}
+	if findActiveNode(t, id, "t") != nil {
+		t.Fatal("async job ran while suspended")
+	}
+
+	if err := runtimeService.ResumeProcessInstance(id); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := executor.RunDueJobs(); done != 1 || err != nil {
+		t.Fatalf("async run after resume = %d %v", done, err)
+	}
+	task := activeTask(t, id, "t")
+
+	if err := runtimeService.SuspendProcessInstance(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runtimeService.CompleteTask(task.Id, "susp-alice", nil); !errors.Is(err, ErrProcessSuspended) {
+		t.Fatalf("complete while suspended error = %v", err)
+	}
+	clock.advance(2 * time.Hour)
+	if done, err := scheduler.RunDueTimers(); done != 0 || err != nil {
+		t.Fatalf("timer run while suspended = %d %v", done, err)
+	}
+	if assignee := activeTask(t, id, "t").Assignee; assignee != "susp-alice" {
+		t.Fatalf("assignee while suspended = %s", assignee)
+	}
+
+	if err := runtimeService.ResumeProcessInstance(id); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := scheduler.RunDueTimers(); done != 1 || err != nil {
+		t.Fatalf("timer run after resume = %d %v", done, err)
+	}
+	if assignee := activeTask(t, id, "t").Assignee; assignee != "susp-bob" {
+		t.Fatalf("assignee after resume = %s", assignee)
+	}
+	completeTaskAs(t, task.Id, "susp-bob", nil)
+	if status := historicProcessStatus(t, id); status != PROCESS_STATUS_COMPLETE {
+		t.Fatalf("status = %s", status)
+	}
+}
+
+func TestSuspendRequiresRunningProcess(t *testing.T) {
+	deployXML(t, "suspendFinished", []byte(commentXML))
+	runtimeService := GetServiceFactory().GetRuntimeService()
+
+	running := startProcess(t, "suspendFinished", "susp-ann", "")
+	terminateOnCleanup(t, running)
+	if err := runtimeService.ResumeProcessInstance(running); !errors.Is(err, ErrProcessNotRunning) {
+		t.Fatalf("resume of running instance error = %v", err)
+	}
+
+	finished := startProcess(t, "suspendFinished", "susp-ann", "")
+	completeTaskAs(t, activeTask(t, finished, "t0").Id, "cmt-user", nil)
+	terminated := startProcess(t, "suspendFinished", "susp-ann", "")
+	if err := runtimeService.TerminateProcessInstance(terminated, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{finished, terminated} {
+		if err := runtimeService.SuspendProcessInstance(id); !errors.Is(err, ErrProcessNotRunning) {
+			t.Errorf("suspend of %s instance error = %v", historicProcessStatus(t, id), err)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package components
 
 import (
 	"database/sql"
+	"fmt"
+	"slices"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -20,12 +22,28 @@ type ProcessDefinition struct {
 }
 
 // RepositoryService 提供了操作流程定义表的接口
+// 模型缓存取模型时会核对流程定义的内容，保存、更新、删除流程定义的事务可以用任何方式提交或者回滚
 type RepositoryService interface {
 	SaveProcessDefinition(tx *sql.Tx, pd *ProcessDefinition) (int, error)
 	GetProcessDefinitionById(id int) (*ProcessDefinition, error)
 	GetProcessDefinitionByNameAndVersion(name string, version int) (*ProcessDefinition, error)
 	GetLatestProcessDefinitionByName(name string) (*ProcessDefinition, error)
+	GetLatestVersionByName(name string) (int, error)
 	UpdateProcessDefinition(tx *sql.Tx, pd *ProcessDefinition) error
 	DeleteProcessDefinition(tx *sql.Tx, id int) error
 	GetTransaction() (*sql.Tx, error)
 }
+
+// 部署前校验流程定义 xml必须能解析，结构必须完整，引用的监听必须已经注册，只有服务节点可以配置 async
+// 校验不通过时返回 *ModelValidationError
+func validateProcessDefinitionXML(xmlContent []byte) error {
+	model, err := ParseXMLByte(xmlContent)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	problems := slices.Concat(ValidateModel(model), listenerProblems(model), handlerProblems(model), expressionProblems(model), assigneeProblems(model), formProblems(model), asyncProblems(xmlContent))
+	if len(problems) > 0 {
+		return &ModelValidationError{ProcessDefinitionName: model.ProcessDefinitionName, Problems: problems}
+	}
+	return nil
+}
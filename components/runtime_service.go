@@ -2,6 +2,9 @@ package components
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
 	"time"
 )
 
@@ -19,7 +22,204 @@ type ProcessInstance struct {
 
 // RuntimeService 提供了操作流程实例的接口
 type RuntimeService interface {
+	//启动流程并推进到第一批等待节点，成功时提交传入的事务，失败时回滚并返回节点执行的错误
+	//启动时网关没有可以走的出线也是直接失败 返回 ErrNoMatchingFlow，不会留下故障状态的流程实例，调用方修正表单数据后重新启动
 	StartProcessInstance(tx *sql.Tx, ProcessDefinitionName string, Business_key string, createdBy string, formParams string) (int, error)
 	CompleteProcessInstance(tx *sql.Tx, ProcessInstanceId int) error
+	//根据id查询流程实例 不存在时返回 nil
+	GetProcessInstanceById(id int) (*ProcessInstance, error)
+	//在事务里读取并锁住流程实例 不存在时返回 nil
+	LockProcessInstance(tx *sql.Tx, id int) (*ProcessInstance, error)
+	//删除流程实例 流程结束归档之后按配置调用
+	DeleteProcessInstance(tx *sql.Tx, id int) error
+	//修改流程实例状态 完成和终止时同时记录结束时间
+	UpdateProcessInstanceStatus(tx *sql.Tx, id int, status string) error
+	//终止流程实例 未完成的审批节点带着原因迁移到历史表，自己管理事务
+	TerminateProcessInstance(processInstanceId int, reason string) error
+	//挂起流程实例 挂起期间不能完成或者打回审批节点
+	SuspendProcessInstance(processInstanceId int) error
+	//恢复挂起的流程实例
+	ResumeProcessInstance(processInstanceId int) error
+	//设置流程变量 scope 为空时是流程实例作用域，分支作用域是分支出线的结构id，节点作用域是节点的结构id，流程结束之后不能再修改
+	SetVariable(processInstanceId int, scope string, name string, value any, userId string) error
+	//查询某个作用域的全部流程变量
+	GetVariables(processInstanceId int, scope string) (map[string]any, error)
+	//处理完故障之后恢复流程实例 比如修正了数据或者流程定义，之后可以重新完成审批节点
+	ResolveIncident(processInstanceId int) error
+	//候选人认领审批节点 同一个审批节点只有一个人能认领成功，自己认领过的再认领直接返回
+	ClaimTask(taskId int, userId string) error
+	//认领人把审批节点退回给候选人
+	UnclaimTask(taskId int, userId string) error
+	//用户的待办 包括分配给用户的和用户或者用户所在的组可以认领的审批节点
+	GetUserTasks(userId string) ([]map[string]interface{}, error)
+	//用户的待办按到期时间排序 最早到期的在前，没有到期时间的排在最后
+	GetUserTasksByDueDate(userId string) ([]map[string]interface{}, error)
+	//负责人把审批节点委派给另一个人 委派的人处理完之后回到负责人，委派中不能完成
+	DelegateTask(taskId int, userId string, delegate string, reason string) error
+	//委派的人处理完 审批节点回到委派人
+	ResolveTask(taskId int, userId string, reason string) error
+	//负责人把审批节点转办给另一个人 不再回到自己
+	TransferTask(taskId int, userId string, assignee string, reason string) error
+	//负责人加签 position 是 SIGNER_POSITION_BEFORE 或者 SIGNER_POSITION_AFTER，两个人都审批完流程才往下走
+	AddSigner(taskId int, userId string, signer string, position string, reason string) error
+	//在流程实例或者审批节点上评论 taskId 为 0 时是流程实例上的评论，流程结束之后也可以评论
+	AddComment(processInstanceId int, taskId int, userId string, message string) (int, error)
+	//查询评论 taskId 为 0 时返回流程实例的全部评论，按时间顺序
+	ListComments(processInstanceId int, taskId int) ([]Comment, error)
+	//记录附件的元数据 文件本身由业务服务存储
+	AddAttachment(attachment Attachment) (int, error)
+	//查询附件 taskId 为 0 时返回流程实例的全部附件
+	ListAttachments(processInstanceId int, taskId int) ([]Attachment, error)
+	//负责人完成审批节点 自己管理事务 返回流程推进后新创建的审批节点
+	CompleteTask(taskId int, userId string, output map[string]any) ([]NodeInstance, error)
+	//把审批节点打回到上游的某个审批节点 取消下游所有未完成的审批节点，返回重新激活的审批节点
+	SendBack(taskId int, targetExecutionId string, comment string) ([]NodeInstance, error)
 	GetTransaction() (*sql.Tx, error)
 }
+
+// 找到模型的开始节点 构造上下文后驱动流程往下执行，各数据库实现插入流程实例记录后调用
+func executeStartEvent(tx *sql.Tx, model *Model, processInstanceId int, processDefinitionName string, createdBy string, formParams string) error {
+	//找到开始节点
+	startEvent := model.StartEvents
+	if len(startEvent) == 0 {
+		return newExecutionError(processDefinitionName, ErrNodeNotFound, fmt.Errorf("process has no start event"))
+	}
+	var startEventElement StartEvent
+	//目前只有一个startEvent 以后不知道会不会扩展为多个
+	for key := range startEvent {
+		startEventElement = startEvent[key]
+	}
+	var ctx = &WorkflowContext{
+		Model:                 model,
+		ProcessInstanceId:     processInstanceId,
+		ProcessDefinitionName: processDefinitionName,
+		// Version:               model.Version,
+		// BusinessKey:           business_key,
+		CurrentUserId: createdBy,
+		Data:          formParams,
+		StartTime:     time.Now(),
+		Tx:            tx,
+	}
+
+	return startEventElement.Execute(ctx)
+}
+
+// FinishTransaction 提交或者回滚调用方开启的事务 err 不为空时回滚并原样返回
+func FinishTransaction(tx *sql.Tx, err error) error {
+	return finishTransaction(tx, err)
+}
+
+// 流程推进结束后统一提交或回滚事务
+func finishTransaction(tx *sql.Tx, err error) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Println("Failed to rollback transaction: ", rollbackErr)
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return fmt.Errorf("%w: failed to commit transaction: %v", ErrPersistenceFailed, commitErr)
+	}
+	return nil
+}
+
+// 各数据库实现共用的完成审批节点的逻辑
+// 在事务里锁住节点实例 校验负责人，按流程实例启动时的版本加载模型，保存输出后推进流程
+func completeTask(runtimeService RuntimeService, taskId int, userId string, output map[string]any) ([]NodeInstance, error) {
+	nodeService := GetServiceFactory().GetNodeService()
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+
+	ctx, node, err := prepareTaskCompletion(runtimeService, nodeService, tx, taskId, userId)
+	if err != nil {
+		return nil, finishTransaction(tx, err)
+	}
+
+	dataBytes, err := json.Marshal(output)
+	if err != nil {
+		return nil, finishTransaction(tx, fmt.Errorf("%w: failed to marshal task output: %v", ErrInvalidInput, err))
+	}
+	task := ctx.Model.Tasks[ctx.CurrentExecutionId]
+	//校验不通过时不完成审批节点 避免不合法的数据进入网关条件
+	if err := validateFormData(task.ExecutionId, task.FormData, string(dataBytes)); err != nil {
+		return nil, finishTransaction(tx, err)
+	}
+	if node.DelegationState == TASK_DELEGATION_SIGNING {
+		return nil, finishTransaction(tx, passSignedTask(tx, node, string(dataBytes)))
+	}
+	if err := finishTransaction(tx, task.completeNode(ctx, taskId, string(dataBytes))); err != nil {
+		return nil, raiseIncident(runtimeService, ctx.ProcessInstanceId, err)
+	}
+	return ctx.NewTasks, nil
+}
+
+// 校验审批节点可以被当前用户完成 并构造推进流程用的上下文
+func prepareTaskCompletion(runtimeService RuntimeService, nodeService NodeService, tx *sql.Tx, taskId int, userId string) (*WorkflowContext, *NodeInstance, error) {
+	node, instance, model, err := lockActiveTask(runtimeService, nodeService, tx, taskId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if node.Assignee != userId {
+		return nil, nil, fmt.Errorf("%w: task %d is assigned to %s", ErrNotTaskAssignee, taskId, node.Assignee)
+	}
+	if node.DelegationState == TASK_DELEGATION_PENDING {
+		return nil, nil, fmt.Errorf("%w: task %d is delegated by %s and must be resolved first", ErrTaskDelegated, taskId, node.Owner)
+	}
+
+	return &WorkflowContext{
+		Model:                 model,
+		ProcessInstanceId:     node.ProcessInstanceId,
+		ProcessDefinitionName: node.ProcessDefinitionName,
+		CurrentUserId:         userId,
+		CurrentExecutionId:    node.ExecutionId,
+		StartTime:             instance.StartTime,
+		Tx:                    tx,
+	}, node, nil
+}
+
+// 在事务里锁住还没有完成的审批节点，按流程实例启动时的版本加载模型
+// 先锁流程实例再锁节点，和终止、挂起流程实例的加锁顺序一致，挂起的流程实例不允许操作
+func lockActiveTask(runtimeService RuntimeService, nodeService NodeService, tx *sql.Tx, taskId int) (*NodeInstance, *ProcessInstance, *Model, error) {
+	existing, err := nodeService.GetNodeInstanceById(taskId)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if existing == nil {
+		return nil, nil, nil, fmt.Errorf("%w: id %d", ErrTaskNotFound, taskId)
+	}
+	instance, err := runtimeService.LockProcessInstance(tx, existing.ProcessInstanceId)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance == nil {
+		return nil, nil, nil, fmt.Errorf("%w: process instance %d of task %d", ErrTaskNotFound, existing.ProcessInstanceId, taskId)
+	}
+	if instance.Status == PROCESS_STATUS_SUSPENDED {
+		return nil, nil, nil, fmt.Errorf("%w: id %d", ErrProcessSuspended, instance.Id)
+	}
+	if instance.Status == PROCESS_STATUS_INCIDENT {
+		return nil, nil, nil, fmt.Errorf("%w: id %d", ErrProcessIncident, instance.Id)
+	}
+
+	node, err := nodeService.LockNodeInstance(tx, taskId)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if node == nil {
+		return nil, nil, nil, fmt.Errorf("%w: id %d", ErrTaskNotFound, taskId)
+	}
+	if node.OutputData != "" {
+		return nil, nil, nil, fmt.Errorf("%w: id %d", ErrTaskAlreadyCompleted, taskId)
+	}
+
+	model, err := getModelByVersion(node.ProcessDefinitionName, instance.Version)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if _, ok := model.Tasks[node.ExecutionId]; !ok {
+		return nil, nil, nil, fmt.Errorf("%w: node %d (%s) is not a task", ErrTaskNotFound, taskId, node.ExecutionId)
+	}
+	return node, instance, model, nil
+}
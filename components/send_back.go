@@ -0,0 +1,155 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+)
+
+// 各数据库实现共用的打回逻辑
+// 打回目标必须是当前审批节点上游已经完成的审批节点，打回时：
+// 1. 目标节点之后产生的、结构上在目标节点下游的节点实例全部删除，未完成的审批节点（包括并行的兄弟分支）记录到历史表
+// 2. 汇聚网关只删除从下游分支到达的记录，其他分支已经到达的记录保留，目标节点重新走到网关时计数依然正确
+// 3. 目标节点重新执行，生成新的审批节点
+func sendBack(runtimeService RuntimeService, taskId int, targetExecutionId string, comment string) ([]NodeInstance, error) {
+	nodeService := GetServiceFactory().GetNodeService()
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+
+	ctx, err := sendBackInTx(runtimeService, nodeService, tx, taskId, targetExecutionId, comment)
+	if err := finishTransaction(tx, err); err != nil {
+		return nil, err
+	}
+	return ctx.NewTasks, nil
+}
+
+func sendBackInTx(runtimeService RuntimeService, nodeService NodeService, tx *sql.Tx, taskId int, targetExecutionId string, comment string) (*WorkflowContext, error) {
+	node, instance, model, err := lockActiveTask(runtimeService, nodeService, tx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := model.Tasks[targetExecutionId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a task of process %s", ErrInvalidSendBack, targetExecutionId, node.ProcessDefinitionName)
+	}
+	downstream := downstreamElements(model, targetExecutionId)
+	if !downstream[node.ExecutionId] {
+		return nil, fmt.Errorf("%w: %s is not upstream of %s", ErrInvalidSendBack, targetExecutionId, node.ExecutionId)
+	}
+
+	nodes, err := nodeService.LockNodeInstancesByProcessInstanceId(tx, node.ProcessInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	// 本轮里目标节点最近一次完成的记录
+	var targetNode *NodeInstance
+	for i := range nodes {
+		if nodes[i].ExecutionId == targetExecutionId && nodes[i].OutputData != "" && nodes[i].Id < node.Id {
+			targetNode = &nodes[i]
+		}
+	}
+	if targetNode == nil {
+		return nil, fmt.Errorf("%w: %s has not been completed before task %d", ErrInvalidSendBack, targetExecutionId, taskId)
+	}
+
+	historyService := GetServiceFactory().GetHistoryService()
+	jobService := GetServiceFactory().GetJobService()
+	// 被删除的已完成节点 它们作用域的变量也要删除，否则条件表达式会读到打回之前的数据
+	clearedScopes := []string{targetExecutionId}
+	for _, current := range nodes {
+		if current.Id <= targetNode.Id || !downstream[current.ExecutionId] {
+			continue
+		}
+		gateway := model.AllData[current.ExecutionId]
+		if incoming, _ := nodeFlows(gateway); isPairedGateway(gateway) && len(incoming) > 1 &&
+			!downstream[current.PreviousExecutionId] && current.PreviousExecutionId != targetExecutionId {
+			continue
+		}
+		if _, isTask := model.Tasks[current.ExecutionId]; isTask && current.OutputData == "" {
+			status := NODE_STATUS_CANCELLED
+			if current.Id == node.Id {
+				status = NODE_STATUS_SENT_BACK
+			}
+			if err := historyService.ArchiveNodeInstance(tx, current.Id, status, comment); err != nil {
+				return nil, newExecutionError(current.ExecutionId, ErrPersistenceFailed, err)
+			}
+		}
+		if err := nodeService.DeleteNodeInstance(tx, current.Id); err != nil {
+			return nil, newExecutionError(current.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if current.OutputData != "" && !slices.Contains(clearedScopes, current.ExecutionId) {
+			clearedScopes = append(clearedScopes, current.ExecutionId)
+		}
+		if err := jobService.DeleteTimerJobsByNodeInstance(tx, current.Id); err != nil {
+			return nil, newExecutionError(current.ExecutionId, ErrPersistenceFailed, err)
+		}
+	}
+	// 还没有执行的下游异步节点也一起取消
+	asyncJobs, err := jobService.GetAsyncJobsByProcessInstance(tx, node.ProcessInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	for _, job := range asyncJobs {
+		if downstream[job.ExecutionId] {
+			if err := jobService.DeleteAsyncJob(tx, job.Id); err != nil {
+				return nil, newExecutionError(job.ExecutionId, ErrPersistenceFailed, err)
+			}
+		}
+	}
+	// 目标节点已经在历史表里了，运行表里只保留重新激活的记录 多实例的审批节点其他负责人完成的记录也一起删除
+	for _, current := range nodes {
+		if current.ExecutionId != targetExecutionId || current.OutputData == "" || current.Id > targetNode.Id {
+			continue
+		}
+		if current.Id == targetNode.Id || target.MultiInstance != nil {
+			if err := nodeService.DeleteNodeInstance(tx, current.Id); err != nil {
+				return nil, newExecutionError(targetExecutionId, ErrPersistenceFailed, err)
+			}
+		}
+	}
+	variableService := GetServiceFactory().GetVariableService()
+	for _, scope := range clearedScopes {
+		if err := variableService.DeleteVariablesByScope(tx, node.ProcessInstanceId, scope, node.Assignee); err != nil {
+			return nil, newExecutionError(scope, ErrPersistenceFailed, err)
+		}
+	}
+
+	ctx := &WorkflowContext{
+		Model:                 model,
+		ProcessInstanceId:     node.ProcessInstanceId,
+		ProcessDefinitionName: node.ProcessDefinitionName,
+		CurrentUserId:         node.Assignee,
+		CurrentExecutionId:    targetNode.PreviousExecutionId,
+		StartTime:             instance.StartTime,
+		Tx:                    tx,
+	}
+	if err := target.Execute(ctx); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// 从某个节点沿着出线能走到的全部节点和序列流，不包含起点本身（除非有环回到起点）
+func downstreamElements(model *Model, executionId string) map[string]bool {
+	visited := make(map[string]bool)
+	queue := []string{executionId}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		_, outgoing := nodeFlows(model.AllData[current])
+		for _, flowId := range outgoing {
+			flow, ok := model.SequenceFlows[flowId]
+			if !ok {
+				continue
+			}
+			visited[flowId] = true
+			if !visited[flow.TargetRef] {
+				visited[flow.TargetRef] = true
+				queue = append(queue, flow.TargetRef)
+			}
+		}
+	}
+	return visited
+}
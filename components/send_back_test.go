@@ -0,0 +1,172 @@
+package components
+
+import (
+	"errors"
+	"testing"
+)
+
+const sendBackLinearXML = `<Process name="sendBackLinear">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t1" name="T1" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <Task executionId="t2" name="T2" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <Task executionId="t3" name="T3" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>f2</Incoming><Outgoing>f3</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f3</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t1"/>
+  <SequenceFlow executionId="f1" sourceRef="t1" targetRef="t2"/>
+  <SequenceFlow executionId="f2" sourceRef="t2" targetRef="t3"/>
+  <SequenceFlow executionId="f3" sourceRef="t3" targetRef="e"/>
+</Process>`
+
+// a 分支有两个审批节点，b 分支一个，汇聚之后是 c
+const sendBackParallelXML = `<Process name="sendBackParallel">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <ParallelGateway executionId="p1"><Incoming>f0</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing></ParallelGateway>
+  <Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing></Task>
+  <Task executionId="a2" name="A2" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fa2</Incoming><Outgoing>fa3</Outgoing></Task>
+  <Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>
+  <ParallelGateway executionId="p2"><Incoming>fa3</Incoming><Incoming>fb2</Incoming><Outgoing>fc</Outgoing></ParallelGateway>
+  <Task executionId="c" name="C" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fc</Incoming><Outgoing>fe</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>fe</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="p1"/>
+  <SequenceFlow executionId="fa" sourceRef="p1" targetRef="a"/>
+  <SequenceFlow executionId="fb" sourceRef="p1" targetRef="b"/>
+  <SequenceFlow executionId="fa2" sourceRef="a" targetRef="a2"/>
+  <SequenceFlow executionId="fa3" sourceRef="a2" targetRef="p2"/>
+  <SequenceFlow executionId="fb2" sourceRef="b" targetRef="p2"/>
+  <SequenceFlow executionId="fc" sourceRef="p2" targetRef="c"/>
+  <SequenceFlow executionId="fe" sourceRef="c" targetRef="e"/>
+</Process>`
+
+func TestSendBackReactivatesUpstreamTask(t *testing.T) {
+	deployXML(t, "sendBackLinear", []byte(sendBackLinearXML))
+	id := startProcess(t, "sendBackLinear", "sb-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t1").Id, "sb-user", map[string]any{"amount": 1})
+	completeTaskAs(t, activeTask(t, id, "t2").Id, "sb-user", map[string]any{"approved": true})
+	t3 := activeTask(t, id, "t3")
+
+	tasks, err := GetServiceFactory().GetRuntimeService().SendBack(t3.Id, "t1", "missing receipts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ExecutionId != "t1" {
+		t.Fatalf("reactivated tasks = %+v", tasks)
+	}
+	if findActiveNode(t, id, "t3") != nil {
+		t.Fatal("sent back task is still active")
+	}
+	if status, comment := historicNodeStatus(t, t3.Id); status != NODE_STATUS_SENT_BACK || comment != "missing receipts" {
+		t.Fatalf("history of sent back task = %s %q", status, comment)
+	}
+
+	// 重新走一遍 t2 需要再次审批
+	completeTaskAs(t, activeTask(t, id, "t1").Id, "sb-user", map[string]any{"amount": 2})
+	completeTaskAs(t, activeTask(t, id, "t2").Id, "sb-user", map[string]any{"approved": true})
+	completeTaskAs(t, activeTask(t, id, "t3").Id, "sb-user", nil)
+	if findActiveNode(t, id, "t3") != nil {
+		t.Fatal("t3 is still active after completion")
+	}
+}
+
+func TestSendBackRejectsInvalidTargets(t *testing.T) {
+	deployXML(t, "sendBackLinear", []byte(sendBackLinearXML))
+	id := startProcess(t, "sendBackLinear", "sb-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t1").Id, "sb-user", nil)
+	t2 := activeTask(t, id, "t2")
+
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	for _, target := range []string{"t3", "e", "missing", "t2"} {
+		if _, err := runtimeService.SendBack(t2.Id, target, ""); !errors.Is(err, ErrInvalidSendBack) {
+			t.Errorf("send back to %s error = %v", target, err)
+		}
+	}
+	// 失败的打回不影响当前审批节点
+	if activeTask(t, id, "t2").Id != t2.Id {
+		t.Fatal("active task changed after failed send back")
+	}
+}
+
+// 打回一个分支内部的审批节点 另一个分支在汇聚网关的到达记录保留
+func TestSendBackInsideParallelBranchKeepsJoinCount(t *testing.T) {
+	deployXML(t, "sendBackParallel", []byte(sendBackParallelXML))
+	id := startProcess(t, "sendBackParallel", "sb-ann", "")
+	completeTaskAs(t, activeTask(t, id, "b").Id, "sb-user", nil)
+	completeTaskAs(t, activeTask(t, id, "a").Id, "sb-user", nil)
+
+	if _, err := GetServiceFactory().GetRuntimeService().SendBack(activeTask(t, id, "a2").Id, "a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if findActiveNode(t, id, "b") != nil {
+		t.Fatal("completed sibling branch was reactivated")
+	}
+	completeTaskAs(t, activeTask(t, id, "a").Id, "sb-user", nil)
+	completeTaskAs(t, activeTask(t, id, "a2").Id, "sb-user", nil)
+	activeTask(t, id, "c")
+}
+
+const sendBackSplitXML = `<Process name="sendBackSplit">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <ParallelGateway executionId="p1"><Incoming>f1</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing></ParallelGateway>
+  <Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing></Task>
+  <Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>
+  <ParallelGateway executionId="p2"><Incoming>fa2</Incoming><Incoming>fb2</Incoming><Outgoing>fc</Outgoing></ParallelGateway>
+  <Task executionId="c" name="C" assigneeType="ByAssigneeName" assigneeKey="sb-user"><Incoming>fc</Incoming><Outgoing>fe</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>fe</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="p1"/>
+  <SequenceFlow executionId="fa" sourceRef="p1" targetRef="a"/>
+  <SequenceFlow executionId="fb" sourceRef="p1" targetRef="b"/>
+  <SequenceFlow executionId="fa2" sourceRef="a" targetRef="p2"/>
+  <SequenceFlow executionId="fb2" sourceRef="b" targetRef="p2"/>
+  <SequenceFlow executionId="fc" sourceRef="p2" targetRef="c"/>
+  <SequenceFlow executionId="fe" sourceRef="c" targetRef="e"/>
+</Process>`
+
+// 打回一个分支的审批节点到分叉之前 并行的兄弟分支一起取消
+func TestSendBackBeforeSplitCancelsSiblingBranch(t *testing.T) {
+	deployXML(t, "sendBackSplit", []byte(sendBackSplitXML))
+	id := startProcess(t, "sendBackSplit", "sb-ann", "")
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "sb-user", nil)
+	a := activeTask(t, id, "a")
+	b := activeTask(t, id, "b")
+
+	if _, err := GetServiceFactory().GetRuntimeService().SendBack(a.Id, "t0", ""); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := historicNodeStatus(t, b.Id); status != NODE_STATUS_CANCELLED {
+		t.Fatalf("sibling branch status = %s", status)
+	}
+	if findActiveNode(t, id, "a") != nil || findActiveNode(t, id, "b") != nil {
+		t.Fatal("branches are still active after send back")
+	}
+
+	// 重新分叉之后汇聚网关要等两个分支都重新到达
+	completeTaskAs(t, activeTask(t, id, "t0").Id, "sb-user", nil)
+	completeTaskAs(t, activeTask(t, id, "a").Id, "sb-user", nil)
+	if findActiveNode(t, id, "c") != nil {
+		t.Fatal("join fired before both branches arrived again")
+	}
+	completeTaskAs(t, activeTask(t, id, "b").Id, "sb-user", nil)
+	activeTask(t, id, "c")
+}
+
+// 内存库里历史节点实例的状态和备注
+func historicNodeStatus(t *testing.T, id int) (string, string) {
+	t.Helper()
+	var (
+		row   memoryNodeRow
+		found bool
+	)
+	historyService := GetServiceFactory().GetHistoryService().(*MemoryHistoryService)
+	err := memoryQuery(historyService.DB, func(data *memoryData) error {
+		row, found = data.historicNodeInstances[id]
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatalf("historic node instance %d not found", id)
+	}
+	return row.Status, row.Comment
+}
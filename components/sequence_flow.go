@@ -19,47 +19,49 @@ type SequenceFlow struct {
 	Listener    string `xml:"Listener"` // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
 }
 
-func (sequenceFlow SequenceFlow) Execute(ctx *WorkflowContext) {
-	//没表达式 直接过
+func (sequenceFlow SequenceFlow) Execute(ctx *WorkflowContext) error {
+	pass, err := sequenceFlow.Evaluate(ctx)
+	if err != nil || !pass {
+		return err
+	}
+	return sequenceFlow.Take(ctx)
+}
+
+// Evaluate 根据表达式判断 这个序列流是否往下走，没表达式 直接过
+func (sequenceFlow SequenceFlow) Evaluate(ctx *WorkflowContext) (bool, error) {
 	if strings.Trim(sequenceFlow.Expression, " ") == "" {
-		exec := ctx.Model.AllData[sequenceFlow.TargetRef]
-		exec.Execute(ctx)
-		return
+		return true, nil
 	}
 
-	//根据表达式判断 这个序列流是否往下走
 	//startEvent.leaveType== 'Sick Leave'
 	attributes := ExtractAttributes(sequenceFlow.Expression)
 	log.Println("Extracted attributes:", attributes) // 输出 ["data.value", "data.status"]
 	// 为表达式中的变量赋值
-	nodeService := GetServiceFactory().GetNodeService()
-	parameters, err1 := nodeService.GetAttributeByExpression(ctx.Tx, sequenceFlow.Expression, ctx.ProcessInstanceId)
-
+	parameters, err1 := resolveExpressionParameters(ctx, sequenceFlow.Expression)
 	if err1 != nil {
-		log.Println("Failed to get attribute:", err1)
-		return
+		return false, newExecutionError(sequenceFlow.ExecutionId, ErrExpressionFailed, err1)
 	}
 
 	result, err := EvaluateExpression(sequenceFlow.Expression, parameters)
 	if err != nil {
-		log.Println("Failed to parse expression:", err)
-		return
+		return false, newExecutionError(sequenceFlow.ExecutionId, ErrExpressionFailed, err)
 	}
 
 	// 判断 result 是否为布尔类型
-	if boolResult, ok := result.(bool); ok {
-		log.Println("Result is a boolean:", boolResult)
-		if boolResult {
-			//条件满足 继续走
-			//执行监听
-			RunListener(sequenceFlow.Listener, ctx)
-			//下一步
-			exec := ctx.Model.AllData[sequenceFlow.TargetRef]
-			exec.Execute(ctx)
-		} else {
-			return
-		}
-	} else {
-		fmt.Printf("Result is not a boolean, it is of type %T with value %v\n", result, result)
+	boolResult, ok := result.(bool)
+	if !ok {
+		return false, newExecutionError(sequenceFlow.ExecutionId, ErrExpressionFailed, fmt.Errorf("result is not a boolean, it is of type %T with value %v", result, result))
+	}
+	log.Println("Result is a boolean:", boolResult)
+	return boolResult, nil
+}
+
+// Take 条件满足 执行监听后进入目标节点
+func (sequenceFlow SequenceFlow) Take(ctx *WorkflowContext) error {
+	//执行监听
+	if listenerErr := RunListener(sequenceFlow.Listener, ctx); listenerErr != nil {
+		return newExecutionError(sequenceFlow.ExecutionId, ErrListenerFailed, listenerErr)
 	}
+	//下一步
+	return executeNode(ctx, sequenceFlow.TargetRef)
 }
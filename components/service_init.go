@@ -7,16 +7,16 @@ import (
 	"sync"
 
 	_ "github.com/go-sql-driver/mysql" // 假设使用 MySQL 数据库驱动
+	_ "github.com/lib/pq"              // PostgreSQL 数据库驱动
 )
 
 func Init(db *sql.DB, dbtype string) {
 	InitializeServiceFactory(dbtype, db)
 
 	log.Println("All services have been successfully initialized based on the database type.")
-	// 建造一个单例的 model 容器，给流程模型存到缓存里 根据定义的名称
-	initModelMap()
-	// 存缓存的流程定义 只需要按照名称 不管版本 ,因为需求里，流程定义数据可能会随时更新的！
-	// 需要通过改缓存中的节点信息 从而让剩下的未执行的节点被影响！
+	// 流程模型存在并发安全的 LRU 缓存里 根据定义的名称和版本，容量用 SetModelCacheCapacity 调整
+	// 流程实例按启动时的版本继续流转，部署新版本不会影响已经启动的实例
+	// 流程定义数据可能会随时更新，更新时只清掉对应版本的缓存，剩下的未执行的节点重新加载后就会受影响
 	// 因为只开放节点的部分信息修改 不开放结构修改 所以应该是安全的
 }
 
@@ -26,6 +26,10 @@ type ServiceFactory interface {
 	GetRepositoryService() RepositoryService
 	GetNodeService() NodeService
 	GetHistoryService() HistoryService
+	GetJobService() JobService
+	GetVariableService() VariableService
+	GetDelegationRuleService() DelegationRuleService
+	GetCommentService() CommentService
 }
 
 var (
@@ -38,7 +42,16 @@ func InitializeServiceFactory(dbtype string, db *sql.DB) {
 		switch dbtype {
 		case MYSQL_DBNAME:
 			serviceFactoryInstance = &MySQLServiceFactory{}
-		// 未来如果添加其他数据库类型
+		case POSTGRES_DBNAME:
+			serviceFactoryInstance = &PostgresServiceFactory{}
+		case SQLITE_DBNAME:
+			// sqlite 建议连接串带上 _txlock=immediate，让写事务一开始就拿到写锁，并行网关汇聚计数才是串行的
+			// 驱动只在开启 cgo 时随包注册 见 sqlite_driver.go
+			serviceFactoryInstance = &SQLiteServiceFactory{}
+		case MEMORY_DBNAME:
+			// 内存实现 不需要外部数据库 db 传 nil 即可
+			serviceFactoryInstance = &MemoryServiceFactory{}
+		// 未来如果添加其他数据库类型 在 sql_dialect.go 里补充方言
 		// case "oracle":
 		//     serviceFactoryInstance = &OracleServiceFactory{}
 		default:
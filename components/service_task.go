@@ -0,0 +1,196 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// ServiceTask 服务节点 流程走到这里时自动调用注册的处理函数，处理结果作为节点的输出数据后直接往下走
+type ServiceTask struct {
+	ExecutionId   string   `xml:"executionId,attr"`
+	Name          string   `xml:"name,attr"`
+	Handler       string   `xml:"handler,attr"` // 处理函数的注册名称
+	Retries       int      `xml:"retries,attr"` // 处理函数失败后立即重试的次数 默认不重试
+	Async         bool     `xml:"async,attr"`   // 为 true 时不在当前事务里执行，交给异步执行器，失败后按退避时间重试
+	Incoming      []string `xml:"Incoming"`
+	Outgoing      []string `xml:"Outgoing"`
+	ErrorOutgoing []string `xml:"ErrorOutgoing"` // 重试后依然失败时走的序列流 没有配置时整个事务回滚
+	X             string   `xml:"x,attr"`
+	Y             string   `xml:"y,attr"`
+	H             string   `xml:"h,attr"`
+	W             string   `xml:"w,attr"`
+	Listener      string   `xml:"Listener"` // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
+}
+
+// ServiceHandler 服务节点的处理函数 返回值保存为节点的输出数据
+// variables 是流程实例当前的变量：流程实例作用域和服务节点所在分支的变量按名称放在第一层，节点作用域的变量按结构id分组（结构id -> 名称 -> 值）
+// 处理函数和流程推进在同一个事务里，耗时很长的调用把节点标记为 async，由异步执行器在单独的事务里执行
+type ServiceHandler func(ctx *WorkflowContext, variables map[string]any) (map[string]any, error)
+
+var (
+	serviceHandlerRegistry = make(map[string]ServiceHandler)
+	serviceHandlerMutex    sync.RWMutex
+)
+
+// RegisterServiceHandler 注册一个具名的服务节点处理函数 业务服务在启动时调用 名称重复或者函数为空直接panic
+func RegisterServiceHandler(name string, handler ServiceHandler) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		panic("service handler name must not be empty")
+	}
+	if handler == nil {
+		panic(fmt.Sprintf("service handler %s must not be nil", name))
+	}
+
+	serviceHandlerMutex.Lock()
+	defer serviceHandlerMutex.Unlock()
+	if _, exists := serviceHandlerRegistry[name]; exists {
+		panic(fmt.Sprintf("service handler %s is already registered", name))
+	}
+	serviceHandlerRegistry[name] = handler
+}
+
+// GetServiceHandler 根据名称查找已注册的处理函数
+func GetServiceHandler(name string) (ServiceHandler, bool) {
+	serviceHandlerMutex.RLock()
+	defer serviceHandlerMutex.RUnlock()
+	handler, ok := serviceHandlerRegistry[strings.TrimSpace(name)]
+	return handler, ok
+}
+
+func (serviceTask ServiceTask) isAsync() bool {
+	return serviceTask.Async
+}
+
+// Execute 调用处理函数 成功时保存输出后走出线，失败时走错误出线
+func (serviceTask ServiceTask) Execute(ctx *WorkflowContext) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	tx := ctx.Tx
+	if tx == nil {
+		return errNilTransaction(serviceTask.ExecutionId)
+	}
+	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, serviceTask.Name, serviceTask.ExecutionId, ctx.CurrentExecutionId, SYSTEM_USER_NOBODY)
+	if initerr != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, initerr)
+	}
+	variables, err := handlerVariables(ctx, serviceTask.ExecutionId)
+	if err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+
+	ctx.CurrentExecutionId = serviceTask.ExecutionId
+	result, handlerErr := serviceTask.invoke(ctx, variables)
+	if handlerErr != nil {
+		if len(serviceTask.ErrorOutgoing) == 0 {
+			return newExecutionError(serviceTask.ExecutionId, ErrHandlerFailed, handlerErr)
+		}
+		return serviceTask.fail(ctx, nodeId, handlerErr)
+	}
+
+	dataBytes, err := json.Marshal(result)
+	if err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrHandlerFailed, fmt.Errorf("failed to marshal handler result: %v", err))
+	}
+	if result == nil {
+		dataBytes = []byte("{}")
+	}
+	if err := nodeService.UpdateNodeInstanceOutput(tx, nodeId, string(dataBytes)); err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+	if err := saveOutputVariables(ctx, serviceTask.ExecutionId, string(dataBytes)); err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+	historyService := GetServiceFactory().GetHistoryService()
+	if err := historyService.CopyNodeInstanceById(tx, nodeId); err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+
+	if listenerErr := RunListener(serviceTask.Listener, ctx); listenerErr != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrListenerFailed, listenerErr)
+	}
+	return serviceTask.takeFlows(ctx, serviceTask.Outgoing)
+}
+
+// 处理函数的输入 从流程变量读取，节点记录被清理或者打回之后和表达式看到的数据一致
+// 服务节点能看到的流程实例和分支作用域的变量放在第一层，节点作用域的变量按结构id分组，和结构id同名时节点作用域优先
+func handlerVariables(ctx *WorkflowContext, executionId string) (map[string]any, error) {
+	result, err := visibleVariables(ctx, executionId)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := GetServiceFactory().GetVariableService().GetAllVariables(ctx.Tx, ctx.ProcessInstanceId)
+	if err != nil {
+		return nil, err
+	}
+	scopes := make(map[string]map[string]any)
+	for _, variable := range variables {
+		if _, isBranch := ctx.Model.BranchParents[variable.Scope]; isBranch || variable.Scope == VARIABLE_SCOPE_PROCESS {
+			continue
+		}
+		scope, ok := scopes[variable.Scope]
+		if !ok {
+			scope = make(map[string]any)
+			scopes[variable.Scope] = scope
+			result[variable.Scope] = scope
+		}
+		scope[variable.Name] = variable.Value
+	}
+	return result, nil
+}
+
+// 调用处理函数 失败时按配置立即重试，处理函数 panic 也当作失败
+func (serviceTask ServiceTask) invoke(ctx *WorkflowContext, variables map[string]any) (map[string]any, error) {
+	handler, ok := GetServiceHandler(serviceTask.Handler)
+	if !ok {
+		return nil, fmt.Errorf("service handler %s is not registered", serviceTask.Handler)
+	}
+	var err error
+	for attempt := 0; attempt <= max(serviceTask.Retries, 0); attempt++ {
+		var result map[string]any
+		result, err = callServiceHandler(handler, ctx, variables)
+		if err == nil {
+			return result, nil
+		}
+		log.Printf("Service handler %s of %s failed (attempt %d): %v", serviceTask.Handler, serviceTask.ExecutionId, attempt+1, err)
+	}
+	return nil, err
+}
+
+func callServiceHandler(handler ServiceHandler, ctx *WorkflowContext, variables map[string]any) (result map[string]any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("service handler panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, variables)
+}
+
+// 处理函数失败 节点以失败状态进历史表，错误信息作为输出数据，走错误出线
+func (serviceTask ServiceTask) fail(ctx *WorkflowContext, nodeId int, handlerErr error) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	dataBytes, _ := json.Marshal(map[string]any{"error": handlerErr.Error()})
+	if err := nodeService.UpdateNodeInstanceOutput(ctx.Tx, nodeId, string(dataBytes)); err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+	if err := saveOutputVariables(ctx, serviceTask.ExecutionId, string(dataBytes)); err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+	historyService := GetServiceFactory().GetHistoryService()
+	if err := historyService.ArchiveNodeInstance(ctx.Tx, nodeId, NODE_STATUS_FAILED, handlerErr.Error()); err != nil {
+		return newExecutionError(serviceTask.ExecutionId, ErrPersistenceFailed, err)
+	}
+	return serviceTask.takeFlows(ctx, serviceTask.ErrorOutgoing)
+}
+
+func (serviceTask ServiceTask) takeFlows(ctx *WorkflowContext, flows []string) error {
+	for _, value := range flows {
+		if err := executeSequenceFlow(ctx, value); err != nil {
+			return err
+		}
+		ctx.CurrentExecutionId = serviceTask.ExecutionId
+	}
+	return nil
+}
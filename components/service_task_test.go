@@ -0,0 +1,57 @@
+package components
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var (
+	serviceInputMu       sync.Mutex
+	serviceInputReceived map[string]any
+)
+
+func init() {
+	RegisterServiceHandler("serviceInputTestHandler", func(ctx *WorkflowContext, variables map[string]any) (map[string]any, error) {
+		serviceInputMu.Lock()
+		defer serviceInputMu.Unlock()
+		serviceInputReceived = variables
+		return map[string]any{"checked": true}, nil
+	})
+}
+
+const serviceInputXML = `<Process name="serviceInput">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="service-user"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <ServiceTask executionId="sv" name="SV" handler="serviceInputTestHandler"><Incoming>f2</Incoming><Outgoing>f3</Outgoing></ServiceTask>
+  <Task executionId="t2" name="T2" assigneeType="ByAssigneeName" assigneeKey="service-user"><Incoming>f3</Incoming><Outgoing>f4</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f4</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>
+  <SequenceFlow executionId="f2" sourceRef="t" targetRef="sv"/>
+  <SequenceFlow executionId="f3" sourceRef="sv" targetRef="t2"/>
+  <SequenceFlow executionId="f4" sourceRef="t2" targetRef="e"/>
+</Process>`
+
+func TestServiceHandlerReceivesProcessAndNodeVariables(t *testing.T) {
+	deployXML(t, "serviceInput", []byte(serviceInputXML))
+	id := startProcess(t, "serviceInput", "service-ann", `{"amount":1500,"applicant":{"dept":"hr"}}`)
+	terminateOnCleanup(t, id)
+	if err := GetServiceFactory().GetRuntimeService().SetVariable(id, "", "priority", "high", "service-ann"); err != nil {
+		t.Fatal(err)
+	}
+	completeTaskAs(t, activeTask(t, id, "t").Id, "service-user", map[string]any{"score": 7})
+
+	serviceInputMu.Lock()
+	received := serviceInputReceived
+	serviceInputMu.Unlock()
+	expected := map[string]any{
+		"amount":    float64(1500),
+		"applicant": map[string]any{"dept": "hr"},
+		"priority":  "high",
+		"t":         map[string]any{"score": float64(7)},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("handler variables = %#v", received)
+	}
+	activeTask(t, id, "t2")
+}
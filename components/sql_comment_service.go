@@ -0,0 +1,111 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SQLCommentService 是 CommentService 接口基于 database/sql 的实现
+type SQLCommentService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLCommentService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// 流程实例上的评论和附件 node_instance_id 为空
+func nullNodeInstanceId(nodeInstanceId int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(nodeInstanceId), Valid: nodeInstanceId != 0}
+}
+
+// 按流程实例查询 nodeInstanceId 不为 0 时只查这个审批节点的
+func commentFilter(processInstanceId int, nodeInstanceId int) (string, []any) {
+	if nodeInstanceId == 0 {
+		return `process_instance_id = ?`, []any{processInstanceId}
+	}
+	return `process_instance_id = ? AND node_instance_id = ?`, []any{processInstanceId, nodeInstanceId}
+}
+
+// AddComment 新增评论
+func (service *SQLCommentService) AddComment(tx *sql.Tx, comment *Comment) (int, error) {
+	query := `
+        INSERT INTO task_comment (process_instance_id, node_instance_id, user_id, message, created_at)
+        VALUES (?, ?, ?, ?, ?)`
+	id, err := service.dialect.insert(tx, query, comment.ProcessInstanceId, nullNodeInstanceId(comment.NodeInstanceId), comment.UserId, comment.Message, comment.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add comment: %v", err)
+	}
+	comment.Id = id
+	return id, nil
+}
+
+// GetComments 查询评论
+func (service *SQLCommentService) GetComments(processInstanceId int, nodeInstanceId int) ([]Comment, error) {
+	filter, args := commentFilter(processInstanceId, nodeInstanceId)
+	query := `SELECT id, process_instance_id, node_instance_id, user_id, message, created_at FROM task_comment WHERE ` + filter + ` ORDER BY id`
+	rows, err := service.dialect.query(service.DB, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %v", err)
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		var (
+			comment        Comment
+			nodeInstanceId sql.NullInt64
+		)
+		if err := rows.Scan(&comment.Id, &comment.ProcessInstanceId, &nodeInstanceId, &comment.UserId, &comment.Message, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %v", err)
+		}
+		comment.NodeInstanceId = int(nodeInstanceId.Int64)
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// AddAttachment 新增附件
+func (service *SQLCommentService) AddAttachment(tx *sql.Tx, attachment *Attachment) (int, error) {
+	query := `
+        INSERT INTO task_attachment (process_instance_id, node_instance_id, name, content_type, uri, checksum, created_by, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	id, err := service.dialect.insert(tx, query, attachment.ProcessInstanceId, nullNodeInstanceId(attachment.NodeInstanceId), attachment.Name, attachment.ContentType,
+		attachment.URI, attachment.Checksum, attachment.CreatedBy, attachment.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add attachment: %v", err)
+	}
+	attachment.Id = id
+	return id, nil
+}
+
+// GetAttachments 查询附件
+func (service *SQLCommentService) GetAttachments(processInstanceId int, nodeInstanceId int) ([]Attachment, error) {
+	filter, args := commentFilter(processInstanceId, nodeInstanceId)
+	query := `SELECT id, process_instance_id, node_instance_id, name, content_type, uri, checksum, created_by, created_at FROM task_attachment WHERE ` + filter + ` ORDER BY id`
+	rows, err := service.dialect.query(service.DB, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %v", err)
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var (
+			attachment     Attachment
+			nodeInstanceId sql.NullInt64
+			contentType    sql.NullString
+			checksum       sql.NullString
+		)
+		err := rows.Scan(&attachment.Id, &attachment.ProcessInstanceId, &nodeInstanceId, &attachment.Name, &contentType, &attachment.URI, &checksum, &attachment.CreatedBy, &attachment.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %v", err)
+		}
+		attachment.NodeInstanceId = int(nodeInstanceId.Int64)
+		attachment.ContentType = contentType.String
+		attachment.Checksum = checksum.String
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
@@ -0,0 +1,97 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLDelegationRuleService 是 DelegationRuleService 接口基于 database/sql 的实现
+type SQLDelegationRuleService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLDelegationRuleService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// 代理规则查询的字段 和 scanDelegationRule 的顺序一致
+const delegationRuleColumns = `id, user_id, substitute, start_time, end_time, process_definition_name, created_by, created_at`
+
+func scanDelegationRule(scanner interface{ Scan(dest ...any) error }) (*DelegationRule, error) {
+	rule := &DelegationRule{}
+	var (
+		processDefinitionName sql.NullString
+		createdBy             sql.NullString
+		createdAt             sql.NullTime
+	)
+	err := scanner.Scan(&rule.Id, &rule.UserId, &rule.Substitute, &rule.StartTime, &rule.EndTime, &processDefinitionName, &createdBy, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	rule.ProcessDefinitionName = processDefinitionName.String
+	rule.CreatedBy = createdBy.String
+	rule.CreatedAt = createdAt.Time
+	return rule, nil
+}
+
+// AddDelegationRule 新增代理规则 时间统一按 UTC 存储，sqlite 的时间是按字符串比较的
+func (service *SQLDelegationRuleService) AddDelegationRule(rule *DelegationRule) (int, error) {
+	if err := validateDelegationRule(rule); err != nil {
+		return 0, err
+	}
+	query := `
+        INSERT INTO delegation_rule (user_id, substitute, start_time, end_time, process_definition_name, created_by, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`
+	id, err := service.dialect.insert(service.DB, query, rule.UserId, rule.Substitute, rule.StartTime.UTC(), rule.EndTime.UTC(),
+		sql.NullString{String: rule.ProcessDefinitionName, Valid: rule.ProcessDefinitionName != ""}, rule.CreatedBy, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to add delegation rule: %v", ErrPersistenceFailed, err)
+	}
+	rule.Id = id
+	return id, nil
+}
+
+// DeleteDelegationRule 删除代理规则
+func (service *SQLDelegationRuleService) DeleteDelegationRule(id int) error {
+	_, err := service.dialect.exec(service.DB, `DELETE FROM delegation_rule WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete delegation rule: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// GetDelegationRules 查询用户的全部代理规则
+func (service *SQLDelegationRuleService) GetDelegationRules(userId string) ([]DelegationRule, error) {
+	query := `SELECT ` + delegationRuleColumns + ` FROM delegation_rule WHERE user_id = ? ORDER BY start_time, id`
+	return service.queryDelegationRules(service.DB, query, userId)
+}
+
+// GetActiveDelegationRules 查询某个时间点对某个流程生效的代理规则
+func (service *SQLDelegationRuleService) GetActiveDelegationRules(tx *sql.Tx, userId string, processDefinitionName string, at time.Time) ([]DelegationRule, error) {
+	query := `
+        SELECT ` + delegationRuleColumns + `
+        FROM delegation_rule
+        WHERE user_id = ? AND start_time <= ? AND end_time > ? AND (process_definition_name IS NULL OR process_definition_name = ?)
+        ORDER BY id`
+	return service.queryDelegationRules(tx, query, userId, at.UTC(), at.UTC(), processDefinitionName)
+}
+
+func (service *SQLDelegationRuleService) queryDelegationRules(executor sqlExecutor, query string, args ...any) ([]DelegationRule, error) {
+	rows, err := service.dialect.query(executor, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delegation rules: %v", err)
+	}
+	defer rows.Close()
+
+	var rules []DelegationRule
+	for rows.Next() {
+		rule, err := scanDelegationRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delegation rule: %v", err)
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
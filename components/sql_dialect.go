@@ -0,0 +1,118 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// sqlExecutor *sql.DB 和 *sql.Tx 都满足的执行接口
+type sqlExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// sqlDialect 屏蔽不同数据库之间的 SQL 差异，服务里统一按 mysql 的 ? 占位符书写
+type sqlDialect struct {
+	name string
+	// 占位符是否需要改写成 $1 $2 ...
+	numberedPlaceholder bool
+	// 插入后是否要通过 RETURNING id 拿自增主键，postgres 的驱动不支持 LastInsertId
+	returningId bool
+	// 行锁语句，sqlite 不支持 为空，靠 _txlock=immediate 串行化写事务
+	forUpdate string
+	// 汇聚网关统计到达分支时的加锁方式
+	countLock countLockStrategy
+}
+
+// countLockStrategy 统计汇聚网关到达的分支时怎么加锁，保证并发到达的分支里只有一个看到全部到齐
+type countLockStrategy int
+
+const (
+	countLockNone            countLockStrategy = iota // 不加锁 由数据库串行化写事务
+	countLockRows                                     // 计数语句直接加 FOR UPDATE
+	countLockProcessInstance                          // 聚合查询不能加 FOR UPDATE，先锁住流程实例
+)
+
+var (
+	mysqlDialect    = &sqlDialect{name: MYSQL_DBNAME, forUpdate: " FOR UPDATE", countLock: countLockRows}
+	postgresDialect = &sqlDialect{name: POSTGRES_DBNAME, numberedPlaceholder: true, returningId: true, forUpdate: " FOR UPDATE", countLock: countLockProcessInstance}
+	sqliteDialect   = &sqlDialect{name: SQLITE_DBNAME}
+)
+
+// rebind 把 ? 占位符改写成当前数据库的写法，引号里的问号不处理
+func (dialect *sqlDialect) rebind(query string) string {
+	if !dialect.numberedPlaceholder {
+		return query
+	}
+	var builder strings.Builder
+	index := 0
+	inQuote := false
+	for _, char := range query {
+		switch {
+		case char == '\'':
+			inQuote = !inQuote
+			builder.WriteRune(char)
+		case char == '?' && !inQuote:
+			index++
+			builder.WriteString(fmt.Sprintf("$%d", index))
+		default:
+			builder.WriteRune(char)
+		}
+	}
+	return builder.String()
+}
+
+func (dialect *sqlDialect) exec(executor sqlExecutor, query string, args ...any) (sql.Result, error) {
+	return executor.Exec(dialect.rebind(query), args...)
+}
+
+func (dialect *sqlDialect) query(executor sqlExecutor, query string, args ...any) (*sql.Rows, error) {
+	return executor.Query(dialect.rebind(query), args...)
+}
+
+func (dialect *sqlDialect) queryRow(executor sqlExecutor, query string, args ...any) *sql.Row {
+	return executor.QueryRow(dialect.rebind(query), args...)
+}
+
+// insert 执行插入语句并返回自增主键
+func (dialect *sqlDialect) insert(executor sqlExecutor, query string, args ...any) (int, error) {
+	if dialect.returningId {
+		var id int
+		query = strings.TrimRight(strings.TrimSpace(query), ";") + " RETURNING id"
+		if err := dialect.queryRow(executor, query, args...).Scan(&id); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	result, err := dialect.exec(executor, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve last insert id: %v", err)
+	}
+	return int(id), nil
+}
+
+// lockedCount 按方言的加锁方式执行流程实例里的计数语句
+func (dialect *sqlDialect) lockedCount(tx *sql.Tx, processInstanceId int, query string, args ...any) (int, error) {
+	switch dialect.countLock {
+	case countLockRows:
+		query += dialect.forUpdate
+	case countLockProcessInstance:
+		var lockedId int
+		err := dialect.queryRow(tx, `SELECT id FROM process_instance WHERE id = ?`+dialect.forUpdate, processInstanceId).Scan(&lockedId)
+		if err != nil {
+			return 0, fmt.Errorf("failed to lock process instance %d: %v", processInstanceId, err)
+		}
+	}
+	var count int
+	if err := dialect.queryRow(tx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
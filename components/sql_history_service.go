@@ -0,0 +1,307 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLHistoryService 是 HistoryService 接口基于 database/sql 的实现
+type SQLHistoryService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLHistoryService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+func (service *SQLHistoryService) CopyNodeInstanceById(tx *sql.Tx, nodeId int) error {
+	query := `
+		INSERT INTO historic_node_instance (
+		    id,
+			process_instance_id,
+			process_definition_name,
+			node_name,
+			execution_id,
+			output_data,
+			previous_execution_id,
+			assignee,
+			start_time,
+			end_time,
+			owner,
+			original_assignee,
+			due_date
+		)
+		SELECT 
+		    id,
+			process_instance_id,
+			process_definition_name,
+			node_name,
+			execution_id,
+			output_data,
+			previous_execution_id,
+			assignee,
+			start_time,
+			end_time,
+			owner,
+			original_assignee,
+			due_date
+		FROM node_instance
+		WHERE id = ?
+	`
+
+	_, err := service.dialect.exec(tx, query, nodeId)
+	if err != nil {
+		return fmt.Errorf("failed to copy node instance to historic_node_instance: %v", err)
+	}
+
+	return nil
+}
+
+func (service *SQLHistoryService) CopyNodeInstance(tx *sql.Tx, nodeId int, processInstanceId int, processDefinitionName string, nodeName string, executionId string,
+	previousExecutionId string, assignee string) (int, error) {
+	query := `
+        INSERT INTO historic_node_instance (id, process_instance_id, process_definition_name, node_name, execution_id, previous_execution_id, assignee, start_time)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	startTime := time.Now()
+	id, err := service.dialect.insert(tx, query, nodeId, processInstanceId, processDefinitionName, nodeName, executionId, previousExecutionId, assignee, startTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy node instance to historic: %v", err)
+	}
+
+	return id, nil
+}
+
+func (service *SQLHistoryService) ArchiveNodeInstance(tx *sql.Tx, nodeId int, status string, comment string) error {
+	query := `
+		INSERT INTO historic_node_instance (
+		    id,
+			process_instance_id,
+			process_definition_name,
+			node_name,
+			execution_id,
+			output_data,
+			previous_execution_id,
+			assignee,
+			start_time,
+			end_time,
+			status,
+			comment,
+			owner,
+			original_assignee,
+			due_date
+		)
+		SELECT 
+		    id,
+			process_instance_id,
+			process_definition_name,
+			node_name,
+			execution_id,
+			output_data,
+			previous_execution_id,
+			assignee,
+			start_time,
+			?,
+			?,
+			?,
+			owner,
+			original_assignee,
+			due_date
+		FROM node_instance
+		WHERE id = ?
+	`
+
+	_, err := service.dialect.exec(tx, query, time.Now(), status, comment, nodeId)
+	if err != nil {
+		return fmt.Errorf("failed to archive node instance: %v", err)
+	}
+
+	return nil
+}
+
+// ArchiveProcessInstance 把流程实例复制到历史流程实例表 同时记录持续时间和结束事件
+func (service *SQLHistoryService) ArchiveProcessInstance(tx *sql.Tx, processInstanceId int, endExecutionId string) error {
+	var (
+		startTime time.Time
+		endTime   sql.NullTime
+	)
+	err := service.dialect.queryRow(tx, `SELECT start_time, end_time FROM process_instance WHERE id = ?`, processInstanceId).Scan(&startTime, &endTime)
+	if err != nil {
+		return fmt.Errorf("failed to archive process instance: %v", err)
+	}
+	if !endTime.Valid {
+		endTime.Time = time.Now()
+	}
+
+	query := `
+		INSERT INTO historic_process_instance (id, process_definition_name, version, status, created_by, business_key, start_time, end_time, duration, end_execution_id)
+		SELECT id, process_definition_name, version, status, created_by, business_key, start_time, ?, ?, ?
+		FROM process_instance
+		WHERE id = ?
+	`
+	_, err = service.dialect.exec(tx, query, endTime.Time, endTime.Time.Sub(startTime).Milliseconds(), sql.NullString{String: endExecutionId, Valid: endExecutionId != ""}, processInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to archive process instance: %v", err)
+	}
+	return nil
+}
+
+// GetHistoricProcessInstanceById 根据id查询历史流程实例
+func (service *SQLHistoryService) GetHistoricProcessInstanceById(id int) (*HistoricProcessInstance, error) {
+	query := `
+		SELECT ` + processInstanceColumns + `, duration, end_execution_id
+		FROM historic_process_instance
+		WHERE id = ?
+	`
+	instance := &HistoricProcessInstance{}
+	var (
+		createdBy      sql.NullString
+		endTime        sql.NullTime
+		duration       sql.NullInt64
+		endExecutionId sql.NullString
+	)
+	err := service.dialect.queryRow(service.DB, query, id).Scan(&instance.Id, &instance.ProcessDefinitionName, &instance.Version, &instance.Business_key, &instance.Status, &createdBy, &instance.StartTime, &endTime, &duration, &endExecutionId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get historic process instance by Id: %v", err)
+	}
+	instance.CreatedBy = createdBy.String
+	if endTime.Valid {
+		instance.EndTime = &endTime.Time
+	}
+	instance.Duration = duration.Int64
+	instance.EndExecutionId = endExecutionId.String
+	return instance, nil
+}
+
+// 历史节点实例的查询列 历史表没有委派状态按空值查询，和运行表共用扫描逻辑
+const historicNodeInstanceColumns = `id, process_instance_id, process_definition_name, node_name, execution_id, output_data, previous_execution_id, assignee, start_time, end_time, owner, NULL, original_assignee, due_date`
+
+// GetHistoricNodeInstanceById 根据Id获取历史节点实例
+func (service *SQLHistoryService) GetHistoricNodeInstanceById(id int) (*NodeInstance, error) {
+	query := `SELECT ` + historicNodeInstanceColumns + ` FROM historic_node_instance WHERE id = ?`
+	instance, err := scanNodeInstance(service.dialect.queryRow(service.DB, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get historic node instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+func (service *SQLHistoryService) GetProcessCompleteTask(ProcessInstanceId int) ([]map[string]interface{}, error) {
+	// 创建一个空的map数组用于存储结果
+	var results []map[string]interface{}
+
+	// 构建查询语句
+	query := `
+			SELECT id, process_instance_id,process_definition_name, node_name, execution_id, output_data, previous_execution_id, assignee, start_time, end_time
+			FROM node_instance
+			WHERE process_instance_id = ?
+		`
+
+	// 执行查询
+	rows, err := service.dialect.query(service.DB, query, ProcessInstanceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// 遍历查询结果
+	for rows.Next() {
+		var (
+			id                    int
+			processInstanceID     int
+			processDefinitionName string
+			nodeName              string
+			executionID           string
+			outputData            sql.NullString
+			previousExecutionID   sql.NullString
+			assignee              sql.NullString
+			startTime             sql.NullString
+			endTime               sql.NullString
+		)
+
+		// 扫描每一行数据
+		err := rows.Scan(&id, &processInstanceID, &processDefinitionName, &nodeName, &executionID, &outputData, &previousExecutionID, &assignee, &startTime, &endTime)
+		if err != nil {
+			return nil, err
+		}
+
+		// 将数据放入map
+		result := map[string]interface{}{
+			"id":                      id,
+			"process_instance_id":     processInstanceID,
+			"process_definition_name": processDefinitionName,
+			"node_name":               nodeName,
+			"execution_id":            executionID,
+			"output_data":             outputData,
+			"previous_execution_id":   previousExecutionID,
+			"assignee":                assignee,
+			"start_time":              startTime,
+			"end_time":                endTime,
+		}
+
+		// 将map放入结果数组
+		results = append(results, result)
+	}
+
+	// 检查是否有错误
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// AddTaskOperation 记录一条审批节点的操作
+func (service *SQLHistoryService) AddTaskOperation(tx *sql.Tx, operation TaskOperation) error {
+	query := `
+		INSERT INTO task_operation (process_instance_id, node_instance_id, execution_id, operation, from_user, to_user, reason, output_data, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := service.dialect.exec(tx, query, operation.ProcessInstanceId, operation.NodeInstanceId, operation.ExecutionId, operation.Operation, operation.FromUser, operation.ToUser,
+		operation.Reason, sql.NullString{String: operation.OutputData, Valid: operation.OutputData != ""}, operation.CreatedBy, operation.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add task operation: %v", err)
+	}
+	return nil
+}
+
+// GetTaskOperations 查询流程实例的审批节点操作记录
+func (service *SQLHistoryService) GetTaskOperations(processInstanceId int) ([]TaskOperation, error) {
+	query := `
+		SELECT id, process_instance_id, node_instance_id, execution_id, operation, from_user, to_user, reason, output_data, created_by, created_at
+		FROM task_operation
+		WHERE process_instance_id = ?
+		ORDER BY id
+	`
+	rows, err := service.dialect.query(service.DB, query, processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task operations: %v", err)
+	}
+	defer rows.Close()
+
+	var operations []TaskOperation
+	for rows.Next() {
+		var (
+			operation  TaskOperation
+			reason     sql.NullString
+			outputData sql.NullString
+		)
+		err := rows.Scan(&operation.Id, &operation.ProcessInstanceId, &operation.NodeInstanceId, &operation.ExecutionId, &operation.Operation, &operation.FromUser, &operation.ToUser,
+			&reason, &outputData, &operation.CreatedBy, &operation.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task operation: %v", err)
+		}
+		operation.Reason = reason.String
+		operation.OutputData = outputData.String
+		operations = append(operations, operation)
+	}
+	return operations, rows.Err()
+}
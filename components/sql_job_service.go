@@ -0,0 +1,345 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLJobService 是 JobService 接口基于 database/sql 的实现
+type SQLJobService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLJobService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// 定时任务查询的字段 和 scanTimerJob 的顺序一致，查询时表的别名是 j
+const timerJobColumns = `j.id, j.process_instance_id, j.node_instance_id, j.execution_id, j.action, j.attempts, j.due_time, j.last_error, j.created_at`
+
+func scanTimerJob(scanner interface{ Scan(dest ...any) error }, extra ...any) (*TimerJob, error) {
+	job := &TimerJob{}
+	var lastError sql.NullString
+	var createdAt sql.NullTime
+	dest := append([]any{&job.Id, &job.ProcessInstanceId, &job.NodeInstanceId, &job.ExecutionId, &job.Action, &job.Attempts, &job.DueTime, &lastError, &createdAt}, extra...)
+	if err := scanner.Scan(dest...); err != nil {
+		return nil, err
+	}
+	job.LastError = lastError.String
+	job.CreatedAt = createdAt.Time
+	return job, nil
+}
+
+// CreateTimerJob 创建定时任务 到期时间统一按 UTC 存储，sqlite 的时间是按字符串比较的
+func (service *SQLJobService) CreateTimerJob(tx *sql.Tx, job *TimerJob) (int, error) {
+	query := `
+        INSERT INTO timer_job (process_instance_id, node_instance_id, execution_id, action, attempts, due_time, last_error, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	id, err := service.dialect.insert(tx, query, job.ProcessInstanceId, job.NodeInstanceId, job.ExecutionId, job.Action, job.Attempts, job.DueTime.UTC(), job.LastError, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create timer job: %v", err)
+	}
+	return id, nil
+}
+
+// GetDueTimerJobs 查询到期的定时任务 挂起或者故障的流程实例的定时任务等恢复之后再触发，不占用每一批的名额
+func (service *SQLJobService) GetDueTimerJobs(now time.Time, limit int) ([]TimerJob, error) {
+	query := `
+        SELECT ` + timerJobColumns + `
+        FROM timer_job j
+        LEFT JOIN process_instance p ON p.id = j.process_instance_id
+        WHERE j.due_time <= ? AND (p.status IS NULL OR p.status NOT IN (?, ?))
+        ORDER BY j.due_time, j.id
+        LIMIT ?`
+	rows, err := service.dialect.query(service.DB, query, now.UTC(), PROCESS_STATUS_SUSPENDED, PROCESS_STATUS_INCIDENT, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due timer jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []TimerJob
+	for rows.Next() {
+		job, err := scanTimerJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan timer job: %v", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// LockTimerJob 在事务里读取并锁住定时任务
+func (service *SQLJobService) LockTimerJob(tx *sql.Tx, id int) (*TimerJob, error) {
+	query := `SELECT ` + timerJobColumns + ` FROM timer_job j WHERE j.id = ?` + service.dialect.forUpdate
+	job, err := scanTimerJob(service.dialect.queryRow(tx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock timer job: %v", err)
+	}
+	return job, nil
+}
+
+// UpdateTimerJobFailure 记录定时任务的失败次数和错误
+func (service *SQLJobService) UpdateTimerJobFailure(tx *sql.Tx, id int, attempts int, dueTime time.Time, lastError string) error {
+	query := `UPDATE timer_job SET attempts = ?, due_time = ?, last_error = ? WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, attempts, dueTime.UTC(), lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to update timer job: %v", err)
+	}
+	return nil
+}
+
+// DeleteTimerJob 删除定时任务
+func (service *SQLJobService) DeleteTimerJob(tx *sql.Tx, id int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM timer_job WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete timer job: %v", err)
+	}
+	return nil
+}
+
+// DeleteTimerJobsByNodeInstance 删除挂在节点实例上的定时任务
+func (service *SQLJobService) DeleteTimerJobsByNodeInstance(tx *sql.Tx, nodeInstanceId int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM timer_job WHERE node_instance_id = ?`, nodeInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to delete timer jobs of node instance: %v", err)
+	}
+	return nil
+}
+
+// DeleteTimerJobsByProcessInstance 删除流程实例的全部定时任务
+func (service *SQLJobService) DeleteTimerJobsByProcessInstance(tx *sql.Tx, processInstanceId int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM timer_job WHERE process_instance_id = ?`, processInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to delete timer jobs of process instance: %v", err)
+	}
+	return nil
+}
+
+// 异步任务查询的字段 和 scanAsyncJob 的顺序一致，查询时表的别名是 j
+const asyncJobColumns = `j.id, j.process_instance_id, j.execution_id, j.previous_execution_id, j.attempts, j.due_time, j.last_error, j.created_at`
+
+func scanAsyncJob(scanner interface{ Scan(dest ...any) error }, extra ...any) (*AsyncJob, error) {
+	job := &AsyncJob{}
+	var previousExecutionId, lastError sql.NullString
+	var createdAt sql.NullTime
+	dest := append([]any{&job.Id, &job.ProcessInstanceId, &job.ExecutionId, &previousExecutionId, &job.Attempts, &job.DueTime, &lastError, &createdAt}, extra...)
+	if err := scanner.Scan(dest...); err != nil {
+		return nil, err
+	}
+	job.PreviousExecutionId = previousExecutionId.String
+	job.LastError = lastError.String
+	job.CreatedAt = createdAt.Time
+	return job, nil
+}
+
+// CreateAsyncJob 创建异步任务 到期时间按 UTC 存储
+func (service *SQLJobService) CreateAsyncJob(tx *sql.Tx, job *AsyncJob) (int, error) {
+	query := `
+        INSERT INTO async_job (process_instance_id, execution_id, previous_execution_id, attempts, due_time, last_error, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`
+	id, err := service.dialect.insert(tx, query, job.ProcessInstanceId, job.ExecutionId, job.PreviousExecutionId, job.Attempts, job.DueTime.UTC(), job.LastError, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create async job: %v", err)
+	}
+	return id, nil
+}
+
+// GetDueAsyncJobs 查询到期的异步任务 挂起或者故障的流程实例的异步任务等恢复之后再执行
+func (service *SQLJobService) GetDueAsyncJobs(now time.Time, limit int) ([]AsyncJob, error) {
+	query := `
+        SELECT ` + asyncJobColumns + `
+        FROM async_job j
+        LEFT JOIN process_instance p ON p.id = j.process_instance_id
+        WHERE j.due_time <= ? AND (p.status IS NULL OR p.status NOT IN (?, ?))
+        ORDER BY j.due_time, j.id
+        LIMIT ?`
+	return service.queryAsyncJobs(service.DB, query, now.UTC(), PROCESS_STATUS_SUSPENDED, PROCESS_STATUS_INCIDENT, limit)
+}
+
+func (service *SQLJobService) queryAsyncJobs(executor sqlExecutor, query string, args ...any) ([]AsyncJob, error) {
+	rows, err := service.dialect.query(executor, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get async jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []AsyncJob
+	for rows.Next() {
+		job, err := scanAsyncJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan async job: %v", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// LockAsyncJob 在事务里读取并锁住异步任务
+func (service *SQLJobService) LockAsyncJob(tx *sql.Tx, id int) (*AsyncJob, error) {
+	query := `SELECT ` + asyncJobColumns + ` FROM async_job j WHERE j.id = ?` + service.dialect.forUpdate
+	job, err := scanAsyncJob(service.dialect.queryRow(tx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock async job: %v", err)
+	}
+	return job, nil
+}
+
+// UpdateAsyncJobFailure 记录失败次数和错误
+func (service *SQLJobService) UpdateAsyncJobFailure(tx *sql.Tx, id int, attempts int, dueTime time.Time, lastError string) error {
+	query := `UPDATE async_job SET attempts = ?, due_time = ?, last_error = ? WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, attempts, dueTime.UTC(), lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to update async job: %v", err)
+	}
+	return nil
+}
+
+// DeleteAsyncJob 删除异步任务
+func (service *SQLJobService) DeleteAsyncJob(tx *sql.Tx, id int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM async_job WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete async job: %v", err)
+	}
+	return nil
+}
+
+// GetAsyncJobsByProcessInstance 查询流程实例还没有执行的异步任务
+func (service *SQLJobService) GetAsyncJobsByProcessInstance(tx *sql.Tx, processInstanceId int) ([]AsyncJob, error) {
+	query := `SELECT ` + asyncJobColumns + ` FROM async_job j WHERE j.process_instance_id = ? ORDER BY j.id`
+	return service.queryAsyncJobs(tx, query, processInstanceId)
+}
+
+// DeleteAsyncJobsByProcessInstance 删除流程实例的全部异步任务
+func (service *SQLJobService) DeleteAsyncJobsByProcessInstance(tx *sql.Tx, processInstanceId int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM async_job WHERE process_instance_id = ?`, processInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to delete async jobs of process instance: %v", err)
+	}
+	return nil
+}
+
+// MoveAsyncJobToDeadLetter 把异步任务移到死信表 失败时间单独更新，避免在 SELECT 里绑定参数时 postgres 推断不出类型
+func (service *SQLJobService) MoveAsyncJobToDeadLetter(tx *sql.Tx, id int) error {
+	query := `
+        INSERT INTO dead_letter_job (id, process_instance_id, execution_id, previous_execution_id, attempts, due_time, last_error, created_at)
+        SELECT id, process_instance_id, execution_id, previous_execution_id, attempts, due_time, last_error, created_at
+        FROM async_job WHERE id = ?`
+	if _, err := service.dialect.exec(tx, query, id); err != nil {
+		return fmt.Errorf("failed to move async job to dead letter: %v", err)
+	}
+	if _, err := service.dialect.exec(tx, `UPDATE dead_letter_job SET failed_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to move async job to dead letter: %v", err)
+	}
+	return service.DeleteAsyncJob(tx, id)
+}
+
+// GetDeadLetterJobs 查询全部死信任务
+func (service *SQLJobService) GetDeadLetterJobs() ([]DeadLetterJob, error) {
+	query := `SELECT ` + asyncJobColumns + `, j.failed_at FROM dead_letter_job j ORDER BY j.id`
+	rows, err := service.dialect.query(service.DB, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []DeadLetterJob
+	for rows.Next() {
+		var failedAt sql.NullTime
+		job, err := scanAsyncJob(rows, &failedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter job: %v", err)
+		}
+		jobs = append(jobs, DeadLetterJob{AsyncJob: *job, FailedAt: failedAt.Time})
+	}
+	return jobs, rows.Err()
+}
+
+// LockDeadLetterJob 在事务里读取并锁住死信任务
+func (service *SQLJobService) LockDeadLetterJob(tx *sql.Tx, id int) (*DeadLetterJob, error) {
+	query := `SELECT ` + asyncJobColumns + `, j.failed_at FROM dead_letter_job j WHERE j.id = ?` + service.dialect.forUpdate
+	var failedAt sql.NullTime
+	job, err := scanAsyncJob(service.dialect.queryRow(tx, query, id), &failedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock dead letter job: %v", err)
+	}
+	return &DeadLetterJob{AsyncJob: *job, FailedAt: failedAt.Time}, nil
+}
+
+// DeleteDeadLetterJob 删除死信任务
+func (service *SQLJobService) DeleteDeadLetterJob(tx *sql.Tx, id int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM dead_letter_job WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter job: %v", err)
+	}
+	return nil
+}
+
+// MoveTimerJobToDeadLetter 把定时任务移到死信表 和异步任务一样单独更新失败时间
+func (service *SQLJobService) MoveTimerJobToDeadLetter(tx *sql.Tx, id int) error {
+	query := `
+        INSERT INTO dead_letter_timer_job (id, process_instance_id, node_instance_id, execution_id, action, attempts, due_time, last_error, created_at)
+        SELECT id, process_instance_id, node_instance_id, execution_id, action, attempts, due_time, last_error, created_at
+        FROM timer_job WHERE id = ?`
+	if _, err := service.dialect.exec(tx, query, id); err != nil {
+		return fmt.Errorf("failed to move timer job to dead letter: %v", err)
+	}
+	if _, err := service.dialect.exec(tx, `UPDATE dead_letter_timer_job SET failed_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to move timer job to dead letter: %v", err)
+	}
+	return service.DeleteTimerJob(tx, id)
+}
+
+// GetDeadLetterTimerJobs 查询全部死信定时任务
+func (service *SQLJobService) GetDeadLetterTimerJobs() ([]DeadLetterTimerJob, error) {
+	query := `SELECT ` + timerJobColumns + `, j.failed_at FROM dead_letter_timer_job j ORDER BY j.id`
+	rows, err := service.dialect.query(service.DB, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter timer jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []DeadLetterTimerJob
+	for rows.Next() {
+		var failedAt sql.NullTime
+		job, err := scanTimerJob(rows, &failedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter timer job: %v", err)
+		}
+		jobs = append(jobs, DeadLetterTimerJob{TimerJob: *job, FailedAt: failedAt.Time})
+	}
+	return jobs, rows.Err()
+}
+
+// LockDeadLetterTimerJob 在事务里读取并锁住死信定时任务
+func (service *SQLJobService) LockDeadLetterTimerJob(tx *sql.Tx, id int) (*DeadLetterTimerJob, error) {
+	query := `SELECT ` + timerJobColumns + `, j.failed_at FROM dead_letter_timer_job j WHERE j.id = ?` + service.dialect.forUpdate
+	var failedAt sql.NullTime
+	job, err := scanTimerJob(service.dialect.queryRow(tx, query, id), &failedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock dead letter timer job: %v", err)
+	}
+	return &DeadLetterTimerJob{TimerJob: *job, FailedAt: failedAt.Time}, nil
+}
+
+// DeleteDeadLetterTimerJob 删除死信定时任务
+func (service *SQLJobService) DeleteDeadLetterTimerJob(tx *sql.Tx, id int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM dead_letter_timer_job WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter timer job: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,530 @@
+package components
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLNodeService 是 NodeService 接口基于 database/sql 的实现
+type SQLNodeService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLNodeService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// StartNodeInstance 创建一个新的节点实例
+func (service *SQLNodeService) InitNodeInstance(tx *sql.Tx, processInstanceId int, processDefinitionName string, nodeName string, executionId string, previousExecutionId string, assignee string) (int, error) {
+	query := `
+        INSERT INTO node_instance (process_instance_id, process_definition_name, node_name, execution_id, previous_execution_id, assignee, start_time)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	startTime := time.Now()
+	id, err := service.dialect.insert(tx, query, processInstanceId, processDefinitionName, nodeName, executionId, previousExecutionId, assignee, startTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start node instance: %v", err)
+	}
+
+	return id, nil
+}
+
+// GetAttributeByExpression 根据表达式获取属性值
+func (service *SQLNodeService) GetAttributeByExpression(tx *sql.Tx, expression string, processInstanceId int) (map[string]interface{}, error) {
+	// 提取表达式中的属性
+	attributes := ExtractAttributes(expression)
+
+	// 用于存储最终的属性和值
+	result := make(map[string]interface{})
+
+	for _, attr := range attributes {
+		// 属性格式为 "A.a" 或者更深的 JSON 路径 "A.a.b"
+		parts := strings.Split(attr, ".")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid attribute format: %s", attr)
+		}
+
+		executionId := parts[0]
+
+		// 因为打回的关系 还有流程配置的关系 历史表里保留全量数据 可能不止一条，节点表因为流程配置可能也有多条
+		// 打回的时候 直接顺着打回目标节点的outgoing全部删除 可以保证至少节点表里最新的数据 就是可用的数据，因为打回的历史数据全部给删除了，留下来的最新的一定是生效的
+		// 因为是用来找自己的轮次的 所以根据start_time还是根据 end_time排序都一样
+		// 必须用事务 否则查询不到当前批次数据
+		query := `SELECT output_data FROM node_instance WHERE execution_id = ? and process_instance_id = ?  ORDER BY start_time DESC LIMIT 1`
+		row := service.dialect.queryRow(tx, query, executionId, processInstanceId)
+
+		var outputData []byte
+		if err := row.Scan(&outputData); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("no data found for execution_id: %s", executionId)
+			}
+			return nil, fmt.Errorf("failed to query database: %v", err)
+		}
+
+		// 解析 JSON 数据
+		var data interface{}
+		if err := json.Unmarshal(outputData, &data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+
+		// 获取具体属性的值
+		value, exists := lookupPath(data, parts[1:])
+		if !exists {
+			return nil, fmt.Errorf("attribute %s not found in output data", attr)
+		}
+
+		// 将属性和值存入结果
+		result[attr] = value
+	}
+
+	return result, nil
+}
+
+// 根据流程实例id和 网关的执行结构id 查询当前并行网关是否满足执行下一步的条件
+// mysql 用 for update 利用间隙锁 防止并发场景下的幻读
+// postgres 不能对聚合查询加锁，先锁住流程实例这一行再计数，读已提交隔离级别下后拿到锁的分支能看到先提交的分支
+// sqlite 的写事务本身就是串行的，不需要额外加锁
+func (service *SQLNodeService) CountParallelGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string) (int, error) {
+	count, err := service.countGatewayIncoming(tx, processInstanceId, executionId, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count parallel gateway incoming: %v", err)
+	}
+	return count, nil
+}
+
+// CountInclusiveGatewayIncoming 统计 id 大于 afterId 的汇聚记录
+func (service *SQLNodeService) CountInclusiveGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string, afterId int) (int, error) {
+	count, err := service.countGatewayIncoming(tx, processInstanceId, executionId, afterId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count inclusive gateway incoming: %v", err)
+	}
+	return count, nil
+}
+
+func (service *SQLNodeService) countGatewayIncoming(tx *sql.Tx, processInstanceId int, executionId string, afterId int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM node_instance
+		WHERE process_instance_id = ? AND execution_id = ? AND id > ?`
+	return service.dialect.lockedCount(tx, processInstanceId, query, processInstanceId, executionId, afterId)
+}
+
+// 节点实例查询的字段 和 scanNodeInstance 的顺序一致
+const nodeInstanceColumns = `id, process_instance_id, process_definition_name, node_name, execution_id, output_data, previous_execution_id, assignee, start_time, end_time, owner, delegation_state, original_assignee, due_date`
+
+// 扫描一行节点实例 输出数据、结束时间和到期时间可能为空
+func scanNodeInstance(scanner interface{ Scan(dest ...any) error }) (*NodeInstance, error) {
+	instance := &NodeInstance{}
+	var (
+		outputData          sql.NullString
+		previousExecutionId sql.NullString
+		endTime             sql.NullTime
+		owner               sql.NullString
+		delegationState     sql.NullString
+		originalAssignee    sql.NullString
+		dueDate             sql.NullTime
+	)
+	err := scanner.Scan(&instance.Id, &instance.ProcessInstanceId, &instance.ProcessDefinitionName, &instance.NodeName, &instance.ExecutionId, &outputData, &previousExecutionId, &instance.Assignee, &instance.StartTime, &endTime, &owner, &delegationState, &originalAssignee, &dueDate)
+	if err != nil {
+		return nil, err
+	}
+	instance.OutputData = outputData.String
+	instance.PreviousExecutionId = previousExecutionId.String
+	instance.EndTime = endTime.Time
+	instance.Owner = owner.String
+	instance.DelegationState = delegationState.String
+	instance.OriginalAssignee = originalAssignee.String
+	instance.DueDate = dueDate.Time
+	return instance, nil
+}
+
+// GetNodeInstanceById 根据Id获取节点实例
+func (service *SQLNodeService) GetNodeInstanceById(id int) (*NodeInstance, error) {
+	query := `SELECT ` + nodeInstanceColumns + ` FROM node_instance WHERE id = ?`
+	instance, err := scanNodeInstance(service.dialect.queryRow(service.DB, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get node instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// LockNodeInstance 在事务里读取并锁住节点实例，防止同一个任务被并发处理两次
+func (service *SQLNodeService) LockNodeInstance(tx *sql.Tx, id int) (*NodeInstance, error) {
+	query := `SELECT ` + nodeInstanceColumns + ` FROM node_instance WHERE id = ?` + service.dialect.forUpdate
+	instance, err := scanNodeInstance(service.dialect.queryRow(tx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock node instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// GetNodeInstancesByProcessInstanceId 根据流程实例Id获取节点实例列表
+func (service *SQLNodeService) GetNodeInstancesByProcessInstanceId(processInstanceId int) ([]*NodeInstance, error) {
+	query := `SELECT ` + nodeInstanceColumns + ` FROM node_instance WHERE process_instance_id = ?`
+	rows, err := service.dialect.query(service.DB, query, processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node instances by process instance Id: %v", err)
+	}
+	defer rows.Close()
+
+	var instances []*NodeInstance
+	for rows.Next() {
+		instance, err := scanNodeInstance(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan node instance: %v", err)
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// LockNodeInstancesByProcessInstanceId 在事务里读取并锁住流程实例的全部节点实例
+func (service *SQLNodeService) LockNodeInstancesByProcessInstanceId(tx *sql.Tx, processInstanceId int) ([]NodeInstance, error) {
+	query := `SELECT ` + nodeInstanceColumns + ` FROM node_instance WHERE process_instance_id = ? ORDER BY id` + service.dialect.forUpdate
+	rows, err := service.dialect.query(tx, query, processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock node instances by process instance Id: %v", err)
+	}
+	defer rows.Close()
+
+	var instances []NodeInstance
+	for rows.Next() {
+		instance, err := scanNodeInstance(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan node instance: %v", err)
+		}
+		instances = append(instances, *instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// GetLatestCompletedNodeInstance 查询某个结构id最近一次有输出数据的节点实例
+func (service *SQLNodeService) GetLatestCompletedNodeInstance(tx *sql.Tx, processInstanceId int, executionId string) (*NodeInstance, error) {
+	query := `SELECT ` + nodeInstanceColumns + ` FROM node_instance WHERE process_instance_id = ? AND execution_id = ? AND output_data IS NOT NULL ORDER BY id DESC LIMIT 1`
+	instance, err := scanNodeInstance(service.dialect.queryRow(tx, query, processInstanceId, executionId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest completed node instance: %v", err)
+	}
+	return instance, nil
+}
+
+// UpdateNodeInstanceOutput 更新节点实例的输出数据
+func (service *SQLNodeService) UpdateNodeInstanceOutput(tx *sql.Tx, id int, outputData string) error {
+	query := `
+        UPDATE node_instance
+        SET output_data = ?, end_time = ?
+        WHERE id = ?
+    `
+	_, err := service.dialect.exec(tx, query, outputData, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update node instance output: %v", err)
+	}
+	return nil
+}
+
+func (service *SQLNodeService) UpdateNodeInstanceAssignee(tx *sql.Tx, id int, assignee string) error {
+	_, err := service.dialect.exec(tx, `UPDATE node_instance SET assignee = ? WHERE id = ?`, assignee, id)
+	if err != nil {
+		return fmt.Errorf("failed to update node instance assignee: %v", err)
+	}
+	return nil
+}
+
+// UpdateNodeInstanceDelegation 修改审批节点的负责人、所有人和委派状态
+func (service *SQLNodeService) UpdateNodeInstanceDelegation(tx *sql.Tx, id int, assignee string, owner string, delegationState string) error {
+	query := `UPDATE node_instance SET assignee = ?, owner = ?, delegation_state = ? WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, assignee, sql.NullString{String: owner, Valid: owner != ""}, sql.NullString{String: delegationState, Valid: delegationState != ""}, id)
+	if err != nil {
+		return fmt.Errorf("failed to update node instance delegation: %v", err)
+	}
+	return nil
+}
+
+// UpdateNodeInstanceSubstitute 按代理规则把审批节点交给代理人 记录原来的负责人
+func (service *SQLNodeService) UpdateNodeInstanceSubstitute(tx *sql.Tx, id int, substitute string, originalAssignee string) error {
+	query := `UPDATE node_instance SET assignee = ?, original_assignee = ? WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, substitute, originalAssignee, id)
+	if err != nil {
+		return fmt.Errorf("failed to update node instance substitute: %v", err)
+	}
+	return nil
+}
+
+// UpdateNodeInstanceDueDate 记录审批节点的到期时间
+func (service *SQLNodeService) UpdateNodeInstanceDueDate(tx *sql.Tx, id int, dueDate time.Time) error {
+	query := `UPDATE node_instance SET due_date = ? WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, dueDate, id)
+	if err != nil {
+		return fmt.Errorf("failed to update node instance due date: %v", err)
+	}
+	return nil
+}
+
+func (service *SQLNodeService) GetAssigneeUndoneTask(assignee string) ([]map[string]interface{}, error) {
+	// 构建查询语句
+	query := `
+        SELECT id, process_instance_id,process_definition_name, node_name, execution_id, output_data, previous_execution_id, assignee, start_time, end_time, due_date
+        FROM node_instance
+        WHERE assignee = ? AND output_data IS NULL
+    `
+	return service.queryTasks(query, assignee)
+}
+
+// GetCandidateUndoneTask 用户的待办 包括直接分配的和可以认领的审批节点
+func (service *SQLNodeService) GetCandidateUndoneTask(userId string, groups []string) ([]map[string]interface{}, error) {
+	args := []any{userId, CANDIDATE_TYPE_USER, userId}
+	candidateFilter := `(c.candidate_type = ? AND c.candidate_id = ?)`
+	if len(groups) > 0 {
+		candidateFilter += ` OR (c.candidate_type = ? AND c.candidate_id IN (?` + strings.Repeat(", ?", len(groups)-1) + `))`
+		args = append(args, CANDIDATE_TYPE_GROUP)
+		for _, group := range groups {
+			args = append(args, group)
+		}
+	}
+	query := `
+        SELECT n.id, n.process_instance_id, n.process_definition_name, n.node_name, n.execution_id, n.output_data, n.previous_execution_id, n.assignee, n.start_time, n.end_time, n.due_date
+        FROM node_instance n
+        WHERE n.output_data IS NULL AND (n.assignee = ? OR (n.assignee = '' AND EXISTS (
+            SELECT 1 FROM node_candidate c WHERE c.node_instance_id = n.id AND (` + candidateFilter + `))))
+        ORDER BY n.id
+    `
+	return service.queryTasks(query, args...)
+}
+
+// 查询审批节点列表 每一行转成 map
+func (service *SQLNodeService) queryTasks(query string, args ...any) ([]map[string]interface{}, error) {
+	// 创建一个空的map数组用于存储结果
+	var results []map[string]interface{}
+
+	// 执行查询
+	rows, err := service.dialect.query(service.DB, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// 遍历查询结果
+	for rows.Next() {
+		var (
+			id                    int
+			processInstanceID     int
+			processDefinitionName string
+			nodeName              string
+			executionID           string
+			outputData            sql.NullString
+			previousExecutionID   sql.NullString
+			assignee              sql.NullString
+			startTime             sql.NullString
+			endTime               sql.NullString
+			dueDate               sql.NullTime
+		)
+
+		// 扫描每一行数据
+		err := rows.Scan(&id, &processInstanceID, &processDefinitionName, &nodeName, &executionID, &outputData, &previousExecutionID, &assignee, &startTime, &endTime, &dueDate)
+		if err != nil {
+			return nil, err
+		}
+
+		// 将数据放入map
+		result := map[string]interface{}{
+			"id":                      id,
+			"process_instance_id":     processInstanceID,
+			"process_definition_name": processDefinitionName,
+			"node_name":               nodeName,
+			"execution_id":            executionID,
+			"output_data":             outputData,
+			"previous_execution_id":   previousExecutionID,
+			"assignee":                assignee,
+			"start_time":              startTime,
+			"end_time":                endTime,
+			"due_date":                nilIfEmptyTime(dueDate),
+		}
+
+		// 将map放入结果数组
+		results = append(results, result)
+	}
+
+	// 检查是否有错误
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (service *SQLNodeService) GetTaskDetailByTaskId(taskId int) (map[string]interface{}, error) {
+	// 构建查询语句
+	query := `
+        SELECT id, process_instance_id,process_definition_name, node_name, execution_id, output_data, previous_execution_id, assignee, start_time, end_time, due_date
+        FROM node_instance
+        WHERE id = ?
+    `
+
+	// 执行查询
+	row := service.dialect.queryRow(service.DB, query, taskId)
+
+	// 定义用于接收查询结果的变量
+	var (
+		id                    int
+		processInstanceID     int
+		processDefinitionName string
+		nodeName              string
+		executionID           string
+		outputData            sql.NullString
+		previousExecutionID   sql.NullString
+		assignee              string
+		startTime             sql.NullTime
+		endTime               sql.NullTime
+		dueDate               sql.NullTime
+	)
+
+	// 扫描查询结果到变量中
+	err := row.Scan(&id, &processInstanceID, &processDefinitionName, &nodeName, &executionID, &outputData, &previousExecutionID, &assignee, &startTime, &endTime, &dueDate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("task with id %d not found", taskId)
+		}
+		return nil, fmt.Errorf("failed to get task detail: %v", err)
+	}
+
+	// 将查询结果赋值到 map 中
+	result := map[string]interface{}{
+		"id":                      id,
+		"process_instance_id":     processInstanceID,
+		"process_definition_name": processDefinitionName,
+		"node_name":               nodeName,
+		"execution_id":            executionID,
+		"output_data":             nilIfEmpty(outputData),
+		"previous_execution_id":   nilIfEmpty(previousExecutionID),
+		"assignee":                assignee,
+		"start_time":              nilIfEmptyTime(startTime),
+		"end_time":                nilIfEmptyTime(endTime),
+		"due_date":                nilIfEmptyTime(dueDate),
+	}
+
+	return result, nil
+}
+
+// DeleteNodeInstance 根据Id删除节点实例
+func (service *SQLNodeService) DeleteNodeInstance(tx *sql.Tx, id int) error {
+	if _, err := service.dialect.exec(tx, `DELETE FROM node_candidate WHERE node_instance_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete node candidates: %v", err)
+	}
+	query := ` DELETE FROM node_instance WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete node instance: %v", err)
+	}
+	return nil
+}
+
+// AddNodeCandidates 记录审批节点的候选人和候选组
+func (service *SQLNodeService) AddNodeCandidates(tx *sql.Tx, processInstanceId int, nodeInstanceId int, users []string, groups []string) error {
+	query := `INSERT INTO node_candidate (node_instance_id, process_instance_id, candidate_type, candidate_id) VALUES (?, ?, ?, ?)`
+	for _, user := range users {
+		if _, err := service.dialect.exec(tx, query, nodeInstanceId, processInstanceId, CANDIDATE_TYPE_USER, user); err != nil {
+			return fmt.Errorf("failed to add candidate user %s: %v", user, err)
+		}
+	}
+	for _, group := range groups {
+		if _, err := service.dialect.exec(tx, query, nodeInstanceId, processInstanceId, CANDIDATE_TYPE_GROUP, group); err != nil {
+			return fmt.Errorf("failed to add candidate group %s: %v", group, err)
+		}
+	}
+	return nil
+}
+
+// GetNodeCandidates 查询审批节点的候选人和候选组 按记录的顺序
+func (service *SQLNodeService) GetNodeCandidates(tx *sql.Tx, nodeInstanceId int) ([]string, []string, error) {
+	query := `SELECT candidate_type, candidate_id FROM node_candidate WHERE node_instance_id = ? ORDER BY id`
+	rows, err := service.dialect.query(tx, query, nodeInstanceId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query node candidates: %v", err)
+	}
+	defer rows.Close()
+
+	var users, groups []string
+	for rows.Next() {
+		var candidateType, candidate string
+		if err := rows.Scan(&candidateType, &candidate); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan node candidate: %v", err)
+		}
+		if candidateType == CANDIDATE_TYPE_GROUP {
+			groups = append(groups, candidate)
+		} else {
+			users = append(users, candidate)
+		}
+	}
+	return users, groups, rows.Err()
+}
+
+func (service *SQLNodeService) GetTaskForm(processDefinitionName string, executionId string) (string, error) {
+	model, err := getLatestModel(processDefinitionName)
+	if err != nil {
+		return "", err
+	}
+
+	formdata := model.Tasks[executionId].FormData
+	return formdata, nil
+}
+
+func (service *SQLNodeService) GetTaskFormByVersion(processDefinitionName string, version int, executionId string) (string, error) {
+	model, err := getModelByVersion(processDefinitionName, version)
+	if err != nil {
+		return "", err
+	}
+	formdata := model.Tasks[executionId].FormData
+	return formdata, nil
+}
+
+// GetProcessVariables 按id顺序读取已经有输出的节点 同一个结构id后面的覆盖前面的，和表达式取值的规则一致
+func (service *SQLNodeService) GetProcessVariables(tx *sql.Tx, processInstanceId int) (map[string]any, error) {
+	query := `SELECT execution_id, output_data FROM node_instance WHERE process_instance_id = ? AND output_data IS NOT NULL ORDER BY id`
+	rows, err := service.dialect.query(tx, query, processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query process variables: %v", err)
+	}
+	defer rows.Close()
+
+	variables := make(map[string]any)
+	for rows.Next() {
+		var executionId string
+		var outputData []byte
+		if err := rows.Scan(&executionId, &outputData); err != nil {
+			return nil, fmt.Errorf("failed to scan process variables: %v", err)
+		}
+		var value any
+		if err := json.Unmarshal(outputData, &value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal output data of %s: %v", executionId, err)
+		}
+		variables[executionId] = value
+	}
+	return variables, rows.Err()
+}
+
+func (service *SQLNodeService) ClearProcessData(tx *sql.Tx, processInstanceId int) error {
+	if _, err := service.dialect.exec(tx, `DELETE FROM node_candidate WHERE process_instance_id = ?`, processInstanceId); err != nil {
+		return fmt.Errorf("failed to delete node candidates: %v", err)
+	}
+	query := ` DELETE FROM node_instance WHERE process_instance_id = ?`
+	_, err := service.dialect.exec(tx, query, processInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to delete node instance: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SQLRepositoryService 是 RepositoryService 接口基于 database/sql 的实现
+type SQLRepositoryService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLRepositoryService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// SaveProcessDefinition 插入新的流程定义到数据库中
+func (service *SQLRepositoryService) SaveProcessDefinition(tx *sql.Tx, pd *ProcessDefinition) (int, error) {
+	if err := validateProcessDefinitionXML(pd.XMLContent); err != nil {
+		return 0, fmt.Errorf("failed to save process definition: %w", err)
+	}
+	query := `
+     INSERT INTO process_definition (process_definition_name, version, xml_content, created_at, created_by, status, description)
+SELECT 
+    ?,
+    COALESCE(MAX(version) + 1, 1),
+    ?, ?, ?, ?, ?
+FROM 
+    (SELECT * FROM process_definition WHERE process_definition_name = ?) AS pd
+    `
+	id, err := service.dialect.insert(tx, query, pd.ProcessDefinitionName, pd.XMLContent, pd.CreatedAt, pd.CreatedBy, pd.Status, pd.Description, pd.ProcessDefinitionName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save process definition: %v", err)
+	}
+	if err := service.invalidateModel(tx, id); err != nil {
+		return 0, fmt.Errorf("failed to save process definition: %v", err)
+	}
+
+	return id, nil
+}
+
+// GetProcessDefinitionById 根据Id获取流程定义
+func (service *SQLRepositoryService) GetProcessDefinitionById(id int) (*ProcessDefinition, error) {
+	query := `SELECT id, process_definition_name, version, xml_content, created_at, created_by, status, description FROM process_definition WHERE id = ?`
+	pd := &ProcessDefinition{}
+	err := service.dialect.queryRow(service.DB, query, id).Scan(&pd.Id, &pd.ProcessDefinitionName, &pd.Version, &pd.XMLContent, &pd.CreatedAt, &pd.CreatedBy, &pd.Status, &pd.Description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get process definition by Id: %v", err)
+	}
+	return pd, nil
+}
+
+// GetProcessDefinitionByNameAndVersion 根据流程名称和版本号获取流程定义
+func (service *SQLRepositoryService) GetProcessDefinitionByNameAndVersion(name string, version int) (*ProcessDefinition, error) {
+	query := `SELECT id, process_definition_name, version, xml_content, created_at, created_by, status, description FROM process_definition WHERE process_definition_name = ? AND version = ?`
+	pd := &ProcessDefinition{}
+	err := service.dialect.queryRow(service.DB, query, name, version).Scan(&pd.Id, &pd.ProcessDefinitionName, &pd.Version, &pd.XMLContent, &pd.CreatedAt, &pd.CreatedBy, &pd.Status, &pd.Description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get process definition by process_definition_name and version: %v", err)
+	}
+	return pd, nil
+}
+
+// GetLatestProcessDefinitionByName 根据流程名称获取最新流程定义
+func (service *SQLRepositoryService) GetLatestProcessDefinitionByName(name string) (*ProcessDefinition, error) {
+	query := `SELECT id, process_definition_name, version, xml_content, created_at, created_by, status, description FROM process_definition pd WHERE pd.version = (SELECT MAX(version)
+FROM process_definition WHERE process_definition_name = pd.process_definition_name) AND pd.process_definition_name = ? `
+	pd := &ProcessDefinition{}
+	err := service.dialect.queryRow(service.DB, query, name).Scan(&pd.Id, &pd.ProcessDefinitionName, &pd.Version, &pd.XMLContent, &pd.CreatedAt, &pd.CreatedBy, &pd.Status, &pd.Description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get process definition by name and version: %v", err)
+	}
+	return pd, nil
+}
+
+// GetLatestVersionByName 根据流程名称获取最新的版本号，没有部署过返回 0
+func (service *SQLRepositoryService) GetLatestVersionByName(name string) (int, error) {
+	query := `SELECT COALESCE(MAX(version), 0) FROM process_definition WHERE process_definition_name = ?`
+	var version int
+	if err := service.dialect.queryRow(service.DB, query, name).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get latest version of process definition: %v", err)
+	}
+	return version, nil
+}
+
+// UpdateProcessDefinition 更新流程定义 只允许改数据 不允许改结构 ，名称和版本都不变，这个限制得在前端做
+func (service *SQLRepositoryService) UpdateProcessDefinition(tx *sql.Tx, pd *ProcessDefinition) error {
+	if err := validateProcessDefinitionXML(pd.XMLContent); err != nil {
+		return fmt.Errorf("failed to update process definition: %w", err)
+	}
+	query := `
+        UPDATE process_definition
+        SET  xml_content = ?, created_by = ?
+        WHERE id = ?
+    `
+	_, err := service.dialect.exec(tx, query, pd.XMLContent, pd.CreatedBy, pd.Id)
+	if err != nil {
+		return fmt.Errorf("failed to update process definition: %v", err)
+	}
+	if err := service.invalidateModel(tx, pd.Id); err != nil {
+		return fmt.Errorf("failed to update process definition: %v", err)
+	}
+	return nil
+}
+
+// DeleteProcessDefinition 根据Id删除流程定义
+func (service *SQLRepositoryService) DeleteProcessDefinition(tx *sql.Tx, id int) error {
+	if err := service.invalidateModel(tx, id); err != nil {
+		return fmt.Errorf("failed to delete process definition: %v", err)
+	}
+	query := `DELETE FROM process_definition WHERE id = ?`
+	_, err := service.dialect.exec(tx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete process definition: %v", err)
+	}
+	return nil
+}
+
+// 查出流程定义的名称和版本 清掉缓存里对应的模型
+func (service *SQLRepositoryService) invalidateModel(tx *sql.Tx, id int) error {
+	query := `SELECT process_definition_name, version FROM process_definition WHERE id = ?`
+	var name string
+	var version int
+	err := service.dialect.queryRow(tx, query, id).Scan(&name, &version)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	InvalidateModel(name, version)
+	return nil
+}
@@ -0,0 +1,222 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLRuntimeService 是 RuntimeService 接口基于 database/sql 的实现，mysql postgres sqlite 共用，差异由 dialect 处理
+type SQLRuntimeService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLRuntimeService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// StartProcessInstance 创建一个新的流程实例
+func (service *SQLRuntimeService) StartProcessInstance(tx *sql.Tx, processDefinitionName string, business_key string, createdBy string, formParams string) (int, error) {
+	//判断是否有现成的 流程定义缓存
+	model, err := getLatestModel(processDefinitionName)
+	if err != nil {
+		return 0, finishTransaction(tx, err)
+	}
+	//在流程实例表里插入记录
+	query := `
+        INSERT INTO process_instance ( process_definition_name, version, status, created_by, business_key ,start_time)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `
+	startTime := time.Now()
+	id, err2 := service.dialect.insert(tx, query, processDefinitionName, model.Version, PROCESS_STATUS_RUNNING, createdBy, business_key, startTime)
+	if err2 != nil {
+		return 0, finishTransaction(tx, fmt.Errorf("%w: failed to start process instance: %v", ErrPersistenceFailed, err2))
+	}
+
+	if err := finishTransaction(tx, executeStartEvent(tx, model, id, processDefinitionName, createdBy, formParams)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (service *SQLRuntimeService) CompleteProcessInstance(tx *sql.Tx, ProcessInstanceId int) error {
+	err := service.UpdateProcessInstanceStatus(tx, ProcessInstanceId, PROCESS_STATUS_COMPLETE)
+	if err != nil {
+		return fmt.Errorf("failed to complete process instance, id: %d %v", ProcessInstanceId, err)
+	}
+	return nil
+}
+
+// DeleteProcessInstance 删除流程实例
+func (service *SQLRuntimeService) DeleteProcessInstance(tx *sql.Tx, id int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM process_instance WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete process instance, id: %d %v", id, err)
+	}
+	return nil
+}
+
+// UpdateProcessInstanceStatus 修改流程实例状态 完成和终止时同时记录结束时间
+func (service *SQLRuntimeService) UpdateProcessInstanceStatus(tx *sql.Tx, id int, status string) error {
+	var endTime sql.NullTime
+	if status == PROCESS_STATUS_COMPLETE || status == PROCESS_STATUS_TERMINATED {
+		endTime = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	query := `
+        UPDATE process_instance
+        SET status = ?, end_time = ?
+        WHERE id = ?
+    `
+	_, err := service.dialect.exec(tx, query, status, endTime, id)
+	if err != nil {
+		return fmt.Errorf("failed to update process instance status, id: %d %v", id, err)
+	}
+	return nil
+}
+
+// 流程实例查询的字段 和 scanProcessInstance 的顺序一致
+const processInstanceColumns = `id, process_definition_name, version, business_key, status, created_by, start_time, end_time`
+
+func scanProcessInstance(scanner interface{ Scan(dest ...any) error }) (*ProcessInstance, error) {
+	instance := &ProcessInstance{}
+	var (
+		createdBy sql.NullString
+		endTime   sql.NullTime
+	)
+	err := scanner.Scan(&instance.Id, &instance.ProcessDefinitionName, &instance.Version, &instance.Business_key, &instance.Status, &createdBy, &instance.StartTime, &endTime)
+	if err != nil {
+		return nil, err
+	}
+	instance.CreatedBy = createdBy.String
+	if endTime.Valid {
+		instance.EndTime = &endTime.Time
+	}
+	return instance, nil
+}
+
+// GetProcessInstanceById 根据id查询流程实例
+func (service *SQLRuntimeService) GetProcessInstanceById(id int) (*ProcessInstance, error) {
+	query := `SELECT ` + processInstanceColumns + ` FROM process_instance WHERE id = ?`
+	instance, err := scanProcessInstance(service.dialect.queryRow(service.DB, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get process instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// LockProcessInstance 在事务里读取并锁住流程实例
+func (service *SQLRuntimeService) LockProcessInstance(tx *sql.Tx, id int) (*ProcessInstance, error) {
+	query := `SELECT ` + processInstanceColumns + ` FROM process_instance WHERE id = ?` + service.dialect.forUpdate
+	instance, err := scanProcessInstance(service.dialect.queryRow(tx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock process instance by Id: %v", err)
+	}
+	return instance, nil
+}
+
+// TerminateProcessInstance 终止流程实例
+func (service *SQLRuntimeService) TerminateProcessInstance(processInstanceId int, reason string) error {
+	return terminateProcessInstance(service, processInstanceId, reason)
+}
+
+// SuspendProcessInstance 挂起流程实例
+func (service *SQLRuntimeService) SuspendProcessInstance(processInstanceId int) error {
+	return changeProcessInstanceStatus(service, processInstanceId, PROCESS_STATUS_RUNNING, PROCESS_STATUS_SUSPENDED)
+}
+
+// ResumeProcessInstance 恢复挂起的流程实例
+func (service *SQLRuntimeService) ResumeProcessInstance(processInstanceId int) error {
+	return changeProcessInstanceStatus(service, processInstanceId, PROCESS_STATUS_SUSPENDED, PROCESS_STATUS_RUNNING)
+}
+
+// ResolveIncident 恢复处于故障状态的流程实例
+func (service *SQLRuntimeService) ResolveIncident(processInstanceId int) error {
+	return changeProcessInstanceStatus(service, processInstanceId, PROCESS_STATUS_INCIDENT, PROCESS_STATUS_RUNNING)
+}
+
+// ClaimTask 候选人认领审批节点
+func (service *SQLRuntimeService) ClaimTask(taskId int, userId string) error {
+	return claimTask(service, taskId, userId)
+}
+
+// UnclaimTask 认领人把审批节点退回给候选人
+func (service *SQLRuntimeService) UnclaimTask(taskId int, userId string) error {
+	return unclaimTask(service, taskId, userId)
+}
+
+// GetUserTasks 查询用户的待办
+func (service *SQLRuntimeService) GetUserTasks(userId string) ([]map[string]interface{}, error) {
+	return getUserTasks(userId)
+}
+
+// GetUserTasksByDueDate 查询用户的待办 按到期时间排序
+func (service *SQLRuntimeService) GetUserTasksByDueDate(userId string) ([]map[string]interface{}, error) {
+	return getUserTasksByDueDate(userId)
+}
+
+// SetVariable 设置流程变量
+func (service *SQLRuntimeService) SetVariable(processInstanceId int, scope string, name string, value any, userId string) error {
+	return setProcessVariable(service, processInstanceId, scope, name, value, userId)
+}
+
+// GetVariables 查询某个作用域的全部流程变量
+func (service *SQLRuntimeService) GetVariables(processInstanceId int, scope string) (map[string]any, error) {
+	return getProcessVariables(processInstanceId, scope)
+}
+
+// DelegateTask 负责人把审批节点委派给另一个人
+func (service *SQLRuntimeService) DelegateTask(taskId int, userId string, delegate string, reason string) error {
+	return delegateTask(service, taskId, userId, delegate, reason)
+}
+
+// ResolveTask 委派的人处理完 审批节点回到委派人
+func (service *SQLRuntimeService) ResolveTask(taskId int, userId string, reason string) error {
+	return resolveTask(service, taskId, userId, reason)
+}
+
+// TransferTask 负责人把审批节点转办给另一个人
+func (service *SQLRuntimeService) TransferTask(taskId int, userId string, assignee string, reason string) error {
+	return transferTask(service, taskId, userId, assignee, reason)
+}
+
+// AddSigner 负责人在自己之前或者之后加签
+func (service *SQLRuntimeService) AddSigner(taskId int, userId string, signer string, position string, reason string) error {
+	return addSigner(service, taskId, userId, signer, position, reason)
+}
+
+// AddComment 在流程实例或者审批节点上评论
+func (service *SQLRuntimeService) AddComment(processInstanceId int, taskId int, userId string, message string) (int, error) {
+	return addComment(service, processInstanceId, taskId, userId, message)
+}
+
+// ListComments 查询评论
+func (service *SQLRuntimeService) ListComments(processInstanceId int, taskId int) ([]Comment, error) {
+	return listComments(processInstanceId, taskId)
+}
+
+// AddAttachment 记录附件的元数据
+func (service *SQLRuntimeService) AddAttachment(attachment Attachment) (int, error) {
+	return addAttachment(service, attachment)
+}
+
+// ListAttachments 查询附件
+func (service *SQLRuntimeService) ListAttachments(processInstanceId int, taskId int) ([]Attachment, error) {
+	return listAttachments(processInstanceId, taskId)
+}
+
+// CompleteTask 负责人完成审批节点
+func (service *SQLRuntimeService) CompleteTask(taskId int, userId string, output map[string]any) ([]NodeInstance, error) {
+	return completeTask(service, taskId, userId, output)
+}
+
+// SendBack 把审批节点打回到上游的审批节点
+func (service *SQLRuntimeService) SendBack(taskId int, targetExecutionId string, comment string) ([]NodeInstance, error) {
+	return sendBack(service, taskId, targetExecutionId, comment)
+}
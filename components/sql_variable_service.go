@@ -0,0 +1,194 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLVariableService 是 VariableService 接口基于 database/sql 的实现
+type SQLVariableService struct {
+	DB      *sql.DB
+	dialect *sqlDialect
+}
+
+func (service *SQLVariableService) GetTransaction() (*sql.Tx, error) {
+	return service.DB.Begin()
+}
+
+// 流程变量查询的字段 和 scanProcessVariable 的顺序一致
+const processVariableColumns = `id, process_instance_id, scope, name, var_type, var_value, updated_by, updated_at`
+
+func scanProcessVariable(scanner interface{ Scan(dest ...any) error }) (*ProcessVariable, error) {
+	variable := &ProcessVariable{}
+	var value string
+	var updatedBy sql.NullString
+	var updatedAt sql.NullTime
+	err := scanner.Scan(&variable.Id, &variable.ProcessInstanceId, &variable.Scope, &variable.Name, &variable.Type, &value, &updatedBy, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if variable.Value, err = decodeVariable(value); err != nil {
+		return nil, err
+	}
+	variable.UpdatedBy = updatedBy.String
+	variable.UpdatedAt = updatedAt.Time
+	return variable, nil
+}
+
+// SetVariable 先锁住已有的变量 存在时修改，不存在时新建，然后记录历史
+func (service *SQLVariableService) SetVariable(tx *sql.Tx, processInstanceId int, scope string, name string, value any, changedBy string) error {
+	varType, varValue, err := encodeVariable(name, value)
+	if err != nil {
+		return err
+	}
+	changedAt := time.Now()
+	query := `SELECT id FROM process_variable WHERE process_instance_id = ? AND scope = ? AND name = ?` + service.dialect.forUpdate
+	var id int
+	action := VARIABLE_ACTION_UPDATE
+	err = service.dialect.queryRow(tx, query, processInstanceId, scope, name).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		action = VARIABLE_ACTION_CREATE
+		query = `
+        INSERT INTO process_variable (process_instance_id, scope, name, var_type, var_value, updated_by, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`
+		if _, err := service.dialect.insert(tx, query, processInstanceId, scope, name, varType, varValue, changedBy, changedAt); err != nil {
+			return fmt.Errorf("failed to create process variable %s: %v", name, err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to lock process variable %s: %v", name, err)
+	default:
+		query = `UPDATE process_variable SET var_type = ?, var_value = ?, updated_by = ?, updated_at = ? WHERE id = ?`
+		if _, err := service.dialect.exec(tx, query, varType, varValue, changedBy, changedAt, id); err != nil {
+			return fmt.Errorf("failed to update process variable %s: %v", name, err)
+		}
+	}
+	return service.insertHistory(tx, processInstanceId, scope, name, varType, sql.NullString{String: varValue, Valid: true}, action, changedBy, changedAt)
+}
+
+func (service *SQLVariableService) insertHistory(tx *sql.Tx, processInstanceId int, scope string, name string, varType string, varValue sql.NullString, action string, changedBy string, changedAt time.Time) error {
+	query := `
+        INSERT INTO historic_variable (process_instance_id, scope, name, var_type, var_value, action, changed_by, changed_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := service.dialect.exec(tx, query, processInstanceId, scope, name, varType, varValue, action, changedBy, changedAt); err != nil {
+		return fmt.Errorf("failed to insert historic variable %s: %v", name, err)
+	}
+	return nil
+}
+
+// GetVariable 在事务里查询一个变量
+func (service *SQLVariableService) GetVariable(tx *sql.Tx, processInstanceId int, scope string, name string) (*ProcessVariable, error) {
+	query := `SELECT ` + processVariableColumns + ` FROM process_variable WHERE process_instance_id = ? AND scope = ? AND name = ?`
+	variable, err := scanProcessVariable(service.dialect.queryRow(tx, query, processInstanceId, scope, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get process variable %s: %v", name, err)
+	}
+	return variable, nil
+}
+
+// GetVariables 在事务里查询某个作用域的全部变量
+func (service *SQLVariableService) GetVariables(tx *sql.Tx, processInstanceId int, scope string) (map[string]any, error) {
+	query := `SELECT ` + processVariableColumns + ` FROM process_variable WHERE process_instance_id = ? AND scope = ?`
+	variables, err := service.queryVariables(tx, query, processInstanceId, scope)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(variables))
+	for _, variable := range variables {
+		result[variable.Name] = variable.Value
+	}
+	return result, nil
+}
+
+// GetProcessInstanceVariables 查询流程实例的全部变量
+func (service *SQLVariableService) GetProcessInstanceVariables(processInstanceId int) ([]ProcessVariable, error) {
+	query := `SELECT ` + processVariableColumns + ` FROM process_variable WHERE process_instance_id = ? ORDER BY scope, name`
+	return service.queryVariables(service.DB, query, processInstanceId)
+}
+
+func (service *SQLVariableService) GetAllVariables(tx *sql.Tx, processInstanceId int) ([]ProcessVariable, error) {
+	query := `SELECT ` + processVariableColumns + ` FROM process_variable WHERE process_instance_id = ? ORDER BY scope, name`
+	return service.queryVariables(tx, query, processInstanceId)
+}
+
+func (service *SQLVariableService) queryVariables(executor sqlExecutor, query string, args ...any) ([]ProcessVariable, error) {
+	rows, err := service.dialect.query(executor, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query process variables: %v", err)
+	}
+	defer rows.Close()
+
+	var variables []ProcessVariable
+	for rows.Next() {
+		variable, err := scanProcessVariable(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan process variable: %v", err)
+		}
+		variables = append(variables, *variable)
+	}
+	return variables, rows.Err()
+}
+
+// DeleteVariablesByScope 删除某个作用域的全部变量 每个变量记录一条删除的历史
+func (service *SQLVariableService) DeleteVariablesByScope(tx *sql.Tx, processInstanceId int, scope string, changedBy string) error {
+	query := `SELECT ` + processVariableColumns + ` FROM process_variable WHERE process_instance_id = ? AND scope = ? ORDER BY name` + service.dialect.forUpdate
+	variables, err := service.queryVariables(tx, query, processInstanceId, scope)
+	if err != nil {
+		return err
+	}
+	changedAt := time.Now()
+	for _, variable := range variables {
+		if err := service.insertHistory(tx, processInstanceId, scope, variable.Name, variable.Type, sql.NullString{}, VARIABLE_ACTION_DELETE, changedBy, changedAt); err != nil {
+			return err
+		}
+	}
+	_, err = service.dialect.exec(tx, `DELETE FROM process_variable WHERE process_instance_id = ? AND scope = ?`, processInstanceId, scope)
+	if err != nil {
+		return fmt.Errorf("failed to delete process variables of scope %s: %v", scope, err)
+	}
+	return nil
+}
+
+// DeleteVariablesByProcessInstance 删除流程实例的全部变量
+func (service *SQLVariableService) DeleteVariablesByProcessInstance(tx *sql.Tx, processInstanceId int) error {
+	_, err := service.dialect.exec(tx, `DELETE FROM process_variable WHERE process_instance_id = ?`, processInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to delete process variables: %v", err)
+	}
+	return nil
+}
+
+// GetVariableHistory 查询变量的修改历史
+func (service *SQLVariableService) GetVariableHistory(processInstanceId int) ([]HistoricVariable, error) {
+	query := `
+        SELECT id, process_instance_id, scope, name, var_type, var_value, action, changed_by, changed_at
+        FROM historic_variable
+        WHERE process_instance_id = ?
+        ORDER BY id`
+	rows, err := service.dialect.query(service.DB, query, processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query historic variables: %v", err)
+	}
+	defer rows.Close()
+
+	var history []HistoricVariable
+	for rows.Next() {
+		var variable HistoricVariable
+		var value, changedBy sql.NullString
+		if err := rows.Scan(&variable.Id, &variable.ProcessInstanceId, &variable.Scope, &variable.Name, &variable.Type, &value, &variable.Action, &changedBy, &variable.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan historic variable: %v", err)
+		}
+		if value.Valid {
+			if variable.Value, err = decodeVariable(value.String); err != nil {
+				return nil, err
+			}
+		}
+		variable.ChangedBy = changedBy.String
+		history = append(history, variable)
+	}
+	return history, rows.Err()
+}
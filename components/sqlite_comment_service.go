@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteCommentService 是 CommentService 接口的 SQLite 实现
+type SQLiteCommentService struct {
+	*SQLCommentService
+}
+
+var sqliteCommentServiceInstance *SQLiteCommentService
+var sqliteCommentServiceOnce sync.Once
+
+// InitializeSQLiteCommentService 初始化单例实例
+func InitializeSQLiteCommentService(db *sql.DB) {
+	sqliteCommentServiceOnce.Do(func() {
+		sqliteCommentServiceInstance = &SQLiteCommentService{&SQLCommentService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteCommentService 获取单例实例
+func GetSQLiteCommentService() *SQLiteCommentService {
+	if sqliteCommentServiceInstance == nil {
+		panic("SQLiteCommentService is not initialized. Call InitializeSQLiteCommentService first.")
+	}
+	return sqliteCommentServiceInstance
+}
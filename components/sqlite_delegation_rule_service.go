@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteDelegationRuleService 是 DelegationRuleService 接口的 SQLite 实现
+type SQLiteDelegationRuleService struct {
+	*SQLDelegationRuleService
+}
+
+var sqliteDelegationRuleServiceInstance *SQLiteDelegationRuleService
+var sqliteDelegationRuleServiceOnce sync.Once
+
+// InitializeSQLiteDelegationRuleService 初始化单例实例
+func InitializeSQLiteDelegationRuleService(db *sql.DB) {
+	sqliteDelegationRuleServiceOnce.Do(func() {
+		sqliteDelegationRuleServiceInstance = &SQLiteDelegationRuleService{&SQLDelegationRuleService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteDelegationRuleService 获取单例实例
+func GetSQLiteDelegationRuleService() *SQLiteDelegationRuleService {
+	if sqliteDelegationRuleServiceInstance == nil {
+		panic("SQLiteDelegationRuleService is not initialized. Call InitializeSQLiteDelegationRuleService first.")
+	}
+	return sqliteDelegationRuleServiceInstance
+}
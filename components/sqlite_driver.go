@@ -0,0 +1,6 @@
+//go:build cgo
+
+package components
+
+// SQLite 数据库驱动 需要开启 cgo，关闭 cgo 时不注册，调用方自行导入驱动
+import _ "github.com/mattn/go-sqlite3"
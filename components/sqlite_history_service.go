@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteHistoryService 是 HistoryService 接口的 SQLite 实现
+type SQLiteHistoryService struct {
+	*SQLHistoryService
+}
+
+var sqliteHistoryServiceInstance *SQLiteHistoryService
+var sqliteHistoryServiceOnce sync.Once
+
+// InitializeSQLiteHistoryService 初始化单例实例
+func InitializeSQLiteHistoryService(db *sql.DB) {
+	sqliteHistoryServiceOnce.Do(func() {
+		sqliteHistoryServiceInstance = &SQLiteHistoryService{&SQLHistoryService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteHistoryService 获取单例实例
+func GetSQLiteHistoryService() *SQLiteHistoryService {
+	if sqliteHistoryServiceInstance == nil {
+		panic("SQLiteHistoryService is not initialized. Call InitializeSQLiteHistoryService first.")
+	}
+	return sqliteHistoryServiceInstance
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteJobService 是 JobService 接口的 SQLite 实现
+type SQLiteJobService struct {
+	*SQLJobService
+}
+
+var sqliteJobServiceInstance *SQLiteJobService
+var sqliteJobServiceOnce sync.Once
+
+// InitializeSQLiteJobService 初始化单例实例
+func InitializeSQLiteJobService(db *sql.DB) {
+	sqliteJobServiceOnce.Do(func() {
+		sqliteJobServiceInstance = &SQLiteJobService{&SQLJobService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteJobService 获取单例实例
+func GetSQLiteJobService() *SQLiteJobService {
+	if sqliteJobServiceInstance == nil {
+		panic("SQLiteJobService is not initialized. Call InitializeSQLiteJobService first.")
+	}
+	return sqliteJobServiceInstance
+}
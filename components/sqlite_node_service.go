@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteNodeService 是 NodeService 接口的 SQLite 实现
+type SQLiteNodeService struct {
+	*SQLNodeService
+}
+
+var sqliteNodeServiceInstance *SQLiteNodeService
+var sqliteNodeServiceOnce sync.Once
+
+// InitializeSQLiteNodeService 初始化单例实例
+func InitializeSQLiteNodeService(db *sql.DB) {
+	sqliteNodeServiceOnce.Do(func() {
+		sqliteNodeServiceInstance = &SQLiteNodeService{&SQLNodeService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteNodeService 获取单例实例
+func GetSQLiteNodeService() *SQLiteNodeService {
+	if sqliteNodeServiceInstance == nil {
+		panic("SQLiteNodeService is not initialized. Call InitializeSQLiteNodeService first.")
+	}
+	return sqliteNodeServiceInstance
+}
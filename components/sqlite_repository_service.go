@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteRepositoryService 是 RepositoryService 接口的 SQLite 实现
+type SQLiteRepositoryService struct {
+	*SQLRepositoryService
+}
+
+var sqliteRepositoryServiceInstance *SQLiteRepositoryService
+var sqliteRepositoryServiceOnce sync.Once
+
+// InitializeSQLiteRepositoryService 初始化单例实例
+func InitializeSQLiteRepositoryService(db *sql.DB) {
+	sqliteRepositoryServiceOnce.Do(func() {
+		sqliteRepositoryServiceInstance = &SQLiteRepositoryService{&SQLRepositoryService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteRepositoryService 获取单例实例
+func GetSQLiteRepositoryService() *SQLiteRepositoryService {
+	if sqliteRepositoryServiceInstance == nil {
+		panic("SQLiteRepositoryService is not initialized. Call InitializeSQLiteRepositoryService first.")
+	}
+	return sqliteRepositoryServiceInstance
+}
@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteRuntimeService 是 RuntimeService 接口的 SQLite 实现
+type SQLiteRuntimeService struct {
+	*SQLRuntimeService
+}
+
+var sqliteRuntimeServiceInstance *SQLiteRuntimeService
+var sqliteRuntimeServiceOnce sync.Once
+
+// InitializeSQLiteRuntimeService 初始化单例实例
+func InitializeSQLiteRuntimeService(db *sql.DB) {
+	sqliteRuntimeServiceOnce.Do(func() {
+		sqliteRuntimeServiceInstance = &SQLiteRuntimeService{&SQLRuntimeService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteRuntimeService 获取单例实例
+func GetSQLiteRuntimeService() *SQLiteRuntimeService {
+	if sqliteRuntimeServiceInstance == nil {
+		panic("SQLiteRuntimeService is not initialized. Call InitializeSQLiteRuntimeService first.")
+	}
+	return sqliteRuntimeServiceInstance
+}
@@ -0,0 +1,50 @@
+package components
+
+import (
+	"database/sql"
+)
+
+type SQLiteServiceFactory struct{}
+
+func (f *SQLiteServiceFactory) InitServiceInstance(db *sql.DB) {
+	InitializeSQLiteRuntimeService(db)
+	InitializeSQLiteRepositoryService(db)
+	InitializeSQLiteNodeService(db)
+	InitializeSQLiteHistoryService(db)
+	InitializeSQLiteJobService(db)
+	InitializeSQLiteVariableService(db)
+	InitializeSQLiteDelegationRuleService(db)
+	InitializeSQLiteCommentService(db)
+}
+
+func (f *SQLiteServiceFactory) GetRuntimeService() RuntimeService {
+	return GetSQLiteRuntimeService()
+}
+
+func (f *SQLiteServiceFactory) GetRepositoryService() RepositoryService {
+	return GetSQLiteRepositoryService()
+}
+
+func (f *SQLiteServiceFactory) GetNodeService() NodeService {
+	return GetSQLiteNodeService()
+}
+
+func (f *SQLiteServiceFactory) GetHistoryService() HistoryService {
+	return GetSQLiteHistoryService()
+}
+
+func (f *SQLiteServiceFactory) GetJobService() JobService {
+	return GetSQLiteJobService()
+}
+
+func (f *SQLiteServiceFactory) GetVariableService() VariableService {
+	return GetSQLiteVariableService()
+}
+
+func (f *SQLiteServiceFactory) GetDelegationRuleService() DelegationRuleService {
+	return GetSQLiteDelegationRuleService()
+}
+
+func (f *SQLiteServiceFactory) GetCommentService() CommentService {
+	return GetSQLiteCommentService()
+}
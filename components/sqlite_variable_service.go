@@ -0,0 +1,29 @@
+package components
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// SQLiteVariableService 是 VariableService 接口的 SQLite 实现
+type SQLiteVariableService struct {
+	*SQLVariableService
+}
+
+var sqliteVariableServiceInstance *SQLiteVariableService
+var sqliteVariableServiceOnce sync.Once
+
+// InitializeSQLiteVariableService 初始化单例实例
+func InitializeSQLiteVariableService(db *sql.DB) {
+	sqliteVariableServiceOnce.Do(func() {
+		sqliteVariableServiceInstance = &SQLiteVariableService{&SQLVariableService{DB: db, dialect: sqliteDialect}}
+	})
+}
+
+// GetSQLiteVariableService 获取单例实例
+func GetSQLiteVariableService() *SQLiteVariableService {
+	if sqliteVariableServiceInstance == nil {
+		panic("SQLiteVariableService is not initialized. Call InitializeSQLiteVariableService first.")
+	}
+	return sqliteVariableServiceInstance
+}
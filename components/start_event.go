@@ -1,7 +1,9 @@
 package components
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 type StartEvent struct {
@@ -16,35 +18,45 @@ type StartEvent struct {
 	Listener    string `xml:"Listener"` // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
 }
 
-func (startEvent StartEvent) Execute(ctx *WorkflowContext) {
+func (startEvent StartEvent) Execute(ctx *WorkflowContext) error {
 	//持久化 新建工作流的输入数据
 	nodeService := GetServiceFactory().GetNodeService()
 	tx := ctx.Tx
 	if tx == nil {
-		log.Println("Failed to get transaction from ctx ")
-		return
+		return errNilTransaction(startEvent.ExecutionId)
 	}
 	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, startEvent.Name, startEvent.ExecutionId, "", ctx.CurrentUserId)
 	//迁徙数据到历史库
 	if initerr != nil {
-		log.Println("Failed to insert startEvent to database: ", initerr)
-		tx.Rollback()
-		return
+		return newExecutionError(startEvent.ExecutionId, ErrPersistenceFailed, initerr)
 	}
 	historyService := GetServiceFactory().GetHistoryService()
 	_, he := historyService.CopyNodeInstance(tx, nodeId, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, startEvent.Name, startEvent.ExecutionId, "", ctx.CurrentUserId)
 	if he != nil {
-		log.Println("Failed to insert startEvent to database: ", he)
-		tx.Rollback()
-		return
+		return newExecutionError(startEvent.ExecutionId, ErrPersistenceFailed, he)
+	}
+
+	//启动表单的数据先按 FormData 校验 再保存为流程实例作用域的变量
+	if err := validateFormData(startEvent.ExecutionId, startEvent.FormData, ctx.Data); err != nil {
+		return newExecutionError(startEvent.ExecutionId, ErrInvalidInput, err)
+	}
+	if strings.TrimSpace(ctx.Data) != "" {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(ctx.Data), &fields); err != nil {
+			return newExecutionError(startEvent.ExecutionId, ErrInvalidInput, fmt.Errorf("form params must be a JSON object: %v", err))
+		}
+		if err := saveOutputVariables(ctx, VARIABLE_SCOPE_PROCESS, ctx.Data); err != nil {
+			return newExecutionError(startEvent.ExecutionId, ErrPersistenceFailed, err)
+		}
 	}
 
 	//流程运转
 	ctx.Tx = tx
 	ctx.CurrentExecutionId = startEvent.ExecutionId
 
-	RunListener(startEvent.Listener, ctx)
+	if listenerErr := RunListener(startEvent.Listener, ctx); listenerErr != nil {
+		return newExecutionError(startEvent.ExecutionId, ErrListenerFailed, listenerErr)
+	}
 
-	sequenceFlow := ctx.Model.SequenceFlows[startEvent.Outgoing]
-	sequenceFlow.Execute(ctx)
+	return executeSequenceFlow(ctx, startEvent.Outgoing)
 }
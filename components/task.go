@@ -2,7 +2,9 @@ package components
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"strings"
+	"time"
 )
 
 // Task 代表 BPMN 中的审批节点
@@ -20,104 +22,190 @@ type Task struct {
 	H            string   `xml:"h,attr"`
 	W            string   `xml:"w,attr"`
 	Listener     string   `xml:"Listener"` // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
+	// 边界定时器 审批节点创建时开始计时
+	BoundaryTimers []BoundaryTimer `xml:"BoundaryTimer"`
+	// 审批期限 审批节点创建后多久到期，写法同 BoundaryTimer 的 duration，为空时取最早到期的边界定时器
+	DueDate string `xml:"dueDate,attr"`
+	// 多实例（会签）配置 为空时是普通的单人审批节点
+	MultiInstance *MultiInstance `xml:"MultiInstance"`
+	// 候选人和候选组 逗号分隔，没有指定负责人时审批节点由候选人认领
+	CandidateUsers  string `xml:"candidateUsers,attr"`
+	CandidateGroups string `xml:"candidateGroups,attr"`
 }
 
-// Execute 是 Task 节点的执行方法
-func (task Task) Execute(ctx *WorkflowContext) {
-	//初始化数据库状态
-	nodeService := GetServiceFactory().GetNodeService()
+// Execute 是 Task 节点的执行方法 初始化审批节点后流程停在这里，等待负责人完成
+func (task Task) Execute(ctx *WorkflowContext) error {
 	//ctx是从上一个节点传递进来的，所以它的CurrentExecutionId就是上级节点的Id,因为task节点可能有多个输入 所以得从ctx拿上级节点,然后上个节点的输出数据也是从ctx拿
 	//因为所有的这些task节点 都是缓存里的 最新的实时数据 所以直接用就行了
-	assigneePeopleName := GetAssigneePeopleName(task.AssigneeType, task.AssigneeKey)
-	tx := ctx.Tx
-	if tx == nil {
-		log.Println("Failed to get transaction from ctx ")
-		return
+	if ctx.Tx == nil {
+		return errNilTransaction(task.ExecutionId)
 	}
-	_, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, task.Name, task.ExecutionId, ctx.CurrentExecutionId, assigneePeopleName)
-	if initerr != nil {
-		log.Println("Failed to InitNodeInstance from database: ", initerr)
-		tx.Rollback()
-		return
+	if task.MultiInstance != nil {
+		return task.executeMultiInstance(ctx)
 	}
+	// 只配置了候选人时 负责人为空，等候选人认领
+	var assignment Assignment
+	if task.AssigneeType != "" {
+		resolved, err := resolveAssignment(ctx, task.AssigneeType, task.AssigneeKey)
+		if err != nil {
+			return newExecutionError(task.ExecutionId, ErrAssigneeFailed, err)
+		}
+		assignment = resolved
+	}
+	assignment.CandidateUsers = append(assignment.CandidateUsers, splitCandidates(task.CandidateUsers)...)
+	assignment.CandidateGroups = append(assignment.CandidateGroups, splitCandidates(task.CandidateGroups)...)
+	if assignment.empty() {
+		return newExecutionError(task.ExecutionId, ErrAssigneeFailed, fmt.Errorf("task has no assigneeType and no candidates"))
+	}
+	// 流程停在审批节点 事务由流程入口统一提交，并行网关分发的多个审批节点因此在同一个事务里
+	return task.initTaskInstance(ctx, ctx.CurrentExecutionId, assignment)
+}
 
-	// 此时流程停止 且上级节点不是并行网关 才可以提交事务，否则此时提交会导致并行网关无法将全部子任务分发
-	frontNodeIsParallelGateway := false
-	target := ctx.CurrentExecutionId
-
-	for _, gateway := range ctx.Model.ParallelGateways {
-		if gateway.ExecutionId == target {
-			frontNodeIsParallelGateway = true // 找到匹配的字符串
+// 插入一条审批节点实例并开始边界定时器的计时 多实例的审批节点每个负责人一条
+func (task Task) initTaskInstance(ctx *WorkflowContext, previousExecutionId string, assignment Assignment) error {
+	//初始化数据库状态
+	nodeService := GetServiceFactory().GetNodeService()
+	tx := ctx.Tx
+	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, task.Name, task.ExecutionId, previousExecutionId, assignment.Assignee)
+	if initerr != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, initerr)
+	}
+	// 负责人不在时按代理规则交给代理人 原来的负责人单独记录，不占用委派和加签用的所有人
+	node := &NodeInstance{Id: nodeId, ProcessInstanceId: ctx.ProcessInstanceId, ExecutionId: task.ExecutionId, Assignee: assignment.Assignee}
+	if err := applyDelegationRules(ctx, node); err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	dueDate, err := task.dueDate()
+	if err != nil {
+		return newExecutionError(task.ExecutionId, ErrInvalidInput, err)
+	}
+	if !dueDate.IsZero() {
+		if err := nodeService.UpdateNodeInstanceDueDate(tx, nodeId, dueDate); err != nil {
+			return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
 		}
 	}
+	if len(assignment.CandidateUsers) > 0 || len(assignment.CandidateGroups) > 0 {
+		if err := nodeService.AddNodeCandidates(tx, ctx.ProcessInstanceId, nodeId, assignment.CandidateUsers, assignment.CandidateGroups); err != nil {
+			return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+		}
+	}
+	ctx.NewTasks = append(ctx.NewTasks, NodeInstance{
+		Id:                    nodeId,
+		ProcessInstanceId:     ctx.ProcessInstanceId,
+		ProcessDefinitionName: ctx.ProcessDefinitionName,
+		NodeName:              task.Name,
+		ExecutionId:           task.ExecutionId,
+		PreviousExecutionId:   previousExecutionId,
+		Assignee:              node.Assignee,
+		StartTime:             time.Now(),
+		OriginalAssignee:      node.OriginalAssignee,
+		DueDate:               dueDate,
+	})
+	//边界定时器从审批节点创建时开始计时
+	for _, timer := range task.BoundaryTimers {
+		if err := scheduleTimer(tx, ctx.ProcessInstanceId, nodeId, timer.ExecutionId, timer.Action, timer.Duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if !frontNodeIsParallelGateway {
-		tx.Commit()
+// 审批节点的到期时间 从 Clock 取当前时间，没有配置期限也没有边界定时器时返回零值
+func (task Task) dueDate() (time.Time, error) {
+	durations := []string{task.DueDate}
+	if strings.TrimSpace(task.DueDate) == "" {
+		durations = durations[:0]
+		for _, timer := range task.BoundaryTimers {
+			durations = append(durations, timer.Duration)
+		}
+	}
+	var dueDate time.Time
+	for _, duration := range durations {
+		wait, err := parseTimerDuration(duration)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if due := now().Add(wait); dueDate.IsZero() || due.Before(dueDate) {
+			dueDate = due
+		}
 	}
+	return dueDate, nil
 }
 
 // 修改审批节点状态 把当前节点表单提交的数据放ctx.Data再传递下去
 // 前端通过页面 调用接口 查询负责人需要审批的节点 去操作这个方法 表单可以直接从缓存拿
-func (task Task) Complete(ctx *WorkflowContext) {
+// Complete 不提交事务，调用方根据返回的错误提交或者回滚 ctx.Tx
+func (task Task) Complete(ctx *WorkflowContext) error {
 	//更新数据库的输出数据 因为可能存在循环 流程实例和结构id不足以判断唯一性 只有用自增id了
 	//需要前端传递task的id 前端把该用户在中流程output为空的数据查询出来
 	frontData, err := ParseJSON(ctx.Data)
-
 	if err != nil {
-		log.Println("Failed to ParseJSON attribute:", ctx.Data)
-		return
+		return newExecutionError(task.ExecutionId, ErrInvalidInput, fmt.Errorf("failed to parse task data %s: %v", ctx.Data, err))
 	}
 	idFloat64, _ := frontData["taskid"].(float64)
 	id := int(idFloat64)
 
 	dataBytes, _ := json.Marshal(frontData["outputData"])
 	data := string(dataBytes)
+	if err := validateFormData(task.ExecutionId, task.FormData, data); err != nil {
+		return newExecutionError(task.ExecutionId, ErrInvalidInput, err)
+	}
 
+	return task.completeNode(ctx, id, data)
+}
+
+// 保存审批节点的输出 迁移到历史表后继续执行后续的序列流
+func (task Task) completeNode(ctx *WorkflowContext, id int, data string) error {
 	nodeService := GetServiceFactory().GetNodeService()
 	tx := ctx.Tx
 	if tx == nil {
-		log.Println("Failed to get transaction from ctx ")
-		return
+		return errNilTransaction(task.ExecutionId)
 	}
 
 	updateerr := nodeService.UpdateNodeInstanceOutput(tx, id, data)
 	if updateerr != nil {
-		log.Println("Failed to update task from database: ", updateerr)
-		tx.Rollback()
-		return
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, updateerr)
+	}
+	//审批节点完成后 挂在上面的定时器不再需要
+	if len(task.BoundaryTimers) > 0 {
+		if err := GetServiceFactory().GetJobService().DeleteTimerJobsByNodeInstance(tx, id); err != nil {
+			return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+		}
 	}
-
-	ctx.CurrentExecutionId = task.ExecutionId
 	//迁徙数据到历史库
 	historyService := GetServiceFactory().GetHistoryService()
 	copyerr := historyService.CopyNodeInstanceById(tx, id)
 	if copyerr != nil {
-		log.Println("Failed to copynode to history: ", copyerr)
-		tx.Rollback()
-		return
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, copyerr)
 	}
+	// 多实例的审批节点 汇总结果，满足完成条件之后才往下走
+	if task.MultiInstance != nil {
+		return task.completeMultiInstance(ctx, id, data)
+	}
+	if err := saveOutputVariables(ctx, task.ExecutionId, data); err != nil {
+		return newExecutionError(task.ExecutionId, ErrPersistenceFailed, err)
+	}
+	return task.leave(ctx)
+}
 
+// 审批节点整体完成 执行监听后走出线
+func (task Task) leave(ctx *WorkflowContext) error {
+	ctx.CurrentExecutionId = task.ExecutionId
 	//执行监听
-	RunListener(task.Listener, ctx)
+	if listenerErr := RunListener(task.Listener, ctx); listenerErr != nil {
+		return newExecutionError(task.ExecutionId, ErrListenerFailed, listenerErr)
+	}
 
 	//执行下一个 或者多个 序列流
 	for _, value := range task.Outgoing {
-		model := *(ctx.Model)
-		model.SequenceFlows[value].Execute(ctx)
-	}
-
-}
-
-func GetAssigneePeopleName(AssigneeType string, AssigneeKey string) string {
-
-	if AssigneeType == ASSIGNEETYPE_NAME {
-		return AssigneeKey
-	} else if AssigneeType == ASSIGNEETYPE_COMPANY {
-		return parentCompany(AssigneeKey)
+		if err := executeSequenceFlow(ctx, value); err != nil {
+			return err
+		}
+		ctx.CurrentExecutionId = task.ExecutionId
 	}
-	return "sc"
+	return nil
 }
 
-func parentCompany(param1 string) string {
-	return param1
+func (task Task) hasCandidates() bool {
+	return len(splitCandidates(task.CandidateUsers)) > 0 || len(splitCandidates(task.CandidateGroups)) > 0
 }
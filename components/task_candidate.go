@@ -0,0 +1,182 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 审批节点候选的类型
+const (
+	CANDIDATE_TYPE_USER  = "user"
+	CANDIDATE_TYPE_GROUP = "group"
+)
+
+// UserGroupsProvider 查询用户所属的组 计算待办和认领时判断用户是不是候选组的成员
+type UserGroupsProvider interface {
+	GetUserGroups(userId string) ([]string, error)
+}
+
+// UserGroupsFunc 让普通函数实现 UserGroupsProvider
+type UserGroupsFunc func(userId string) ([]string, error)
+
+func (f UserGroupsFunc) GetUserGroups(userId string) ([]string, error) {
+	return f(userId)
+}
+
+type noUserGroups struct{}
+
+func (noUserGroups) GetUserGroups(string) ([]string, error) {
+	return nil, nil
+}
+
+var (
+	currentUserGroupsProvider UserGroupsProvider = noUserGroups{}
+	userGroupsMutex           sync.RWMutex
+)
+
+// SetUserGroupsProvider 设置查询用户所属组的实现 业务服务在启动时调用，传 nil 恢复为所有用户都不属于任何组
+func SetUserGroupsProvider(provider UserGroupsProvider) {
+	userGroupsMutex.Lock()
+	defer userGroupsMutex.Unlock()
+	if provider == nil {
+		provider = noUserGroups{}
+	}
+	currentUserGroupsProvider = provider
+}
+
+func getUserGroups(userId string) ([]string, error) {
+	userGroupsMutex.RLock()
+	provider := currentUserGroupsProvider
+	userGroupsMutex.RUnlock()
+	groups, err := provider.GetUserGroups(userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups of user %s: %v", userId, err)
+	}
+	return groups, nil
+}
+
+// 逗号分隔的候选人或者候选组 去掉空白和重复
+func splitCandidates(value string) []string {
+	var candidates []string
+	for _, candidate := range strings.Split(value, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate != "" && !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// 各数据库实现共用的待办查询逻辑 用户自己的审批节点加上用户和用户所在的组可以认领的审批节点
+func getUserTasks(userId string) ([]map[string]interface{}, error) {
+	groups, err := getUserGroups(userId)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := GetServiceFactory().GetNodeService().GetCandidateUndoneTask(userId, groups)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return tasks, nil
+}
+
+// 待办按到期时间排序 到期时间相同或者都没有时保持按id的顺序
+func getUserTasksByDueDate(userId string) ([]map[string]interface{}, error) {
+	tasks, err := getUserTasks(userId)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		left, leftOk := tasks[i]["due_date"].(time.Time)
+		right, rightOk := tasks[j]["due_date"].(time.Time)
+		if leftOk && rightOk {
+			return left.Before(right)
+		}
+		return leftOk && !rightOk
+	})
+	return tasks, nil
+}
+
+// 各数据库实现共用的认领逻辑 锁住流程实例和审批节点后再判断，同一个审批节点只有一个人能认领成功
+func claimTask(runtimeService RuntimeService, taskId int, userId string) error {
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, claimTaskInTx(runtimeService, tx, taskId, userId))
+}
+
+func claimTaskInTx(runtimeService RuntimeService, tx *sql.Tx, taskId int, userId string) error {
+	if strings.TrimSpace(userId) == "" {
+		return fmt.Errorf("%w: user id must not be empty", ErrInvalidInput)
+	}
+	nodeService := GetServiceFactory().GetNodeService()
+	node, _, _, err := lockActiveTask(runtimeService, nodeService, tx, taskId)
+	if err != nil {
+		return err
+	}
+	if node.Assignee == userId {
+		return nil
+	}
+	if node.Assignee != "" {
+		return fmt.Errorf("%w: task %d is claimed by %s", ErrTaskAlreadyClaimed, taskId, node.Assignee)
+	}
+	users, groups, err := nodeService.GetNodeCandidates(tx, taskId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	candidate := slices.Contains(users, userId)
+	if !candidate && len(groups) > 0 {
+		userGroups, err := getUserGroups(userId)
+		if err != nil {
+			return err
+		}
+		candidate = slices.ContainsFunc(userGroups, func(group string) bool { return slices.Contains(groups, group) })
+	}
+	if !candidate {
+		return fmt.Errorf("%w: user %s, task %d", ErrNotTaskCandidate, userId, taskId)
+	}
+	if err := nodeService.UpdateNodeInstanceAssignee(tx, taskId, userId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return substituteAssignee(tx, node, userId)
+}
+
+// 各数据库实现共用的取消认领逻辑 只有认领人可以取消，没有候选人的审批节点不能退回
+func unclaimTask(runtimeService RuntimeService, taskId int, userId string) error {
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, unclaimTaskInTx(runtimeService, tx, taskId, userId))
+}
+
+func unclaimTaskInTx(runtimeService RuntimeService, tx *sql.Tx, taskId int, userId string) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	node, _, _, err := lockActiveTask(runtimeService, nodeService, tx, taskId)
+	if err != nil {
+		return err
+	}
+	if node.Assignee != userId {
+		return fmt.Errorf("%w: task %d is assigned to %s", ErrNotTaskAssignee, taskId, node.Assignee)
+	}
+	if delegationActive(node) {
+		return fmt.Errorf("%w: task %d is %s", ErrTaskDelegated, taskId, node.DelegationState)
+	}
+	users, groups, err := nodeService.GetNodeCandidates(tx, taskId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if len(users) == 0 && len(groups) == 0 {
+		return fmt.Errorf("%w: task %d has no candidates", ErrInvalidInput, taskId)
+	}
+	if err := nodeService.UpdateNodeInstanceAssignee(tx, taskId, ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package components
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// 审批节点只配置候选人和候选组 没有负责人
+const claimXML = `<Process name="claimTask">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" candidateUsers="claim-ann, claim-bob" candidateGroups="claim-finance"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f1</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="e"/>
+</Process>`
+
+// claim-carl 属于候选组，其他人不属于任何组
+func useClaimGroups(t *testing.T) {
+	SetUserGroupsProvider(UserGroupsFunc(func(userId string) ([]string, error) {
+		if userId == "claim-carl" {
+			return []string{"claim-finance"}, nil
+		}
+		return nil, nil
+	}))
+	t.Cleanup(func() { SetUserGroupsProvider(nil) })
+}
+
+// 用户待办里有没有某个审批节点
+func hasUserTask(t *testing.T, userId string, taskId int) bool {
+	t.Helper()
+	tasks, err := GetServiceFactory().GetRuntimeService().GetUserTasks(userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range tasks {
+		if task["id"] == taskId {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClaimTaskByCandidateUser(t *testing.T) {
+	deployXML(t, "claimTask", []byte(claimXML))
+	id := startProcess(t, "claimTask", "claim-starter", "")
+	task := activeTask(t, id, "t0")
+	if task.Assignee != "" {
+		t.Fatalf("unclaimed task assignee = %s", task.Assignee)
+	}
+	if !hasUserTask(t, "claim-ann", task.Id) || !hasUserTask(t, "claim-bob", task.Id) {
+		t.Fatal("candidates do not see the unclaimed task")
+	}
+
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.ClaimTask(task.Id, "claim-dave"); !errors.Is(err, ErrNotTaskCandidate) {
+		t.Fatalf("claim by non candidate error = %v", err)
+	}
+	if _, err := runtimeService.CompleteTask(task.Id, "claim-ann", nil); !errors.Is(err, ErrNotTaskAssignee) {
+		t.Fatalf("complete before claim error = %v", err)
+	}
+	if err := runtimeService.ClaimTask(task.Id, "claim-ann"); err != nil {
+		t.Fatal(err)
+	}
+	// 自己认领过的再认领直接返回
+	if err := runtimeService.ClaimTask(task.Id, "claim-ann"); err != nil {
+		t.Fatalf("claim again error = %v", err)
+	}
+	if err := runtimeService.ClaimTask(task.Id, "claim-bob"); !errors.Is(err, ErrTaskAlreadyClaimed) {
+		t.Fatalf("claim of claimed task error = %v", err)
+	}
+	if hasUserTask(t, "claim-bob", task.Id) {
+		t.Fatal("claimed task is still in other candidates' tasks")
+	}
+
+	// 只有认领人可以退回 退回之后其他候选人可以认领
+	if err := runtimeService.UnclaimTask(task.Id, "claim-bob"); !errors.Is(err, ErrNotTaskAssignee) {
+		t.Fatalf("unclaim by other user error = %v", err)
+	}
+	if err := runtimeService.UnclaimTask(task.Id, "claim-ann"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtimeService.ClaimTask(task.Id, "claim-bob"); err != nil {
+		t.Fatal(err)
+	}
+	completeTaskAs(t, task.Id, "claim-bob", nil)
+	if status := historicProcessStatus(t, id); status != PROCESS_STATUS_COMPLETE {
+		t.Fatalf("status = %s", status)
+	}
+}
+
+func TestClaimTaskByCandidateGroup(t *testing.T) {
+	useClaimGroups(t)
+	deployXML(t, "claimTask", []byte(claimXML))
+	id := startProcess(t, "claimTask", "claim-starter", "")
+	task := activeTask(t, id, "t0")
+	if !hasUserTask(t, "claim-carl", task.Id) {
+		t.Fatal("group member does not see the unclaimed task")
+	}
+	if hasUserTask(t, "claim-dave", task.Id) {
+		t.Fatal("user outside the group sees the task")
+	}
+
+	if err := GetServiceFactory().GetRuntimeService().ClaimTask(task.Id, "claim-carl"); err != nil {
+		t.Fatal(err)
+	}
+	if activeTask(t, id, "t0").Assignee != "claim-carl" {
+		t.Fatal("group member did not become the assignee")
+	}
+	completeTaskAs(t, task.Id, "claim-carl", nil)
+}
+
+// 多个候选人同时认领 只有一个人成功
+func TestConcurrentClaimHasOneWinner(t *testing.T) {
+	useClaimGroups(t)
+	deployXML(t, "claimTask", []byte(claimXML))
+	id := startProcess(t, "claimTask", "claim-starter", "")
+	task := activeTask(t, id, "t0")
+
+	users := []string{"claim-ann", "claim-bob", "claim-carl"}
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = GetServiceFactory().GetRuntimeService().ClaimTask(task.Id, user)
+		}()
+	}
+	wg.Wait()
+
+	var winners []string
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			winners = append(winners, users[i])
+		case !errors.Is(err, ErrTaskAlreadyClaimed):
+			t.Errorf("claim by %s error = %v", users[i], err)
+		}
+	}
+	if len(winners) != 1 {
+		t.Fatalf("winners = %v", winners)
+	}
+	if assignee := activeTask(t, id, "t0").Assignee; assignee != winners[0] {
+		t.Fatalf("assignee = %s, winner %s", assignee, winners[0])
+	}
+	completeTaskAs(t, task.Id, winners[0], nil)
+}
+
+// 已经结束的流程实例在历史表里的状态
+func historicProcessStatus(t *testing.T, processInstanceId int) string {
+	t.Helper()
+	historic, err := GetServiceFactory().GetHistoryService().GetHistoricProcessInstanceById(processInstanceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if historic == nil {
+		t.Fatalf("historic process instance %d not found", processInstanceId)
+	}
+	return historic.Status
+}
@@ -0,0 +1,158 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 审批节点的委派状态
+const (
+	TASK_DELEGATION_PENDING  = "pending"  // 委派中 委派的人处理完之后回到所有人，委派中不能完成审批节点
+	TASK_DELEGATION_RESOLVED = "resolved" // 委派的人已经处理完 审批节点回到了所有人手里
+	TASK_DELEGATION_SIGNING  = "signing"  // 加签中 当前负责人审批完之后交给所有人继续审批
+)
+
+// 加签的位置
+const (
+	SIGNER_POSITION_BEFORE = "before" // 加签的人先审批 审批完回到当前负责人
+	SIGNER_POSITION_AFTER  = "after"  // 当前负责人审批完 再交给加签的人审批
+)
+
+// 审批节点操作记录的类型
+const (
+	TASK_OPERATION_DELEGATE          = "delegate"        // 委派
+	TASK_OPERATION_RESOLVE           = "resolve"         // 委派的人处理完 回到所有人
+	TASK_OPERATION_TRANSFER          = "transfer"        // 转办 负责人永久换成另一个人
+	TASK_OPERATION_ADD_SIGNER_BEFORE = "addSignerBefore" // 前加签
+	TASK_OPERATION_ADD_SIGNER_AFTER  = "addSignerAfter"  // 后加签
+	TASK_OPERATION_SIGN              = "sign"            // 加签中的一方审批完 交给另一方
+	TASK_OPERATION_AUTO_DELEGATE     = "autoDelegate"    // 负责人不在 按代理规则自动交给代理人
+)
+
+// 委派中或者加签中的审批节点 不能再委派、转办、加签或者退回给候选人
+func delegationActive(node *NodeInstance) bool {
+	return node.DelegationState == TASK_DELEGATION_PENDING || node.DelegationState == TASK_DELEGATION_SIGNING
+}
+
+// 各数据库实现共用的操作审批节点的逻辑 锁住审批节点，校验操作人是当前负责人之后在同一个事务里修改
+func operateTask(runtimeService RuntimeService, taskId int, userId string, operate func(tx *sql.Tx, node *NodeInstance) error) error {
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, operateTaskInTx(runtimeService, tx, taskId, userId, operate))
+}
+
+func operateTaskInTx(runtimeService RuntimeService, tx *sql.Tx, taskId int, userId string, operate func(tx *sql.Tx, node *NodeInstance) error) error {
+	node, _, _, err := lockActiveTask(runtimeService, GetServiceFactory().GetNodeService(), tx, taskId)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(userId) == "" || node.Assignee != userId {
+		return fmt.Errorf("%w: task %d is assigned to %s", ErrNotTaskAssignee, taskId, node.Assignee)
+	}
+	return operate(tx, node)
+}
+
+// 委派、转办、加签的目标 不能为空也不能是当前负责人
+func checkTaskTarget(node *NodeInstance, target string) error {
+	if strings.TrimSpace(target) == "" {
+		return fmt.Errorf("%w: target user must not be empty", ErrInvalidInput)
+	}
+	if target == node.Assignee {
+		return fmt.Errorf("%w: task %d is already assigned to %s", ErrInvalidInput, node.Id, target)
+	}
+	if delegationActive(node) {
+		return fmt.Errorf("%w: task %d is %s", ErrTaskDelegated, node.Id, node.DelegationState)
+	}
+	return nil
+}
+
+// 修改负责人并记录操作 操作人就是操作前的负责人
+func changeTaskAssignee(tx *sql.Tx, node *NodeInstance, operation string, assignee string, owner string, delegationState string, reason string, outputData string) error {
+	if err := GetServiceFactory().GetNodeService().UpdateNodeInstanceDelegation(tx, node.Id, assignee, owner, delegationState); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	toUser := assignee
+	if operation == TASK_OPERATION_ADD_SIGNER_AFTER {
+		toUser = owner
+	}
+	err := GetServiceFactory().GetHistoryService().AddTaskOperation(tx, TaskOperation{
+		ProcessInstanceId: node.ProcessInstanceId,
+		NodeInstanceId:    node.Id,
+		ExecutionId:       node.ExecutionId,
+		Operation:         operation,
+		FromUser:          node.Assignee,
+		ToUser:            toUser,
+		Reason:            reason,
+		OutputData:        outputData,
+		CreatedBy:         node.Assignee,
+		CreatedAt:         time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// 委派 负责人交给另一个人处理，处理完之后回到自己手里再决定是否完成
+func delegateTask(runtimeService RuntimeService, taskId int, userId string, delegate string, reason string) error {
+	return operateTask(runtimeService, taskId, userId, func(tx *sql.Tx, node *NodeInstance) error {
+		if err := checkTaskTarget(node, delegate); err != nil {
+			return err
+		}
+		if err := changeTaskAssignee(tx, node, TASK_OPERATION_DELEGATE, delegate, userId, TASK_DELEGATION_PENDING, reason, ""); err != nil {
+			return err
+		}
+		return substituteAssignee(tx, node, delegate)
+	})
+}
+
+// 委派的人处理完 审批节点回到所有人
+func resolveTask(runtimeService RuntimeService, taskId int, userId string, reason string) error {
+	return operateTask(runtimeService, taskId, userId, func(tx *sql.Tx, node *NodeInstance) error {
+		if node.DelegationState != TASK_DELEGATION_PENDING {
+			return fmt.Errorf("%w: task %d is not delegated", ErrInvalidInput, taskId)
+		}
+		return changeTaskAssignee(tx, node, TASK_OPERATION_RESOLVE, node.Owner, node.Owner, TASK_DELEGATION_RESOLVED, reason, "")
+	})
+}
+
+// 转办 负责人永久换成另一个人，之前的委派记录不再保留
+func transferTask(runtimeService RuntimeService, taskId int, userId string, assignee string, reason string) error {
+	return operateTask(runtimeService, taskId, userId, func(tx *sql.Tx, node *NodeInstance) error {
+		if err := checkTaskTarget(node, assignee); err != nil {
+			return err
+		}
+		if err := changeTaskAssignee(tx, node, TASK_OPERATION_TRANSFER, assignee, "", "", reason, ""); err != nil {
+			return err
+		}
+		return substituteAssignee(tx, node, assignee)
+	})
+}
+
+// 加签 前加签时加签的人先审批，后加签时当前负责人先审批，审批完都交给另一方，另一方完成时流程才往下走
+func addSigner(runtimeService RuntimeService, taskId int, userId string, signer string, position string, reason string) error {
+	return operateTask(runtimeService, taskId, userId, func(tx *sql.Tx, node *NodeInstance) error {
+		if err := checkTaskTarget(node, signer); err != nil {
+			return err
+		}
+		switch position {
+		case SIGNER_POSITION_BEFORE:
+			if err := changeTaskAssignee(tx, node, TASK_OPERATION_ADD_SIGNER_BEFORE, signer, userId, TASK_DELEGATION_SIGNING, reason, ""); err != nil {
+				return err
+			}
+			return substituteAssignee(tx, node, signer)
+		case SIGNER_POSITION_AFTER:
+			return changeTaskAssignee(tx, node, TASK_OPERATION_ADD_SIGNER_AFTER, userId, signer, TASK_DELEGATION_SIGNING, reason, "")
+		}
+		return fmt.Errorf("%w: unknown signer position %q", ErrInvalidInput, position)
+	})
+}
+
+// 加签中的审批节点完成时 提交的数据记录到操作历史，审批节点交给所有人继续审批，流程不往下走
+func passSignedTask(tx *sql.Tx, node *NodeInstance, outputData string) error {
+	return changeTaskAssignee(tx, node, TASK_OPERATION_SIGN, node.Owner, "", "", "", outputData)
+}
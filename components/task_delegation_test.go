@@ -0,0 +1,166 @@
+package components
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+const taskDelegationXML = `<Process name="taskDelegation">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <Task executionId="t0" name="T0" assigneeType="ByAssigneeName" assigneeKey="td-ann"><Incoming>f0</Incoming><Outgoing>f1</Outgoing></Task>
+  <Task executionId="t1" name="T1" assigneeType="ByAssigneeName" assigneeKey="td-boss"><Incoming>f1</Incoming><Outgoing>f2</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="t0"/>
+  <SequenceFlow executionId="f1" sourceRef="t0" targetRef="t1"/>
+  <SequenceFlow executionId="f2" sourceRef="t1" targetRef="e"/>
+</Process>`
+
+// 启动一个流程实例 返回第一个审批节点
+func startDelegationProcess(t *testing.T) *NodeInstance {
+	t.Helper()
+	deployXML(t, "taskDelegation", []byte(taskDelegationXML))
+	id := startProcess(t, "taskDelegation", "td-starter", "")
+	return activeTask(t, id, "t0")
+}
+
+// 流程实例里审批节点操作记录的类型 按发生顺序
+func taskOperations(t *testing.T, processInstanceId int) []string {
+	t.Helper()
+	operations, err := GetServiceFactory().GetHistoryService().GetTaskOperations(processInstanceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, operation := range operations {
+		names = append(names, operation.Operation)
+	}
+	return names
+}
+
+func TestDelegateAndResolveTask(t *testing.T) {
+	task := startDelegationProcess(t)
+	id := task.ProcessInstanceId
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.DelegateTask(task.Id, "td-ann", "td-dan", "check numbers"); err != nil {
+		t.Fatal(err)
+	}
+	delegated := activeTask(t, id, "t0")
+	if delegated.Assignee != "td-dan" || delegated.Owner != "td-ann" || delegated.DelegationState != TASK_DELEGATION_PENDING {
+		t.Fatalf("delegated task = %s %s %s", delegated.Assignee, delegated.Owner, delegated.DelegationState)
+	}
+
+	// 委派中不能完成，也不能再委派
+	if _, err := runtimeService.CompleteTask(task.Id, "td-dan", nil); !errors.Is(err, ErrTaskDelegated) {
+		t.Fatalf("complete delegated task error = %v", err)
+	}
+	if _, err := runtimeService.CompleteTask(task.Id, "td-ann", nil); !errors.Is(err, ErrNotTaskAssignee) {
+		t.Fatalf("complete by owner error = %v", err)
+	}
+	if err := runtimeService.DelegateTask(task.Id, "td-dan", "td-eve", ""); !errors.Is(err, ErrTaskDelegated) {
+		t.Fatalf("delegate again error = %v", err)
+	}
+
+	if err := runtimeService.ResolveTask(task.Id, "td-dan", "numbers ok"); err != nil {
+		t.Fatal(err)
+	}
+	resolved := activeTask(t, id, "t0")
+	if resolved.Assignee != "td-ann" || resolved.DelegationState != TASK_DELEGATION_RESOLVED {
+		t.Fatalf("resolved task = %s %s", resolved.Assignee, resolved.DelegationState)
+	}
+	if err := runtimeService.ResolveTask(task.Id, "td-ann", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("resolve of resolved task error = %v", err)
+	}
+	completeTaskAs(t, task.Id, "td-ann", nil)
+	activeTask(t, id, "t1")
+	if operations := taskOperations(t, id); !slices.Equal(operations, []string{TASK_OPERATION_DELEGATE, TASK_OPERATION_RESOLVE}) {
+		t.Fatalf("operations = %v", operations)
+	}
+}
+
+func TestTransferTask(t *testing.T) {
+	task := startDelegationProcess(t)
+	id := task.ProcessInstanceId
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.TransferTask(task.Id, "td-ann", "td-ann", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("transfer to self error = %v", err)
+	}
+	if err := runtimeService.TransferTask(task.Id, "td-dan", "td-tom", ""); !errors.Is(err, ErrNotTaskAssignee) {
+		t.Fatalf("transfer by other user error = %v", err)
+	}
+	if err := runtimeService.TransferTask(task.Id, "td-ann", "td-tom", "on holiday"); err != nil {
+		t.Fatal(err)
+	}
+	transferred := activeTask(t, id, "t0")
+	if transferred.Assignee != "td-tom" || transferred.Owner != "" || transferred.DelegationState != "" {
+		t.Fatalf("transferred task = %s %s %s", transferred.Assignee, transferred.Owner, transferred.DelegationState)
+	}
+
+	// 转办之后不再回到原来的负责人
+	if _, err := runtimeService.CompleteTask(task.Id, "td-ann", nil); !errors.Is(err, ErrNotTaskAssignee) {
+		t.Fatalf("complete by previous assignee error = %v", err)
+	}
+	completeTaskAs(t, task.Id, "td-tom", nil)
+	activeTask(t, id, "t1")
+}
+
+// 前加签 加签的人先审批，审批完回到当前负责人
+func TestAddSignerBefore(t *testing.T) {
+	task := startDelegationProcess(t)
+	id := task.ProcessInstanceId
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.AddSigner(task.Id, "td-ann", "td-sam", SIGNER_POSITION_BEFORE, "legal review"); err != nil {
+		t.Fatal(err)
+	}
+	if signing := activeTask(t, id, "t0"); signing.Assignee != "td-sam" || signing.DelegationState != TASK_DELEGATION_SIGNING {
+		t.Fatalf("signing task = %s %s", signing.Assignee, signing.DelegationState)
+	}
+
+	tasks := completeTaskAs(t, task.Id, "td-sam", map[string]any{"legal": "ok"})
+	if len(tasks) != 0 {
+		t.Fatalf("signer completion created tasks %+v", tasks)
+	}
+	back := activeTask(t, id, "t0")
+	if back.Id != task.Id || back.Assignee != "td-ann" || back.DelegationState != "" {
+		t.Fatalf("task after signer = %d %s %s", back.Id, back.Assignee, back.DelegationState)
+	}
+	completeTaskAs(t, task.Id, "td-ann", nil)
+	activeTask(t, id, "t1")
+
+	operations, err := GetServiceFactory().GetHistoryService().GetTaskOperations(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 2 || operations[1].Operation != TASK_OPERATION_SIGN || operations[1].OutputData != `{"legal":"ok"}` {
+		t.Fatalf("operations = %+v", operations)
+	}
+}
+
+// 后加签 当前负责人先审批，审批完交给加签的人，加签的人完成时流程才往下走
+func TestAddSignerAfter(t *testing.T) {
+	task := startDelegationProcess(t)
+	id := task.ProcessInstanceId
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	if err := runtimeService.AddSigner(task.Id, "td-ann", "td-sam", "middle", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("unknown position error = %v", err)
+	}
+	if err := runtimeService.AddSigner(task.Id, "td-ann", "td-sam", SIGNER_POSITION_AFTER, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtimeService.TransferTask(task.Id, "td-ann", "td-tom", ""); !errors.Is(err, ErrTaskDelegated) {
+		t.Fatalf("transfer while signing error = %v", err)
+	}
+
+	completeTaskAs(t, task.Id, "td-ann", nil)
+	if findActiveNode(t, id, "t1") != nil {
+		t.Fatal("process moved on before the signer approved")
+	}
+	if signer := activeTask(t, id, "t0"); signer.Assignee != "td-sam" || signer.DelegationState != "" {
+		t.Fatalf("task after assignee = %s %s", signer.Assignee, signer.DelegationState)
+	}
+	completeTaskAs(t, task.Id, "td-sam", nil)
+	activeTask(t, id, "t1")
+	if operations := taskOperations(t, id); !slices.Equal(operations, []string{TASK_OPERATION_ADD_SIGNER_AFTER, TASK_OPERATION_SIGN}) {
+		t.Fatalf("operations = %v", operations)
+	}
+}
@@ -0,0 +1,135 @@
+package components
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BoundaryTimer 挂在审批节点上的边界定时器 审批节点创建时开始计时，到期时审批节点还没有完成就按 action 处理
+type BoundaryTimer struct {
+	ExecutionId  string   `xml:"executionId,attr"`
+	Name         string   `xml:"name,attr"`
+	Duration     string   `xml:"duration,attr"`     // 等待时长 支持 72h30m 和 ISO 8601 的 P3DT12H 两种写法
+	Action       string   `xml:"action,attr"`       // 到期后的动作 TIMER_ACTION_* 之一
+	AssigneeType string   `xml:"assigneeType,attr"` // 升级和转派的负责人指定方式
+	AssigneeKey  string   `xml:"assigneeKey,attr"`  // 升级和转派的负责人标识
+	Outgoing     []string `xml:"Outgoing"`          // action 为 flow 时走的序列流 序列流的 sourceRef 是审批节点
+	Listener     string   `xml:"Listener"`          // 定时器触发后的监听
+}
+
+// IntermediateTimerEvent 中间定时事件 流程走到这里后等待一段时间再继续往下走
+type IntermediateTimerEvent struct {
+	ExecutionId string   `xml:"executionId,attr"`
+	Name        string   `xml:"name,attr"`
+	Duration    string   `xml:"duration,attr"` // 等待时长 写法同 BoundaryTimer
+	Incoming    []string `xml:"Incoming"`
+	Outgoing    []string `xml:"Outgoing"`
+	X           string   `xml:"x,attr"`
+	Y           string   `xml:"y,attr"`
+	H           string   `xml:"h,attr"`
+	W           string   `xml:"w,attr"`
+	Listener    string   `xml:"Listener"` // 任务监听 执行完毕之后的后续逻辑 方法名称可以用逗号隔开 传递多段逻辑
+}
+
+// Execute 记录节点实例并创建定时任务 流程停在这里，由调度器到期后继续推进
+func (timerEvent IntermediateTimerEvent) Execute(ctx *WorkflowContext) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	tx := ctx.Tx
+	if tx == nil {
+		return errNilTransaction(timerEvent.ExecutionId)
+	}
+	nodeId, initerr := nodeService.InitNodeInstance(tx, ctx.ProcessInstanceId, ctx.ProcessDefinitionName, timerEvent.Name, timerEvent.ExecutionId, ctx.CurrentExecutionId, SYSTEM_USER_NOBODY)
+	if initerr != nil {
+		return newExecutionError(timerEvent.ExecutionId, ErrPersistenceFailed, initerr)
+	}
+	return scheduleTimer(tx, ctx.ProcessInstanceId, nodeId, timerEvent.ExecutionId, TIMER_ACTION_FLOW, timerEvent.Duration)
+}
+
+// 定时器到期 保存输出并迁移到历史表后继续执行后续的序列流
+func (timerEvent IntermediateTimerEvent) fire(ctx *WorkflowContext, nodeId int) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	if err := nodeService.UpdateNodeInstanceOutput(ctx.Tx, nodeId, "{}"); err != nil {
+		return newExecutionError(timerEvent.ExecutionId, ErrPersistenceFailed, err)
+	}
+	historyService := GetServiceFactory().GetHistoryService()
+	if err := historyService.CopyNodeInstanceById(ctx.Tx, nodeId); err != nil {
+		return newExecutionError(timerEvent.ExecutionId, ErrPersistenceFailed, err)
+	}
+
+	ctx.CurrentExecutionId = timerEvent.ExecutionId
+	if listenerErr := RunListener(timerEvent.Listener, ctx); listenerErr != nil {
+		return newExecutionError(timerEvent.ExecutionId, ErrListenerFailed, listenerErr)
+	}
+	for _, value := range timerEvent.Outgoing {
+		if err := executeSequenceFlow(ctx, value); err != nil {
+			return err
+		}
+		ctx.CurrentExecutionId = timerEvent.ExecutionId
+	}
+	return nil
+}
+
+// 根据结构id找到审批节点上的边界定时器
+func (task Task) boundaryTimer(executionId string) (BoundaryTimer, bool) {
+	for _, timer := range task.BoundaryTimers {
+		if timer.ExecutionId == executionId {
+			return timer, true
+		}
+	}
+	return BoundaryTimer{}, false
+}
+
+// 按时长创建定时任务 到期时间从 Clock 取
+func scheduleTimer(tx *sql.Tx, processInstanceId int, nodeId int, executionId string, action string, duration string) error {
+	wait, err := parseTimerDuration(duration)
+	if err != nil {
+		return newExecutionError(executionId, ErrInvalidInput, err)
+	}
+	jobService := GetServiceFactory().GetJobService()
+	_, err = jobService.CreateTimerJob(tx, &TimerJob{
+		ProcessInstanceId: processInstanceId,
+		NodeInstanceId:    nodeId,
+		ExecutionId:       executionId,
+		Action:            action,
+		DueTime:           now().Add(wait),
+	})
+	if err != nil {
+		return newExecutionError(executionId, ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// ISO 8601 的时长 只支持天、小时、分钟、秒，年和月的长度不固定 不支持
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// 解析定时器的时长 先按 go 的写法 72h30m，再按 ISO 8601 的 P3DT12H
+func parseTimerDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("timer duration is empty")
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		matches := isoDurationPattern.FindStringSubmatch(strings.ToUpper(value))
+		if matches == nil || value == "P" || strings.HasSuffix(strings.ToUpper(value), "T") {
+			return 0, fmt.Errorf("invalid timer duration %s", value)
+		}
+		units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+		duration = 0
+		for i, unit := range units {
+			if matches[i+1] == "" {
+				continue
+			}
+			amount, _ := strconv.ParseFloat(matches[i+1], 64)
+			duration += time.Duration(amount * float64(unit))
+		}
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("timer duration %s must be positive", value)
+	}
+	return duration, nil
+}
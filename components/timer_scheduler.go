@@ -0,0 +1,360 @@
+package components
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 调度器每一轮最多处理的定时任务数量
+const DEFAULT_TIMER_BATCH_SIZE = 100
+
+// TimerScheduler 定时任务调度器 按固定间隔扫描到期的定时任务并逐个触发，每个定时任务一个事务
+// 多个进程同时运行调度器时，同一个定时任务只会被一个事务拿到；也可以不启动协程，由外部的定时框架调用 RunDueTimers
+// 触发失败时和异步任务一样记录错误、按指数退避推迟，失败次数用完移到死信表
+type TimerScheduler struct {
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	backoff     time.Duration
+	mu          sync.Mutex
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewTimerScheduler 创建调度器 interval 是扫描间隔，重试策略默认和异步执行器一致
+func NewTimerScheduler(interval time.Duration) *TimerScheduler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &TimerScheduler{
+		interval:    interval,
+		batchSize:   DEFAULT_TIMER_BATCH_SIZE,
+		maxAttempts: DEFAULT_ASYNC_MAX_ATTEMPTS,
+		backoff:     DEFAULT_ASYNC_BACKOFF,
+	}
+}
+
+// SetRetryPolicy 设置最多失败几次以及第一次失败后的等待时间，需要在 Start 之前调用
+func (scheduler *TimerScheduler) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if maxAttempts > 0 {
+		scheduler.maxAttempts = maxAttempts
+	}
+	if backoff > 0 {
+		scheduler.backoff = backoff
+	}
+}
+
+// Start 启动扫描协程 已经启动时什么都不做
+func (scheduler *TimerScheduler) Start() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.stop != nil {
+		return
+	}
+	scheduler.stop = make(chan struct{})
+	scheduler.done = make(chan struct{})
+	go scheduler.loop(scheduler.stop, scheduler.done)
+}
+
+// Stop 停止扫描协程 等待正在处理的一轮结束后返回
+func (scheduler *TimerScheduler) Stop() {
+	scheduler.mu.Lock()
+	stop, done := scheduler.stop, scheduler.done
+	scheduler.stop, scheduler.done = nil, nil
+	scheduler.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (scheduler *TimerScheduler) loop(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := scheduler.RunDueTimers(); err != nil {
+				log.Println("Failed to fire timer jobs: ", err)
+			}
+		}
+	}
+}
+
+// RunDueTimers 触发当前已经到期的定时任务，返回触发的数量
+// 单个定时任务失败时回滚它自己的事务，记录失败后推迟重试，不影响其他定时任务
+func (scheduler *TimerScheduler) RunDueTimers() (int, error) {
+	jobService := GetServiceFactory().GetJobService()
+	jobs, err := jobService.GetDueTimerJobs(now(), scheduler.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	fired := 0
+	var errs []error
+	for _, job := range jobs {
+		ok, err := scheduler.fireTimerJob(jobService, job)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("timer job %d: %w", job.Id, err))
+			continue
+		}
+		if ok {
+			fired++
+		}
+	}
+	return fired, errors.Join(errs...)
+}
+
+// 在一个事务里触发定时任务 已经被处理或者不需要处理时返回 false，失败时在新的事务里记录失败
+func (scheduler *TimerScheduler) fireTimerJob(jobService JobService, job TimerJob) (bool, error) {
+	tx, err := jobService.GetTransaction()
+	if err != nil {
+		return false, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	fired, err := fireTimerJobInTx(jobService, tx, job)
+	if err = finishTransaction(tx, err); err == nil {
+		return fired, nil
+	}
+	if errors.Is(err, ErrNoMatchingFlow) {
+		// 重试也走不下去 定时任务保留，流程实例标记为故障，恢复之后再触发
+		return false, raiseIncident(GetServiceFactory().GetRuntimeService(), job.ProcessInstanceId, err)
+	}
+	if recordErr := scheduler.recordFailure(jobService, job, err); recordErr != nil {
+		return false, errors.Join(err, recordErr)
+	}
+	return false, err
+}
+
+// 记录失败 次数用完时移到死信表
+func (scheduler *TimerScheduler) recordFailure(jobService JobService, job TimerJob, cause error) error {
+	tx, err := jobService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, scheduler.recordFailureInTx(jobService, tx, job, cause))
+}
+
+func (scheduler *TimerScheduler) recordFailureInTx(jobService JobService, tx *sql.Tx, job TimerJob, cause error) error {
+	if _, err := GetServiceFactory().GetRuntimeService().LockProcessInstance(tx, job.ProcessInstanceId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	locked, err := jobService.LockTimerJob(tx, job.Id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if locked == nil {
+		return nil
+	}
+	scheduler.mu.Lock()
+	maxAttempts, backoff := scheduler.maxAttempts, scheduler.backoff
+	scheduler.mu.Unlock()
+
+	attempts := locked.Attempts + 1
+	if err := jobService.UpdateTimerJobFailure(tx, locked.Id, attempts, now().Add(retryDelay(backoff, attempts)), cause.Error()); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if attempts >= maxAttempts {
+		log.Printf("Timer job %d (%s) moved to dead letter after %d attempts: %v", locked.Id, locked.ExecutionId, attempts, cause)
+		if err := jobService.MoveTimerJobToDeadLetter(tx, locked.Id); err != nil {
+			return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+		}
+	}
+	return nil
+}
+
+// RetryDeadLetterTimerJob 把死信定时任务放回定时任务表，失败次数清零并立即到期，返回新的定时任务id
+// 节点在这期间已经完成的话，触发时会直接忽略
+func RetryDeadLetterTimerJob(deadLetterJobId int) (int, error) {
+	jobService := GetServiceFactory().GetJobService()
+	tx, err := jobService.GetTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	id, err := retryDeadLetterTimerJobInTx(jobService, tx, deadLetterJobId)
+	if err := finishTransaction(tx, err); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func retryDeadLetterTimerJobInTx(jobService JobService, tx *sql.Tx, deadLetterJobId int) (int, error) {
+	job, err := jobService.LockDeadLetterTimerJob(tx, deadLetterJobId)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if job == nil {
+		return 0, fmt.Errorf("%w: dead letter timer job %d", ErrJobNotFound, deadLetterJobId)
+	}
+	id, err := jobService.CreateTimerJob(tx, &TimerJob{
+		ProcessInstanceId: job.ProcessInstanceId,
+		NodeInstanceId:    job.NodeInstanceId,
+		ExecutionId:       job.ExecutionId,
+		Action:            job.Action,
+		DueTime:           now(),
+		LastError:         job.LastError,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if err := jobService.DeleteDeadLetterTimerJob(tx, deadLetterJobId); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return id, nil
+}
+
+// 加锁顺序和完成审批节点一致：先流程实例 再定时任务 最后节点实例
+func fireTimerJobInTx(jobService JobService, tx *sql.Tx, job TimerJob) (bool, error) {
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	nodeService := GetServiceFactory().GetNodeService()
+
+	instance, err := runtimeService.LockProcessInstance(tx, job.ProcessInstanceId)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance != nil && processInstancePaused(instance.Status) {
+		// 挂起或者故障期间不触发 恢复后再处理
+		return false, nil
+	}
+	locked, err := jobService.LockTimerJob(tx, job.Id)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if locked == nil || locked.DueTime.After(now()) {
+		// 已经被其他调度器处理 或者刚刚失败被推迟了
+		return false, nil
+	}
+	// 定时任务触发后就删除 后面的检查不通过时也不再保留
+	if err := jobService.DeleteTimerJob(tx, locked.Id); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if instance == nil || instance.Status != PROCESS_STATUS_RUNNING {
+		return false, nil
+	}
+	// 节点已经完成或者被打回删除了 定时器失效
+	node, err := nodeService.LockNodeInstance(tx, locked.NodeInstanceId)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	if node == nil || node.OutputData != "" {
+		return false, nil
+	}
+
+	model, err := getModelByVersion(instance.ProcessDefinitionName, instance.Version)
+	if err != nil {
+		return false, err
+	}
+	ctx := &WorkflowContext{
+		Model:                 model,
+		ProcessInstanceId:     node.ProcessInstanceId,
+		ProcessDefinitionName: node.ProcessDefinitionName,
+		CurrentUserId:         SYSTEM_USER_NOBODY,
+		CurrentExecutionId:    node.ExecutionId,
+		StartTime:             instance.StartTime,
+		Tx:                    tx,
+	}
+	if timerEvent, ok := model.IntermediateTimerEvents[locked.ExecutionId]; ok {
+		return true, timerEvent.fire(ctx, node.Id)
+	}
+	task, ok := model.Tasks[node.ExecutionId]
+	if !ok {
+		return false, newExecutionError(locked.ExecutionId, ErrNodeNotFound, fmt.Errorf("timer is attached to %s which is not a task", node.ExecutionId))
+	}
+	timer, ok := task.boundaryTimer(locked.ExecutionId)
+	if !ok {
+		return false, newExecutionError(locked.ExecutionId, ErrNodeNotFound, fmt.Errorf("boundary timer is not defined on task %s", task.ExecutionId))
+	}
+	return true, timer.fire(ctx, task, node)
+}
+
+// 转派或升级后的负责人 定时器配置了负责人时按配置分配
+// 升级定时器没有配置时交给当前负责人的上级，查不到上级时报错，不能原样留给当前负责人
+func (timer BoundaryTimer) newAssignment(ctx *WorkflowContext, node *NodeInstance) (Assignment, error) {
+	if timer.AssigneeKey != "" {
+		assigneeType := timer.AssigneeType
+		if assigneeType == "" {
+			assigneeType = ASSIGNEETYPE_NAME
+		}
+		return resolveAssignment(ctx, assigneeType, timer.AssigneeKey)
+	}
+	if node.Assignee == "" {
+		return Assignment{}, fmt.Errorf("task %s has no assignee to escalate from", node.ExecutionId)
+	}
+	manager, err := getManager(node.Assignee)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if manager == "" || manager == node.Assignee {
+		return Assignment{}, fmt.Errorf("no manager found for %s", node.Assignee)
+	}
+	return Assignment{Assignee: manager}, nil
+}
+
+// 边界定时器到期 按动作处理还没有完成的审批节点
+func (timer BoundaryTimer) fire(ctx *WorkflowContext, task Task, node *NodeInstance) error {
+	nodeService := GetServiceFactory().GetNodeService()
+	switch timer.Action {
+	case TIMER_ACTION_COMPLETE:
+		if err := task.completeNode(ctx, node.Id, `{"timedOut":true}`); err != nil {
+			return err
+		}
+	case TIMER_ACTION_ESCALATE, TIMER_ACTION_REASSIGN:
+		assignment, err := timer.newAssignment(ctx, node)
+		if err != nil {
+			return newExecutionError(timer.ExecutionId, ErrAssigneeFailed, err)
+		}
+		if err := nodeService.UpdateNodeInstanceAssignee(ctx.Tx, node.Id, assignment.Assignee); err != nil {
+			return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+		}
+		node.Assignee = assignment.Assignee
+		if err := applyDelegationRules(ctx, node); err != nil {
+			return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+		}
+		// 转派给角色或者多个人时 负责人为空，由新的候选人认领
+		if assignment.Assignee == "" {
+			if err := nodeService.AddNodeCandidates(ctx.Tx, ctx.ProcessInstanceId, node.Id, assignment.CandidateUsers, assignment.CandidateGroups); err != nil {
+				return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+			}
+		}
+	case TIMER_ACTION_FLOW:
+		// 审批节点以超时状态结束 不执行审批节点的出线，改走定时器的出线
+		if err := nodeService.UpdateNodeInstanceOutput(ctx.Tx, node.Id, `{"timedOut":true}`); err != nil {
+			return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if err := saveOutputVariables(ctx, task.ExecutionId, `{"timedOut":true}`); err != nil {
+			return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+		}
+		historyService := GetServiceFactory().GetHistoryService()
+		if err := historyService.ArchiveNodeInstance(ctx.Tx, node.Id, NODE_STATUS_TIMED_OUT, timer.Name); err != nil {
+			return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+		}
+		if err := GetServiceFactory().GetJobService().DeleteTimerJobsByNodeInstance(ctx.Tx, node.Id); err != nil {
+			return newExecutionError(timer.ExecutionId, ErrPersistenceFailed, err)
+		}
+	default:
+		return newExecutionError(timer.ExecutionId, ErrInvalidInput, fmt.Errorf("unknown timer action %q", timer.Action))
+	}
+	// complete 的监听在审批节点完成之后执行，flow 的监听在走出线之前执行
+	ctx.CurrentExecutionId = task.ExecutionId
+	if listenerErr := RunListener(timer.Listener, ctx); listenerErr != nil {
+		return newExecutionError(timer.ExecutionId, ErrListenerFailed, listenerErr)
+	}
+	if timer.Action != TIMER_ACTION_FLOW {
+		return nil
+	}
+	for _, value := range timer.Outgoing {
+		if err := executeSequenceFlow(ctx, value); err != nil {
+			return err
+		}
+		ctx.CurrentExecutionId = task.ExecutionId
+	}
+	return nil
+}
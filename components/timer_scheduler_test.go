@@ -0,0 +1,258 @@
+package components
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 手动拨动的时钟
+type manualClock struct {
+	mu      sync.Mutex
+	current time.Time
+}
+
+func (clock *manualClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.current
+}
+
+func (clock *manualClock) advance(duration time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.current = clock.current.Add(duration)
+}
+
+// 换成手动时钟 测试结束时恢复系统时钟
+func useManualClock(t *testing.T) *manualClock {
+	clock := &manualClock{current: time.Now()}
+	SetClock(clock)
+	t.Cleanup(func() { SetClock(nil) })
+	return clock
+}
+
+// 测试结束时终止还在运行的流程实例 清掉它的定时任务，不影响后面的测试
+func terminateOnCleanup(t *testing.T, processInstanceId int) {
+	t.Cleanup(func() {
+		_ = GetServiceFactory().GetRuntimeService().TerminateProcessInstance(processInstanceId, "test finished")
+	})
+}
+
+// 流程实例还没有触发的定时任务
+func timerJobsOf(t *testing.T, processInstanceId int) []TimerJob {
+	t.Helper()
+	jobs, err := GetServiceFactory().GetJobService().GetDueTimerJobs(now().Add(1000*time.Hour), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []TimerJob
+	for _, job := range jobs {
+		if job.ProcessInstanceId == processInstanceId {
+			result = append(result, job)
+		}
+	}
+	return result
+}
+
+var (
+	failingResolverMu    sync.Mutex
+	failingResolverError error
+)
+
+func init() {
+	RegisterAssigneeResolver("ByTimerTestResolver", AssigneeResolverFunc(func(ctx *WorkflowContext, assigneeKey string) (Assignment, error) {
+		failingResolverMu.Lock()
+		defer failingResolverMu.Unlock()
+		if failingResolverError != nil {
+			return Assignment{}, failingResolverError
+		}
+		return Assignment{Assignee: assigneeKey}, nil
+	}))
+}
+
+func setResolverError(err error) {
+	failingResolverMu.Lock()
+	defer failingResolverMu.Unlock()
+	failingResolverError = err
+}
+
+const timerRetryXML = `<Process name="timerRetry">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="retry-alice"><Incoming>f1</Incoming><Outgoing>f2</Outgoing>
+    <BoundaryTimer executionId="t_reassign" duration="1h" action="reassign" assigneeType="ByTimerTestResolver" assigneeKey="retry-bob"/>
+  </Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>
+  <SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>
+</Process>`
+
+func TestTimerFailureBacksOffAndMovesToDeadLetter(t *testing.T) {
+	clock := useManualClock(t)
+	setResolverError(errors.New("directory unavailable"))
+	t.Cleanup(func() { setResolverError(nil) })
+	deployXML(t, "timerRetry", []byte(timerRetryXML))
+	id := startProcess(t, "timerRetry", "retry-ann", "")
+	terminateOnCleanup(t, id)
+
+	scheduler := NewTimerScheduler(time.Hour)
+	scheduler.SetRetryPolicy(2, time.Minute)
+	clock.advance(61 * time.Minute)
+	if _, err := scheduler.RunDueTimers(); !errors.Is(err, ErrAssigneeFailed) {
+		t.Fatalf("first run error = %v", err)
+	}
+	jobs := timerJobsOf(t, id)
+	if len(jobs) != 1 || jobs[0].Attempts != 1 || jobs[0].LastError == "" {
+		t.Fatalf("jobs after first failure = %+v", jobs)
+	}
+	if !jobs[0].DueTime.Equal(now().Add(time.Minute)) {
+		t.Fatalf("due time after first failure = %v, now %v", jobs[0].DueTime, now())
+	}
+	// 退避期间不再触发
+	if fired, err := scheduler.RunDueTimers(); fired != 0 || err != nil {
+		t.Fatalf("run during backoff = %d %v", fired, err)
+	}
+
+	clock.advance(2 * time.Minute)
+	if _, err := scheduler.RunDueTimers(); !errors.Is(err, ErrAssigneeFailed) {
+		t.Fatalf("second run error = %v", err)
+	}
+	if jobs := timerJobsOf(t, id); len(jobs) != 0 {
+		t.Fatalf("timer jobs after dead letter = %+v", jobs)
+	}
+	deadLetters, err := GetServiceFactory().GetJobService().GetDeadLetterTimerJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deadLetter *DeadLetterTimerJob
+	for i := range deadLetters {
+		if deadLetters[i].ProcessInstanceId == id {
+			deadLetter = &deadLetters[i]
+		}
+	}
+	if deadLetter == nil || deadLetter.Attempts != 2 {
+		t.Fatalf("dead letter timer jobs = %+v", deadLetters)
+	}
+	if task := activeTask(t, id, "t"); task.Assignee != "retry-alice" {
+		t.Fatalf("assignee after failures = %s", task.Assignee)
+	}
+
+	// 修复之后放回定时任务表 重新触发
+	setResolverError(nil)
+	if _, err := RetryDeadLetterTimerJob(deadLetter.Id); err != nil {
+		t.Fatal(err)
+	}
+	if fired, err := scheduler.RunDueTimers(); fired != 1 || err != nil {
+		t.Fatalf("run after retry = %d %v", fired, err)
+	}
+	if task := activeTask(t, id, "t"); task.Assignee != "retry-bob" {
+		t.Fatalf("assignee after retry = %s", task.Assignee)
+	}
+}
+
+const timerEscalateXML = `<Process name="timerEscalate">
+  <StartEvent executionId="s"><Outgoing>f1</Outgoing></StartEvent>
+  <Task executionId="t" name="T" assigneeType="ByAssigneeName" assigneeKey="escalate-alice"><Incoming>f1</Incoming><Outgoing>f2</Outgoing>
+    <BoundaryTimer executionId="t_escalate" duration="1h" action="escalate"/>
+  </Task>
+  <EndEvent executionId="e"><Incoming>f2</Incoming></EndEvent>
+  <SequenceFlow executionId="f1" sourceRef="s" targetRef="t"/>
+  <SequenceFlow executionId="f2" sourceRef="t" targetRef="e"/>
+</Process>`
+
+func TestEscalationWithoutManagerFails(t *testing.T) {
+	clock := useManualClock(t)
+	t.Cleanup(func() { SetManagerProvider(nil) })
+	deployXML(t, "timerEscalate", []byte(timerEscalateXML))
+	id := startProcess(t, "timerEscalate", "escalate-ann", "")
+	terminateOnCleanup(t, id)
+
+	scheduler := NewTimerScheduler(time.Hour)
+	scheduler.SetRetryPolicy(5, time.Minute)
+	clock.advance(61 * time.Minute)
+	// 没有组织架构 不能升级给自己
+	if _, err := scheduler.RunDueTimers(); !errors.Is(err, ErrAssigneeFailed) {
+		t.Fatalf("run without manager provider = %v", err)
+	}
+	SetManagerProvider(ManagerFunc(func(userId string) (string, error) { return userId, nil }))
+	clock.advance(2 * time.Minute)
+	if _, err := scheduler.RunDueTimers(); !errors.Is(err, ErrAssigneeFailed) {
+		t.Fatalf("run when user is their own manager = %v", err)
+	}
+	if task := activeTask(t, id, "t"); task.Assignee != "escalate-alice" {
+		t.Fatalf("assignee after failed escalation = %s", task.Assignee)
+	}
+
+	SetManagerProvider(ManagerFunc(func(userId string) (string, error) { return "escalate-boss", nil }))
+	clock.advance(5 * time.Minute)
+	if fired, err := scheduler.RunDueTimers(); fired != 1 || err != nil {
+		t.Fatalf("run with manager = %d %v", fired, err)
+	}
+	if task := activeTask(t, id, "t"); task.Assignee != "escalate-boss" {
+		t.Fatalf("assignee after escalation = %s", task.Assignee)
+	}
+}
+
+// 三个审批节点都由 due-user 审批 a 配置了期限，b 只有边界定时器，c 都没有
+const dueDateXML = `<Process name="dueDate">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <ParallelGateway executionId="p1"><Incoming>f0</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing><Outgoing>fc</Outgoing></ParallelGateway>
+  <Task executionId="a" name="A" assigneeType="ByAssigneeName" assigneeKey="due-user" dueDate="48h"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing>
+    <BoundaryTimer executionId="a_remind" duration="1h" action="reassign" assigneeType="ByAssigneeName" assigneeKey="due-user"/>
+  </Task>
+  <Task executionId="b" name="B" assigneeType="ByAssigneeName" assigneeKey="due-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing>
+    <BoundaryTimer executionId="b_late" duration="P1D" action="complete"/>
+    <BoundaryTimer executionId="b_soon" duration="2h" action="complete"/>
+  </Task>
+  <Task executionId="c" name="C" assigneeType="ByAssigneeName" assigneeKey="due-user"><Incoming>fc</Incoming><Outgoing>fc2</Outgoing></Task>
+  <ParallelGateway executionId="p2"><Incoming>fa2</Incoming><Incoming>fb2</Incoming><Incoming>fc2</Incoming><Outgoing>fe</Outgoing></ParallelGateway>
+  <EndEvent executionId="e"><Incoming>fe</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="p1"/>
+  <SequenceFlow executionId="fa" sourceRef="p1" targetRef="a"/>
+  <SequenceFlow executionId="fb" sourceRef="p1" targetRef="b"/>
+  <SequenceFlow executionId="fc" sourceRef="p1" targetRef="c"/>
+  <SequenceFlow executionId="fa2" sourceRef="a" targetRef="p2"/>
+  <SequenceFlow executionId="fb2" sourceRef="b" targetRef="p2"/>
+  <SequenceFlow executionId="fc2" sourceRef="c" targetRef="p2"/>
+  <SequenceFlow executionId="fe" sourceRef="p2" targetRef="e"/>
+</Process>`
+
+// 到期时间取审批期限 没有期限时取最早到期的边界定时器，待办可以按到期时间排序
+func TestTaskDueDate(t *testing.T) {
+	clock := useManualClock(t)
+	deployXML(t, "dueDate", []byte(dueDateXML))
+	id := startProcess(t, "dueDate", "due-ann", "")
+	terminateOnCleanup(t, id)
+	started := clock.Now()
+
+	a, b, c := activeTask(t, id, "a"), activeTask(t, id, "b"), activeTask(t, id, "c")
+	expected := map[*NodeInstance]time.Time{a: started.Add(48 * time.Hour), b: started.Add(2 * time.Hour), c: {}}
+	for task, dueDate := range expected {
+		if !task.DueDate.Equal(dueDate) {
+			t.Errorf("due date of %s = %v, want %v", task.ExecutionId, task.DueDate, dueDate)
+		}
+	}
+
+	tasks, err := GetServiceFactory().GetRuntimeService().GetUserTasksByDueDate("due-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []int
+	for _, task := range tasks {
+		order = append(order, task["id"].(int))
+	}
+	if len(order) != 3 || order[0] != b.Id || order[1] != a.Id || order[2] != c.Id {
+		t.Fatalf("tasks by due date = %v, want %v", order, []int{b.Id, a.Id, c.Id})
+	}
+
+	// 完成之后历史表保留到期时间
+	completeTaskAs(t, a.Id, "due-user", nil)
+	historic, err := GetServiceFactory().GetHistoryService().GetHistoricNodeInstanceById(a.Id)
+	if err != nil || historic == nil {
+		t.Fatalf("historic task %d: %v", a.Id, err)
+	}
+	if !historic.DueDate.Equal(started.Add(48 * time.Hour)) {
+		t.Fatalf("historic due date = %v", historic.DueDate)
+	}
+}
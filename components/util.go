@@ -5,10 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"regexp"
-	"strings"
-
-	"github.com/Knetic/govaluate"
 )
 
 // 读取文件内容
@@ -38,21 +34,17 @@ func ToJsonString(data map[string]interface{}) (string, error) {
 	return string(jsonData), nil
 }
 
-// govaluate表达式计算
+// govaluate表达式计算 参数按原来的类型传入：数字按数字比较，字符串里的引号不会破坏表达式
+// 参数的键是表达式里的属性路径（结构id.字段.子字段）或者流程实例作用域的变量名
+// 表单提交的数字字符串和日期字符串和数字、日期比较大小时先转换，例如 startEvent.startDate > '2024-01-01'
 func EvaluateExpression(expression string, parameters map[string]interface{}) (interface{}, error) {
-	for key, value := range parameters {
-		// 将参数中的值替换到表达式中
-		strValue := fmt.Sprintf("'%v'", value)
-		expression = strings.ReplaceAll(expression, key, strValue)
-	}
-	// 创建表达式解析器
-	expr, err := govaluate.NewEvaluableExpression(expression)
+	expr, err := parseExpression(expression)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse expression: %v", err)
+		return nil, err
 	}
 
 	// 评估表达式
-	result, err := expr.Evaluate(nil)
+	result, err := expr.Evaluate(expressionParameters(expr, parameters))
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate expression: %v", err)
 	}
@@ -60,20 +52,18 @@ func EvaluateExpression(expression string, parameters map[string]interface{}) (i
 	return result, nil
 }
 
-// 从govaluate表达式字符串中提取属性名
+// 从govaluate表达式字符串中提取属性路径，字符串常量里的内容不算
 func ExtractAttributes(expression string) []string {
-	// 定义正则表达式，用于匹配属性名，例如 A.a 或 data.value
-	regex := regexp.MustCompile(`\b[A-Za-z_]\w*\.\w+\b`)
-	matches := regex.FindAllString(expression, -1)
-
 	// 去重处理
 	uniqueMatches := make(map[string]bool)
 	var attributes []string
-	for _, match := range matches {
-		if _, exists := uniqueMatches[match]; !exists {
-			uniqueMatches[match] = true
-			attributes = append(attributes, match)
+	for _, match := range attributePathRegex.FindAllStringSubmatch(expression, -1) {
+		attribute := match[3]
+		if attribute == "" || uniqueMatches[attribute] {
+			continue
 		}
+		uniqueMatches[attribute] = true
+		attributes = append(attributes, attribute)
 	}
 
 	return attributes
@@ -0,0 +1,312 @@
+package components
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 流程变量的作用域 为空是整个流程实例
+// 并行网关和包容网关分出的每个分支用分支出线的结构id作为作用域，分支里的节点共用，兄弟分支之间互不可见，见 Model.BranchScopes
+// 节点的输出按节点的结构id保存，用 结构id.字段 引用
+const VARIABLE_SCOPE_PROCESS = ""
+
+// 流程变量的类型 值统一按 JSON 存储，类型用来在读取和计算表达式时还原
+const (
+	VARIABLE_TYPE_STRING  = "string"
+	VARIABLE_TYPE_NUMBER  = "number"
+	VARIABLE_TYPE_BOOLEAN = "boolean"
+	VARIABLE_TYPE_JSON    = "json" // 对象或者数组
+	VARIABLE_TYPE_NULL    = "null"
+)
+
+// 变量历史记录的动作
+const (
+	VARIABLE_ACTION_CREATE = "create"
+	VARIABLE_ACTION_UPDATE = "update"
+	VARIABLE_ACTION_DELETE = "delete"
+)
+
+// ProcessVariable 流程变量 和节点的输出数据分开存储，节点记录被清理之后依然可以在条件表达式里使用
+type ProcessVariable struct {
+	Id                int
+	ProcessInstanceId int
+	Scope             string // 作用域 VARIABLE_SCOPE_PROCESS 或者节点的结构id
+	Name              string
+	Type              string // VARIABLE_TYPE_* 之一
+	Value             any
+	UpdatedBy         string
+	UpdatedAt         time.Time
+}
+
+// HistoricVariable 流程变量的每一次修改 删除时值为空
+type HistoricVariable struct {
+	Id                int
+	ProcessInstanceId int
+	Scope             string
+	Name              string
+	Type              string
+	Value             any
+	Action            string // VARIABLE_ACTION_* 之一
+	ChangedBy         string
+	ChangedAt         time.Time
+}
+
+// VariableService 提供了操作流程变量表和变量历史表的接口
+type VariableService interface {
+	GetTransaction() (*sql.Tx, error)
+	//设置变量 不存在时新建，每一次修改都往历史表记录一条
+	SetVariable(tx *sql.Tx, processInstanceId int, scope string, name string, value any, changedBy string) error
+	//在事务里查询一个变量 不存在时返回 nil
+	GetVariable(tx *sql.Tx, processInstanceId int, scope string, name string) (*ProcessVariable, error)
+	//在事务里查询某个作用域的全部变量 名称 -> 值
+	GetVariables(tx *sql.Tx, processInstanceId int, scope string) (map[string]any, error)
+	//查询流程实例的全部变量 按作用域和名称排序
+	GetProcessInstanceVariables(processInstanceId int) ([]ProcessVariable, error)
+	//在事务里查询流程实例的全部变量 按作用域和名称排序
+	GetAllVariables(tx *sql.Tx, processInstanceId int) ([]ProcessVariable, error)
+	//删除某个作用域的全部变量 同时记录历史，打回时清理下游节点的变量
+	DeleteVariablesByScope(tx *sql.Tx, processInstanceId int, scope string, changedBy string) error
+	//流程结束或终止时删除运行中的变量 历史表保留
+	DeleteVariablesByProcessInstance(tx *sql.Tx, processInstanceId int) error
+	//查询变量的修改历史 按修改顺序排序
+	GetVariableHistory(processInstanceId int) ([]HistoricVariable, error)
+}
+
+// 把变量的值转成 JSON 并判断类型
+func encodeVariable(name string, value any) (string, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("%w: variable name must not be empty", ErrInvalidInput)
+	}
+	dataBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: failed to marshal variable %s: %v", ErrInvalidInput, name, err)
+	}
+	decoded, err := decodeVariable(string(dataBytes))
+	if err != nil {
+		return "", "", err
+	}
+	switch decoded.(type) {
+	case nil:
+		return VARIABLE_TYPE_NULL, string(dataBytes), nil
+	case string:
+		return VARIABLE_TYPE_STRING, string(dataBytes), nil
+	case float64:
+		return VARIABLE_TYPE_NUMBER, string(dataBytes), nil
+	case bool:
+		return VARIABLE_TYPE_BOOLEAN, string(dataBytes), nil
+	default:
+		return VARIABLE_TYPE_JSON, string(dataBytes), nil
+	}
+}
+
+func decodeVariable(data string) (any, error) {
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal variable value: %v", err)
+	}
+	return value, nil
+}
+
+// 节点的输出数据按字段保存为节点作用域的变量，输出不是 JSON 对象时不保存
+func saveOutputVariables(ctx *WorkflowContext, scope string, data string) error {
+	if strings.TrimSpace(data) == "" {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return nil
+	}
+	// 按名称顺序保存 变量历史的顺序是固定的
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	variableService := GetServiceFactory().GetVariableService()
+	for _, name := range names {
+		if err := variableService.SetVariable(ctx.Tx, ctx.ProcessInstanceId, scope, name, fields[name], ctx.CurrentUserId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 计算条件表达式需要的参数 属性路径按下面的顺序取值，后面的路径在取到的 JSON 值里继续往下找：
+// 1. 第一段是节点的结构id，第二段是这个节点作用域的变量
+// 2. 第一段是开始事件的结构id，第二段是流程实例作用域的变量，启动表单的字段保存在流程实例作用域，例如 startEvent.applicant.dept
+// 3. 第一段是当前分支作用域的变量，从里到外，例如 review.score
+// 4. 第一段是流程实例作用域的变量，例如 applicant.dept
+// 5. 升级之前启动的流程实例没有变量，取这个节点最近一次的输出数据
+// 不带点的名称取当前分支和流程实例作用域的变量 里层分支的同名变量优先
+func resolveExpressionParameters(ctx *WorkflowContext, expression string) (map[string]any, error) {
+	parameters := make(map[string]any)
+	for _, attr := range ExtractAttributes(expression) {
+		value, err := resolveAttribute(ctx, strings.Split(attr, "."))
+		if err != nil {
+			return nil, err
+		}
+		parameters[attr] = value
+	}
+	variables, err := visibleVariables(ctx, ctx.CurrentExecutionId)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range variables {
+		parameters[name] = value
+	}
+	return parameters, nil
+}
+
+// 节点能看到的变量 流程实例作用域的变量被所在分支从外到里逐层覆盖，兄弟分支的变量看不到
+func visibleVariables(ctx *WorkflowContext, executionId string) (map[string]any, error) {
+	variableService := GetServiceFactory().GetVariableService()
+	variables, err := variableService.GetVariables(ctx.Tx, ctx.ProcessInstanceId, VARIABLE_SCOPE_PROCESS)
+	if err != nil {
+		return nil, err
+	}
+	chain := ctx.Model.branchChain(executionId)
+	for i := len(chain) - 1; i >= 0; i-- {
+		branch, err := variableService.GetVariables(ctx.Tx, ctx.ProcessInstanceId, chain[i])
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range branch {
+			variables[name] = value
+		}
+	}
+	return variables, nil
+}
+
+func resolveAttribute(ctx *WorkflowContext, path []string) (any, error) {
+	attr := strings.Join(path, ".")
+	variableService := GetServiceFactory().GetVariableService()
+	variable, err := variableService.GetVariable(ctx.Tx, ctx.ProcessInstanceId, path[0], path[1])
+	if err != nil {
+		return nil, err
+	}
+	if variable == nil && isStartEvent(ctx.Model, path[0]) {
+		variable, err = variableService.GetVariable(ctx.Tx, ctx.ProcessInstanceId, VARIABLE_SCOPE_PROCESS, path[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if variable == nil {
+		for _, scope := range append(ctx.Model.branchChain(ctx.CurrentExecutionId), VARIABLE_SCOPE_PROCESS) {
+			variable, err = variableService.GetVariable(ctx.Tx, ctx.ProcessInstanceId, scope, path[0])
+			if err != nil {
+				return nil, err
+			}
+			if variable != nil {
+				path = append([]string{path[0]}, path...)
+				break
+			}
+		}
+	}
+	var root any
+	if variable != nil {
+		root = variable.Value
+	} else {
+		node, err := GetServiceFactory().GetNodeService().GetLatestCompletedNodeInstance(ctx.Tx, ctx.ProcessInstanceId, path[0])
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			return nil, fmt.Errorf("no data found for attribute: %s", attr)
+		}
+		if root, err = decodeVariable(node.OutputData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal output data of %s: %v", path[0], err)
+		}
+		path = append([]string{path[0]}, path...)
+	}
+	value, ok := lookupPath(root, path[2:])
+	if !ok {
+		return nil, fmt.Errorf("attribute %s not found", attr)
+	}
+	return value, nil
+}
+
+func isStartEvent(model *Model, executionId string) bool {
+	if model == nil {
+		return false
+	}
+	_, ok := model.StartEvents[executionId]
+	return ok
+}
+
+// BranchScope 当前节点所在分支的变量作用域 不在分支里时是流程实例作用域
+func (ctx *WorkflowContext) BranchScope() string {
+	if ctx.Model == nil {
+		return VARIABLE_SCOPE_PROCESS
+	}
+	return ctx.Model.BranchScopes[ctx.CurrentExecutionId]
+}
+
+// SetVariable 在当前事务里设置变量 监听和服务节点的处理函数里使用，scope 为空时是流程实例作用域
+// 只给当前分支设置时传 ctx.BranchScope()
+func (ctx *WorkflowContext) SetVariable(scope string, name string, value any) error {
+	if ctx.Tx == nil {
+		return errNilTransaction(ctx.CurrentExecutionId)
+	}
+	return GetServiceFactory().GetVariableService().SetVariable(ctx.Tx, ctx.ProcessInstanceId, scope, name, value, ctx.CurrentUserId)
+}
+
+// GetVariables 在当前事务里读取某个作用域的全部变量
+func (ctx *WorkflowContext) GetVariables(scope string) (map[string]any, error) {
+	if ctx.Tx == nil {
+		return nil, errNilTransaction(ctx.CurrentExecutionId)
+	}
+	return GetServiceFactory().GetVariableService().GetVariables(ctx.Tx, ctx.ProcessInstanceId, scope)
+}
+
+// 分支网关走一条出线之前清掉这个分支上一次留下的变量 打回或者循环之后重新分支时从头开始
+func clearBranchVariables(ctx *WorkflowContext, flowId string) error {
+	if _, isBranch := ctx.Model.BranchParents[flowId]; !isBranch {
+		return nil
+	}
+	return GetServiceFactory().GetVariableService().DeleteVariablesByScope(ctx.Tx, ctx.ProcessInstanceId, flowId, ctx.CurrentUserId)
+}
+
+// 各数据库实现共用的设置变量逻辑 流程实例没有结束时才能修改，挂起和故障的流程实例也可以修改，修正数据后再恢复
+func setProcessVariable(runtimeService RuntimeService, processInstanceId int, scope string, name string, value any, userId string) error {
+	tx, err := runtimeService.GetTransaction()
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %v", ErrPersistenceFailed, err)
+	}
+	return finishTransaction(tx, setProcessVariableInTx(runtimeService, tx, processInstanceId, scope, name, value, userId))
+}
+
+func setProcessVariableInTx(runtimeService RuntimeService, tx *sql.Tx, processInstanceId int, scope string, name string, value any, userId string) error {
+	if _, _, err := encodeVariable(name, value); err != nil {
+		return err
+	}
+	instance, err := lockProcessInstance(runtimeService, tx, processInstanceId)
+	if err != nil {
+		return err
+	}
+	if instance.Status != PROCESS_STATUS_RUNNING && !processInstancePaused(instance.Status) {
+		return fmt.Errorf("%w: id %d status %s", ErrProcessNotRunning, processInstanceId, instance.Status)
+	}
+	if err := GetServiceFactory().GetVariableService().SetVariable(tx, processInstanceId, scope, name, value, userId); err != nil {
+		return fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	return nil
+}
+
+// 各数据库实现共用的查询变量逻辑
+func getProcessVariables(processInstanceId int, scope string) (map[string]any, error) {
+	variables, err := GetServiceFactory().GetVariableService().GetProcessInstanceVariables(processInstanceId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersistenceFailed, err)
+	}
+	result := make(map[string]any)
+	for _, variable := range variables {
+		if variable.Scope == scope {
+			result[variable.Name] = variable.Value
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 启动表单的字段用 startEvent.字段 引用 互斥网关按顺序判断
+const startFormRoutingXML = `<Process name="startFormRouting">
+  <StartEvent executionId="startEvent"><Outgoing>f0</Outgoing></StartEvent>
+  <ExclusiveGateway executionId="x1" default="fo"><Incoming>f0</Incoming><Outgoing>fs</Outgoing><Outgoing>fh</Outgoing><Outgoing>fb</Outgoing><Outgoing>fo</Outgoing></ExclusiveGateway>
+  <Task executionId="sick" name="Sick" assigneeType="ByAssigneeName" assigneeKey="route-user"><Incoming>fs</Incoming><Outgoing>fs2</Outgoing></Task>
+  <Task executionId="hr" name="HR" assigneeType="ByAssigneeName" assigneeKey="route-user"><Incoming>fh</Incoming><Outgoing>fh2</Outgoing></Task>
+  <Task executionId="big" name="Big" assigneeType="ByAssigneeName" assigneeKey="route-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing></Task>
+  <Task executionId="other" name="Other" assigneeType="ByAssigneeName" assigneeKey="route-user"><Incoming>fo</Incoming><Outgoing>fo2</Outgoing></Task>
+  <EndEvent executionId="es"><Incoming>fs2</Incoming></EndEvent>
+  <EndEvent executionId="eh"><Incoming>fh2</Incoming></EndEvent>
+  <EndEvent executionId="eb"><Incoming>fb2</Incoming></EndEvent>
+  <EndEvent executionId="eo"><Incoming>fo2</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="startEvent" targetRef="x1"/>
+  <SequenceFlow executionId="fs" sourceRef="x1" targetRef="sick"><ConditionExpression>startEvent.leaveType == 'Sick Leave'</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fh" sourceRef="x1" targetRef="hr"><ConditionExpression>startEvent.applicant.dept == 'hr'</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fb" sourceRef="x1" targetRef="big"><ConditionExpression>startEvent.startDate &gt; '2024-01-01' &amp;&amp; startEvent.amount &gt; 1000</ConditionExpression></SequenceFlow>
+  <SequenceFlow executionId="fo" sourceRef="x1" targetRef="other"/>
+  <SequenceFlow executionId="fs2" sourceRef="sick" targetRef="es"/>
+  <SequenceFlow executionId="fh2" sourceRef="hr" targetRef="eh"/>
+  <SequenceFlow executionId="fb2" sourceRef="big" targetRef="eb"/>
+  <SequenceFlow executionId="fo2" sourceRef="other" targetRef="eo"/>
+</Process>`
+
+func TestStartEventPrefixResolvesStartFormFields(t *testing.T) {
+	deployXML(t, "startFormRouting", []byte(startFormRoutingXML))
+	cases := []struct {
+		form     string
+		expected string
+	}{
+		{`{"leaveType":"Sick Leave","applicant":{"dept":"it"},"startDate":"2024-05-01","amount":"10"}`, "sick"},
+		{`{"leaveType":"Annual Leave","applicant":{"dept":"hr"},"startDate":"2024-05-01","amount":"10"}`, "hr"},
+		{`{"leaveType":"Annual Leave","applicant":{"dept":"it"},"startDate":"2024-05-01","amount":"1500"}`, "big"},
+		{`{"leaveType":"Annual Leave","applicant":{"dept":"it"},"startDate":"2023-05-01","amount":"1500"}`, "other"},
+	}
+	for _, c := range cases {
+		id := startProcess(t, "startFormRouting", "route-ann", c.form)
+		terminateOnCleanup(t, id)
+		if findActiveNode(t, id, c.expected) == nil {
+			t.Errorf("form %s did not reach %s", c.form, c.expected)
+		}
+	}
+}
+
+func init() {
+	// 把当前节点的名字记到所在分支的 owner 变量里
+	RegisterListener("branchTestMark", func(ctx *WorkflowContext) error {
+		return ctx.SetVariable(ctx.BranchScope(), "owner", "br-"+ctx.CurrentExecutionId)
+	})
+}
+
+// 每个分支两个审批节点 第二个节点按 owner 变量指定负责人
+const branchScopeXML = `<Process name="branchScope">
+  <StartEvent executionId="s"><Outgoing>f0</Outgoing></StartEvent>
+  <ParallelGateway executionId="p1"><Incoming>f0</Incoming><Outgoing>fa</Outgoing><Outgoing>fb</Outgoing></ParallelGateway>
+  <Task executionId="a1" name="A1" assigneeType="ByAssigneeName" assigneeKey="br-user"><Incoming>fa</Incoming><Outgoing>fa2</Outgoing><Listener>branchTestMark</Listener></Task>
+  <Task executionId="a2" name="A2" assigneeType="ByExpression" assigneeKey="owner"><Incoming>fa2</Incoming><Outgoing>fa3</Outgoing></Task>
+  <Task executionId="b1" name="B1" assigneeType="ByAssigneeName" assigneeKey="br-user"><Incoming>fb</Incoming><Outgoing>fb2</Outgoing><Listener>branchTestMark</Listener></Task>
+  <Task executionId="b2" name="B2" assigneeType="ByExpression" assigneeKey="owner"><Incoming>fb2</Incoming><Outgoing>fb3</Outgoing></Task>
+  <ParallelGateway executionId="p2"><Incoming>fa3</Incoming><Incoming>fb3</Incoming><Outgoing>fc</Outgoing></ParallelGateway>
+  <Task executionId="c" name="C" assigneeType="ByExpression" assigneeKey="owner"><Incoming>fc</Incoming><Outgoing>fe</Outgoing></Task>
+  <EndEvent executionId="e"><Incoming>fe</Incoming></EndEvent>
+  <SequenceFlow executionId="f0" sourceRef="s" targetRef="p1"/>
+  <SequenceFlow executionId="fa" sourceRef="p1" targetRef="a1"/>
+  <SequenceFlow executionId="fb" sourceRef="p1" targetRef="b1"/>
+  <SequenceFlow executionId="fa2" sourceRef="a1" targetRef="a2"/>
+  <SequenceFlow executionId="fa3" sourceRef="a2" targetRef="p2"/>
+  <SequenceFlow executionId="fb2" sourceRef="b1" targetRef="b2"/>
+  <SequenceFlow executionId="fb3" sourceRef="b2" targetRef="p2"/>
+  <SequenceFlow executionId="fc" sourceRef="p2" targetRef="c"/>
+  <SequenceFlow executionId="fe" sourceRef="c" targetRef="e"/>
+</Process>`
+
+func TestBranchScopesFollowModelStructure(t *testing.T) {
+	model, err := ParseXMLByte([]byte(branchScopeXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"a1": "fa", "a2": "fa", "b1": "fb", "b2": "fb"}
+	if !reflect.DeepEqual(model.BranchScopes, expected) {
+		t.Fatalf("branch scopes = %v", model.BranchScopes)
+	}
+	if forks := map[string]string{"p2": "p1"}; !reflect.DeepEqual(model.JoinForks, forks) {
+		t.Fatalf("join forks = %v", model.JoinForks)
+	}
+}
+
+// 分支里的节点共用分支作用域的变量 兄弟分支和汇聚之后都看不到
+func TestVariablesAreScopedPerBranch(t *testing.T) {
+	deployXML(t, "branchScope", []byte(branchScopeXML))
+	id := startProcess(t, "branchScope", "br-ann", `{"owner":"br-process"}`)
+	completeTaskAs(t, activeTask(t, id, "a1").Id, "br-user", nil)
+	completeTaskAs(t, activeTask(t, id, "b1").Id, "br-user", nil)
+
+	a2 := activeTask(t, id, "a2")
+	b2 := activeTask(t, id, "b2")
+	if a2.Assignee != "br-a1" || b2.Assignee != "br-b1" {
+		t.Fatalf("assignees inside branches = %s %s", a2.Assignee, b2.Assignee)
+	}
+	runtimeService := GetServiceFactory().GetRuntimeService()
+	for scope, owner := range map[string]string{VARIABLE_SCOPE_PROCESS: "br-process", "fa": "br-a1", "fb": "br-b1"} {
+		variables, err := runtimeService.GetVariables(id, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if variables["owner"] != owner {
+			t.Errorf("owner in scope %q = %v", scope, variables["owner"])
+		}
+	}
+
+	completeTaskAs(t, a2.Id, "br-a1", nil)
+	completeTaskAs(t, b2.Id, "br-b1", nil)
+	if c := activeTask(t, id, "c"); c.Assignee != "br-process" {
+		t.Fatalf("assignee after join = %s", c.Assignee)
+	}
+}
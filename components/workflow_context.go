@@ -11,9 +11,10 @@ type WorkflowContext struct {
 	ProcessDefinitionName string // 流程定义名称
 	// Version               int       // 流程定义版本
 	// BusinessKey           string    // 业务标识符
-	CurrentUserId      string    // 当前操作的用户Id
-	CurrentExecutionId string    // 当前执行的任务（节点，网关，序列流）结构Id
-	Data               string    // 流程节点数据json
-	StartTime          time.Time // 工作流启动时间
-	Tx                 *sql.Tx   // 当前事务
+	CurrentUserId      string         // 当前操作的用户Id
+	CurrentExecutionId string         // 当前执行的任务（节点，网关，序列流）结构Id
+	Data               string         // 流程节点数据json
+	StartTime          time.Time      // 工作流启动时间
+	Tx                 *sql.Tx        // 当前事务
+	NewTasks           []NodeInstance // 本次推进过程中新创建的审批节点
 }
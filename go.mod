@@ -2,9 +2,11 @@ module github.com/sc1247892011/zjf_workflow
 
 go 1.22.4
 
-require github.com/go-sql-driver/mysql v1.8.1
-
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/Knetic/govaluate v3.0.0+incompatible // indirect
+	github.com/Knetic/govaluate v3.0.0+incompatible
+	github.com/go-sql-driver/mysql v1.8.1
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.32
 )
+
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -71,14 +71,14 @@ func testRepositoryDeploy() {
 	// 打开数据库连接
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		log.Println("Failed to connect to database: %v", err)
+		log.Printf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
 	// 测试数据库连接
 	err = db.Ping()
 	if err != nil {
-		log.Println("Failed to ping database: %v", err)
+		log.Printf("Failed to ping database: %v", err)
 	}
 
 	fmt.Println("Successfully connected to the database!")
@@ -121,7 +121,7 @@ func testParse() {
 	// 调用 ParseXML 函数解析 XML 文件
 	model, err := components.ParseXML(filename)
 	if err != nil {
-		log.Println("Error parsing XML file: %v", err)
+		log.Printf("Error parsing XML file: %v", err)
 	}
 
 	// 输出解析结果
@@ -171,7 +171,7 @@ func testRuntimeServiceStart() {
 	// 打开数据库连接
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		log.Println("Failed to connect to database: %v", err)
+		log.Printf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 	components.Init(db, "mysql")
@@ -210,13 +210,13 @@ func testExpression() {
 
 	expr, err := govaluate.NewEvaluableExpression(expression)
 	if err != nil {
-		fmt.Errorf("failed to parse expression: %v", err)
+		fmt.Printf("failed to parse expression: %v\n", err)
 	}
 
 	// 评估表达式
 	result, err := expr.Evaluate(nil)
 	if err != nil {
-		fmt.Errorf("failed to evaluate expression: %v", err)
+		fmt.Printf("failed to evaluate expression: %v\n", err)
 	}
 	result2, _ := result.(string)
 	fmt.Printf(result2)